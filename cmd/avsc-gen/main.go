@@ -0,0 +1,45 @@
+// Command avsc-gen writes the Avro schema of a Go model struct.
+//
+// It is meant for go:generate, run from the package declaring the model:
+//
+//	//go:generate go run ../cmd/avsc-gen -type User -name Users -namespace kafka.example -out ../docker/kafka/user-schema-value.avsc
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"kafka-go-example/infra/avrogen"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory of the Go package declaring the model")
+	typeName := flag.String("type", "", "model struct type name")
+	name := flag.String("name", "", "record name (default the type name)")
+	namespace := flag.String("namespace", "", "record namespace")
+	timeType := flag.String("time", "string", "encoding of time.Time: string, timestamp-millis, timestamp-micros, local-timestamp-millis or local-timestamp-micros")
+	out := flag.String("out", "", "output .avsc file (default stdout)")
+	flag.Parse()
+
+	if *typeName == "" {
+		log.Fatalf("Missing -type")
+	}
+
+	schema, err := avrogen.GenerateSchema(*dir, *typeName, avrogen.SchemaOptions{
+		Name:      *name,
+		Namespace: *namespace,
+		TimeType:  *timeType,
+	})
+	if err != nil {
+		log.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(schema)
+		return
+	}
+	if err := os.WriteFile(*out, schema, 0644); err != nil {
+		log.Fatalf("Failed to write schema: %v", err)
+	}
+}
@@ -1,94 +1,70 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"flag"
+	"log"
+	"os"
+
 	"kafka-go-example/infra/avro"
 	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/consumer"
+	"kafka-go-example/infra/kafka"
+	"kafka-go-example/infra/serde"
 	"kafka-go-example/models"
-	"os"
-	"os/signal"
-	"syscall"
-
-	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avrov2"
 )
 
-const topic = "user-topic"
-
 func main() {
+	topic := flag.String("topic", "user-topic", "topic of users to consume")
+	format := flag.String("format", "", "payload format: avro, protobuf or jsonschema (default chosen by the schema of each message)")
+	flag.Parse()
+
 	// read config
 	kafkaCfg := config.LoadKafkaConfig()
+	consumerCfg := config.LoadConsumerConfig()
 	schemaregistryCfg := config.LoadSchemaRegistryConfig()
-	group := kafkaCfg.Group
-	topics := []string{topic}
 
 	// create consumer
-	c, err := kafka.NewConsumer(&kafka.ConfigMap{
-		"bootstrap.servers":  kafkaCfg.BootstrapServers,
-		"group.id":           group,
-		"session.timeout.ms": 6000,
-		"auto.offset.reset":  "earliest",
-	})
+	c, err := kafka.NewKafkaConsumer(kafkaCfg)
 	if err != nil {
-		fmt.Printf("failed to create consumer: %s\n", err)
-		os.Exit(1)
+		log.Fatalf("Failed to create consumer: %v", err)
 	}
-	defer c.Close()
-	fmt.Printf("created consumer %v\n", c)
 
 	// create schema registry deserializer
-	deserializer, err := avro.NewAvroDeserializer(schemaregistryCfg)
+	client, err := avro.NewSchemaRegistryClient(schemaregistryCfg)
 	if err != nil {
-		fmt.Printf("failed to create deserializer: %s\n", err)
-		os.Exit(1)
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+	var deserializer serde.DeserializerInterface
+	if *format == "" {
+		deserializer, err = serde.NewSchemaDeserializer(client)
+	} else {
+		deserializer, err = serde.NewDeserializer(client, *format)
 	}
-
-	// set up signal handling
-	sigchan := make(chan os.Signal, 1)
-	signal.Notify(sigchan, syscall.SIGINT, syscall.SIGTERM)
-
-	// subscribe to topics
-	err = c.SubscribeTopics(topics, nil)
 	if err != nil {
-		fmt.Printf("failed to subscribe to topics: %s\n", err)
-		os.Exit(1)
+		log.Fatalf("Failed to create deserializer: %v", err)
 	}
-	fmt.Printf("subscribed to topics: %v\n", topics)
 
-	// handle incoming messages
-	run := true
-	for run {
-		select {
-		case sig := <-sigchan:
-			fmt.Printf("caught signal %v: terminating\n", sig)
-			run = false
-		default:
-			ev := c.Poll(100)
-			if ev == nil {
-				continue
-			}
-			handleKafkaEvent(ev, deserializer)
-		}
+	// create the producer forwarding failed messages to the retry and dead-letter topics
+	kp, err := kafka.NewKafkaProducer(kafkaCfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to create producer: %v", err)
 	}
+	producer := kafka.NewProducer(kp)
+	defer producer.Close()
 
-	fmt.Printf("closing consumer\n")
-}
-
-func handleKafkaEvent(e kafka.Event, deserializer *avrov2.Deserializer) {
-	switch evt := e.(type) {
-	case *kafka.Message:
-		value := models.User{}
-		err := deserializer.DeserializeInto(*evt.TopicPartition.Topic, evt.Value, &value)
-		if err != nil {
-			fmt.Printf("failed to deserialize payload: %s\n", err)
-		} else {
-			fmt.Printf("Incoming message on %s:\n%+v\n\n", evt.TopicPartition, value)
-		}
-		if evt.Headers != nil {
-			fmt.Printf("headers: %v\n", evt.Headers)
+	// register handlers and run until SIGINT or SIGTERM
+	users := consumer.NewConsumer(c, deserializer, producer, consumerCfg)
+	consumer.Register(users, *topic, func(_ context.Context, msg consumer.Message[models.User]) error {
+		log.Printf("Incoming message on %s [%d] at %v: %+v", msg.Topic, msg.Partition, msg.Offset, msg.Value)
+		if msg.Headers != nil {
+			log.Printf("Headers: %v", msg.Headers)
 		}
-	// ...handle other event types as needed...
-	default:
-		fmt.Printf("Ignored event: %v\n", e)
+		return nil
+	})
+	if err := users.RunUntilSignal(); err != nil {
+		log.Printf("Consumer failed: %v", err)
+		producer.Close()
+		os.Exit(1)
 	}
 }
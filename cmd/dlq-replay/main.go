@@ -0,0 +1,65 @@
+// Command dlq-replay produces the messages of a dead-letter topic back to the
+// topic they failed on, once the cause of the failure is fixed.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/consumer"
+	"kafka-go-example/infra/kafka"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func main() {
+	topic := flag.String("topic", "", "source topic whose dead-letter topic is replayed")
+	dlq := flag.String("dlq", "", "dead-letter topic to replay instead of the one of -topic")
+	target := flag.String("to", "", "topic to produce to (default the source topic of each message)")
+	maxMessages := flag.Int("max", 0, "messages to replay (default all)")
+	group := flag.String("group", "dlq-replay", "consumer group tracking the replayed messages")
+	flag.Parse()
+
+	if *dlq == "" {
+		if *topic == "" {
+			log.Fatalf("Missing -topic or -dlq")
+		}
+		*dlq = consumer.DeadLetterTopic(*topic)
+	}
+
+	kafkaCfg := config.LoadKafkaConfig()
+	kafkaCfg.Group = *group
+	configMap, err := kafka.ConsumerConfigMap(kafkaCfg)
+	if err != nil {
+		log.Fatalf("Failed to configure consumer: %v", err)
+	}
+	configMap.SetKey("enable.partition.eof", true)
+	c, err := confluent.NewConsumer(configMap)
+	if err != nil {
+		log.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.Close()
+
+	kp, err := kafka.NewKafkaProducer(kafkaCfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to create producer: %v", err)
+	}
+	producer := kafka.NewProducer(kp)
+	defer producer.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	replayed, err := consumer.ReplayDeadLetters(ctx, c, producer, *dlq, consumer.ReplayOptions{Target: *target, Max: *maxMessages})
+	log.Printf("Replayed %d messages from %s", replayed, *dlq)
+	if err != nil {
+		log.Printf("Replay failed: %v", err)
+		producer.Close()
+		c.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,37 @@
+// Command fake-registry serves an in-memory schema registry seeded from schema files,
+// for running the producer, consumer and schema commands without Docker.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"kafka-go-example/infra/fakeregistry"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	schemas := flag.String("schemas", "docker/kafka", "comma separated directories of .avsc files or schema files to register, under subjects named after the files")
+	flag.Parse()
+
+	registry := fakeregistry.NewRegistry()
+	for _, path := range strings.Split(*schemas, ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		subjects, err := registry.Seed(path)
+		if err != nil {
+			log.Fatalf("Failed to seed %s: %v", path, err)
+		}
+		for _, subject := range subjects {
+			log.Printf("Registered subject %s", subject)
+		}
+	}
+
+	log.Printf("Fake schema registry listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, registry); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+}
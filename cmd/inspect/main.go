@@ -0,0 +1,102 @@
+// Command inspect prints the messages of topics as JSON, to inspect what
+// producers wrote: from a start offset, timestamp or the last messages, matching
+// a field filter, with their key, headers and schema ids. It is read-only: it
+// never commits offsets, and never joins a group other than a throwaway one.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/inspect"
+	"kafka-go-example/infra/kafka"
+
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+func main() {
+	topics := flag.String("topic", "user-topic", "comma separated topics to consume")
+	group := flag.String("group", "", "consumer group whose committed offsets to start from, without joining it, implies -assign (default a throwaway group)")
+	assign := flag.Bool("assign", false, "assign the partitions without joining a group or committing")
+	partitions := flag.String("partitions", "", "comma separated partitions to consume, implies -assign (default all)")
+	offset := flag.String("offset", "", "start offset: stored, beginning, end, an offset or -N for the last N messages (default stored)")
+	timestamp := flag.String("timestamp", "", "start at the first message at or after a time, RFC 3339 or Unix milliseconds")
+	maxRecords := flag.Int("max", 0, "records to print (default unlimited)")
+	filter := flag.String("filter", "", "print records whose field equals a value: path=value or path!=value, e.g. value.country.code=NL")
+	output := flag.String("output", inspect.FormatJSON, "output format: json, pretty or avro-json")
+	exit := flag.Bool("exit", false, "exit at the end of every partition")
+	flag.Parse()
+
+	opts := inspect.Options{Topics: strings.Split(*topics, ","), Assign: *assign || *partitions != "" || *group != "", Max: *maxRecords, Exit: *exit}
+	var err error
+	if opts.Partitions, err = inspect.ParsePartitions(*partitions); err != nil {
+		log.Fatalf("Invalid -partitions: %v", err)
+	}
+	if opts.Start, err = inspect.ParseStart(*offset, *timestamp); err != nil {
+		log.Fatalf("Invalid start: %v", err)
+	}
+	if *group == "" && opts.Assign && opts.Start == (inspect.Start{Offset: confluent.OffsetStored}) {
+		opts.Start.Offset = confluent.OffsetBeginning // without a group there are no stored offsets
+	}
+	if opts.Filter, err = inspect.ParseFilter(*filter); err != nil {
+		log.Fatalf("Invalid -filter: %v", err)
+	}
+	encoder, err := inspect.NewEncoder(os.Stdout, *output)
+	if err != nil {
+		log.Fatalf("Invalid -output: %v", err)
+	}
+
+	// read config
+	kafkaCfg := config.LoadKafkaConfig()
+	schemaregistryCfg := config.LoadSchemaRegistryConfig()
+
+	// create consumer: it never commits, so it cannot move the offsets of the
+	// consumers it inspects. With -group it only reads the committed offsets of
+	// the group, its partitions are assigned so it never joins the group and
+	// takes partitions from its members; otherwise it uses a throwaway group.
+	kafkaCfg.Group = *group
+	if kafkaCfg.Group == "" {
+		kafkaCfg.Group = fmt.Sprintf("inspect-%d-%d", os.Getpid(), time.Now().UnixNano())
+	}
+	configMap, err := kafka.ConsumerConfigMap(kafkaCfg)
+	if err != nil {
+		log.Fatalf("Failed to configure consumer: %v", err)
+	}
+	configMap.SetKey("enable.auto.commit", false)
+	configMap.SetKey("enable.partition.eof", *exit)
+	c, err := confluent.NewConsumer(configMap)
+	if err != nil {
+		log.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.Close()
+
+	// create the decoder of any schema registry format
+	client, err := avro.NewSchemaRegistryClient(schemaregistryCfg)
+	if err != nil {
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+	decoder, err := inspect.NewDecoder(client, *output == inspect.FormatAvroJSON)
+	if err != nil {
+		log.Fatalf("Failed to create decoder: %v", err)
+	}
+
+	// print until done, SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	printed, err := inspect.Run(ctx, c, opts, decoder, encoder)
+	log.Printf("Printed %d records", printed)
+	if err != nil {
+		log.Printf("Inspect failed: %v", err)
+		c.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,160 @@
+// Command lag reports how far consumer groups are behind: committed offsets
+// against high watermarks per partition, and the age of the next message to
+// consume. It prints the lag once, or serves it as Prometheus metrics.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/kafka"
+)
+
+func main() {
+	groups := flag.String("group", "", "comma separated consumer groups (default KAFKA_CONSUMER_GROUP)")
+	topics := flag.String("topic", "", "comma separated topics (default the topics each group committed to)")
+	listen := flag.String("listen", "", "address serving the lag as Prometheus metrics on /metrics, e.g. :9308 (default print once)")
+	interval := flag.Duration("interval", 30*time.Second, "time between measurements when serving metrics")
+	maxLag := flag.Int64("max-lag", 0, "warn, or exit 1 when printing, when a partition lags more messages (default no limit)")
+	maxTimeLag := flag.Duration("max-time-lag", 0, "warn, or exit 1 when printing, when the next message is older (default no limit)")
+	flag.Parse()
+
+	kafkaCfg := config.LoadKafkaConfig()
+	if *groups == "" {
+		*groups = kafkaCfg.Group
+	}
+	monitor := &monitor{groups: split(*groups), topics: split(*topics), maxLag: *maxLag, maxTimeLag: *maxTimeLag}
+
+	admin, err := kafka.NewKafkaAdmin(kafkaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create admin client: %v", err)
+	}
+	defer admin.Close()
+	monitor.admin = admin
+
+	// a throwaway group reading watermarks and message timestamps, never committing
+	kafkaCfg.Group = fmt.Sprintf("lag-%d-%d", os.Getpid(), time.Now().UnixNano())
+	c, err := kafka.NewKafkaConsumer(kafkaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create consumer: %v", err)
+	}
+	defer c.Close()
+	monitor.consumer = c
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *listen == "" {
+		exceeded, err := monitor.measure(ctx)
+		if err != nil {
+			log.Printf("Failed to measure lag: %v", err)
+			c.Close()
+			admin.Close()
+			os.Exit(1)
+		}
+		for _, lag := range monitor.lags {
+			fmt.Println(lag)
+		}
+		if exceeded {
+			c.Close()
+			admin.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	server := &http.Server{Addr: *listen, Handler: monitor}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}()
+	log.Printf("Serving lag metrics on %s/metrics", *listen)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		if _, err := monitor.measure(ctx); err != nil {
+			log.Printf("Failed to measure lag: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Failed to stop metrics server: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// monitor measures the lag of groups and serves the last measurement.
+type monitor struct {
+	admin      kafka.LagAdminInterface
+	consumer   kafka.LagConsumerInterface
+	groups     []string
+	topics     []string
+	maxLag     int64
+	maxTimeLag time.Duration
+
+	mu   sync.Mutex
+	lags []kafka.PartitionLag
+}
+
+// measure refreshes the lag and warns about the partitions over the limits,
+// reporting whether there are any.
+func (m *monitor) measure(ctx context.Context) (bool, error) {
+	lags, err := kafka.ConsumerLag(ctx, m.admin, m.consumer, m.groups, m.topics, time.Now())
+	if err != nil {
+		return false, err
+	}
+	m.mu.Lock()
+	m.lags = lags
+	m.mu.Unlock()
+
+	exceeded := false
+	for _, lag := range lags {
+		if (m.maxLag > 0 && lag.Lag > m.maxLag) || (m.maxTimeLag > 0 && lag.TimeLag > m.maxTimeLag) {
+			log.Printf("Lag over the limit: %s", lag)
+			exceeded = true
+		}
+	}
+	return exceeded, nil
+}
+
+func (m *monitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" {
+		http.NotFound(w, r)
+		return
+	}
+	m.mu.Lock()
+	lags := m.lags
+	m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := kafka.WriteLagMetrics(w, lags); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// split returns the values of a comma separated list.
+func split(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
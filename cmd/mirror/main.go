@@ -0,0 +1,81 @@
+// Command mirror consumes the topics of the mirrors in config/mirrors and writes
+// their records to MySQL tables, the reverse of the producer tasks.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/consumer"
+	"kafka-go-example/infra/database"
+	"kafka-go-example/infra/kafka"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/mirrors"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func main() {
+	configDir := flag.String("config", "config/mirrors", "directory of the mirror configurations")
+	group := flag.String("group", "", "consumer group (default KAFKA_CONSUMER_GROUP)")
+	flag.Parse()
+
+	// read config
+	kafkaCfg := config.LoadKafkaConfig()
+	if *group != "" {
+		kafkaCfg.Group = *group
+	}
+	consumerCfg := config.LoadConsumerConfig()
+	schemaregistryCfg := config.LoadSchemaRegistryConfig()
+	mirrorConfigs, err := config.LoadMirrorConfigs(*configDir)
+	if err != nil {
+		log.Fatalf("Failed to load mirror configurations: %v", err)
+	}
+	if len(mirrorConfigs) == 0 {
+		log.Fatalf("No mirror configurations in %s", *configDir)
+	}
+
+	db, err := database.NewDatabase(config.LoadDatabaseConfig())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	client, err := avro.NewSchemaRegistryClient(schemaregistryCfg)
+	if err != nil {
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+	deserializer, err := serde.NewSchemaDeserializer(client)
+	if err != nil {
+		log.Fatalf("Failed to create deserializer: %v", err)
+	}
+
+	c, err := kafka.NewKafkaConsumer(kafkaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create consumer: %v", err)
+	}
+	kp, err := kafka.NewKafkaProducer(kafkaCfg, nil)
+	if err != nil {
+		log.Fatalf("Failed to create producer: %v", err)
+	}
+	producer := kafka.NewProducer(kp)
+	defer producer.Close()
+
+	// register a handler per mirror and run until SIGINT or SIGTERM
+	mirrorConsumer := consumer.NewConsumer(c, deserializer, producer, consumerCfg)
+	for _, cfg := range mirrorConfigs {
+		if err := mirrors.RegisterMirror(mirrorConsumer, db, cfg, deserializer); err != nil {
+			log.Fatalf("Failed to register mirror %s: %v", cfg.Name, err)
+		}
+		log.Printf("Mirroring %s into table %s", cfg.Topic, cfg.Table)
+	}
+	if err := mirrorConsumer.RunUntilSignal(); err != nil {
+		log.Printf("Mirror failed: %v", err)
+		producer.Close()
+		db.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,85 @@
+// Command model-gen writes Go model structs for an Avro record schema, read
+// from an .avsc file or from a schema registry subject.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/avrogen"
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+)
+
+func main() {
+	schemaFile := flag.String("schema", "", "Avro schema file")
+	subject := flag.String("subject", "", "schema registry subject to read instead of -schema")
+	version := flag.Int("version", 0, "version of -subject (default latest)")
+	pkg := flag.String("package", "models", "Go package name")
+	typeName := flag.String("type", "", "Go type of the top-level record (default the record name)")
+	dbTags := flag.Bool("db", false, "add db tags named after the Avro fields")
+	timeFields := flag.String("time-fields", "", "comma separated string fields holding RFC 3339 timestamps, generated as time.Time")
+	out := flag.String("out", "", "output .go file (default stdout)")
+	flag.Parse()
+
+	schema, source, err := readSchema(*schemaFile, *subject, *version)
+	if err != nil {
+		log.Fatalf("Failed to read schema: %v", err)
+	}
+
+	opts := avrogen.ModelOptions{
+		Package:  *pkg,
+		TypeName: *typeName,
+		Source:   source,
+		DBTags:   *dbTags,
+	}
+	if *timeFields != "" {
+		opts.TimeFields = strings.Split(*timeFields, ",")
+	}
+
+	src, err := avrogen.GenerateModels(schema, opts)
+	if err != nil {
+		log.Fatalf("Failed to generate models: %v", err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*out, src, 0644); err != nil {
+		log.Fatalf("Failed to write models: %v", err)
+	}
+}
+
+// readSchema returns the schema and a description of where it was read from.
+func readSchema(file, subject string, version int) (string, string, error) {
+	if subject == "" {
+		if file == "" {
+			return "", "", errors.New("missing -schema or -subject")
+		}
+		data, err := os.ReadFile(file)
+		return string(data), file, err
+	}
+
+	client, err := avro.NewSchemaRegistryClient(config.LoadSchemaRegistryConfig())
+	if err != nil {
+		return "", "", err
+	}
+
+	var metadata schemaregistry.SchemaMetadata
+	if version > 0 {
+		metadata, err = client.GetSchemaMetadata(subject, version)
+	} else {
+		metadata, err = client.GetLatestSchemaMetadata(subject)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return metadata.Schema, fmt.Sprintf("subject %s version %d", subject, metadata.Version), nil
+}
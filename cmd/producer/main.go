@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"kafka-go-example/infra/avro"
 	"kafka-go-example/infra/config"
 	"kafka-go-example/infra/database"
 	"kafka-go-example/infra/kafka"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/infra/sink"
 	"kafka-go-example/tasks"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -21,6 +27,16 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load task configuration: %v", err)
 	}
+	taskCfg = config.ApplyDryRun(taskCfg, config.LoadDryRunConfig())
+
+	// Verify the task model against the registered schema before reading any row
+	schemaClient, err := avro.NewSchemaRegistryClient(schemaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+	if !avro.NewSchemaManager(schemaClient).VerifyTasks([]config.TaskConfig{taskCfg}, tasks.TaskModel, schemaCfg.AutoRegisterSchemas) {
+		log.Fatalf("Task model does not match its registered schema")
+	}
 
 	// Initialize database
 	db, err := database.NewDatabase(dbCfg)
@@ -29,22 +45,19 @@ func main() {
 	}
 	defer db.Close()
 
-	// Initialize serializer
-	avroSer, err := avro.NewAvroSerializer(schemaCfg)
-	if err != nil {
-		log.Fatalf("Failed to create avro serializer: %v", err)
-	}
-	serializer, err := avro.NewSerializer(avroSer)
+	// Initialize the serializer of the task format
+	serializer, err := serde.NewSerializer(schemaClient, taskCfg.Format, schemaCfg)
 	if err != nil {
 		log.Fatalf("Failed to create serializer: %v", err)
 	}
 
 	// Initialize producer
-	kafkaProducer, err := kafka.NewKafkaProducer(kafkaCfg)
+	pool := kafka.NewProducerPool(kafkaCfg)
+	defer pool.Close()
+	producer, err := sink.NewTaskProducer(taskCfg, pool, schemaCfg)
 	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %v", err)
+		log.Fatalf("Failed to create producer: %v", err)
 	}
-	producer := kafka.NewProducer(kafkaProducer)
 	defer producer.Close()
 
 	// Create the task
@@ -53,6 +66,8 @@ func main() {
 		log.Fatalf("Failed to create task: %v", err)
 	}
 
-	// Execute the task once
-	t.Execute()
+	// Execute the task once, stopping early on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	t.Execute(ctx)
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -40,7 +41,7 @@ func main() {
 	// Initialize repositories
 	userRepo := repositories.NewRepository[models.User](db, query)
 
-	users, errs := userRepo.Stream(time.Now().Add(-24 * time.Hour))
+	users, errs := userRepo.Stream(context.Background(), time.Now().Add(-24*time.Hour))
 
 	for user := range users {
 		fmt.Printf("User: %+v\n\n", user)
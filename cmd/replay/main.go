@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/database"
+	"kafka-go-example/infra/kafka"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/infra/sink"
+	"kafka-go-example/tasks"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// timeLayouts are the accepted formats for --from and --to.
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+func main() {
+	configFile := flag.String("config", "config/users.yaml", "task configuration file")
+	from := flag.String("from", "", "replay rows updated after this time")
+	to := flag.String("to", "", "replay rows updated up to this time (default now)")
+	ids := flag.String("ids", "", "comma separated list of ids to replay instead of a time window")
+	topic := flag.String("topic", "", "topic to produce to instead of the task topic")
+	flag.Parse()
+
+	opts, err := parseOptions(*from, *to, *ids, *topic)
+	if err != nil {
+		log.Fatalf("Invalid replay options: %v", err)
+	}
+
+	// Load configurations
+	dbCfg := config.LoadDatabaseConfig()
+	kafkaCfg := config.LoadKafkaConfig()
+	schemaCfg := config.LoadSchemaRegistryConfig()
+	taskCfg, err := config.LoadSingleTaskConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load task configuration: %v", err)
+	}
+	taskCfg = config.ApplyDryRun(taskCfg, config.LoadDryRunConfig())
+
+	// Initialize database
+	db, err := database.NewDatabase(dbCfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// Initialize the serializer of the task format
+	schemaClient, err := avro.NewSchemaRegistryClient(schemaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+	serializer, err := serde.NewSerializer(schemaClient, taskCfg.Format, schemaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create serializer: %v", err)
+	}
+
+	// Initialize producer
+	pool := kafka.NewProducerPool(kafkaCfg)
+	defer pool.Close()
+	producer, err := sink.NewTaskProducer(taskCfg, pool, schemaCfg)
+	if err != nil {
+		log.Fatalf("Failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	// Create the task
+	t, err := tasks.CreateTask(db, taskCfg, serializer, producer)
+	if err != nil {
+		log.Fatalf("Failed to create task: %v", err)
+	}
+
+	// Replay the selected rows once, stopping early on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := t.Replay(ctx, opts); err != nil {
+		log.Fatalf("Failed to replay task: %v", err)
+	}
+}
+
+// parseOptions builds replay options from the command line flags.
+func parseOptions(from, to, ids, topic string) (tasks.ReplayOptions, error) {
+	opts := tasks.ReplayOptions{Topic: topic}
+
+	if ids != "" {
+		for _, s := range strings.Split(ids, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return opts, fmt.Errorf("invalid id %q: %w", s, err)
+			}
+			opts.IDs = append(opts.IDs, id)
+		}
+		return opts, nil
+	}
+
+	if from == "" {
+		return opts, fmt.Errorf("either --from or --ids is required")
+	}
+
+	var err error
+	if opts.From, err = parseTime(from); err != nil {
+		return opts, err
+	}
+
+	opts.To = time.Now()
+	if to != "" {
+		if opts.To, err = parseTime(to); err != nil {
+			return opts, err
+		}
+	}
+
+	if !opts.From.Before(opts.To) {
+		return opts, fmt.Errorf("--from must be before --to")
+	}
+
+	return opts, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected one of %v", value, timeLayouts)
+}
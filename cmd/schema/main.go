@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/tasks"
+)
+
+const usage = `Usage: schema <command> [flags]
+
+Commands:
+  register       register the schema files of the task subjects
+  compatibility  set the compatibility level of the task subjects
+  check          check schema files against the latest registered versions
+  verify         verify task models against the registered schemas
+
+Run "schema <command> -h" for the command flags.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	configDir := flags.String("config", "config", "directory of task configuration files")
+	subject := flags.String("subject", "", "subject to use instead of the task subjects")
+	file := flags.String("file", "", "schema file of -subject")
+	level := flags.String("level", "", "compatibility level, e.g. BACKWARD or FULL_TRANSITIVE")
+
+	// subjects loads the subjects once the flags are parsed
+	subjects := func() []avro.SubjectFile {
+		subjects, err := loadSubjects(*configDir, *subject, *file)
+		if err != nil {
+			log.Fatalf("Failed to load subjects: %v", err)
+		}
+		return subjects
+	}
+
+	var run func(*avro.SchemaManager) bool
+	switch os.Args[1] {
+	case "register":
+		run = func(manager *avro.SchemaManager) bool { return register(manager, subjects()) }
+	case "compatibility":
+		run = func(manager *avro.SchemaManager) bool { return setCompatibility(manager, subjects(), *level) }
+	case "check":
+		run = func(manager *avro.SchemaManager) bool { return check(manager, subjects()) }
+	case "verify":
+		run = func(manager *avro.SchemaManager) bool { return verify(manager, *configDir) }
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	flags.Parse(os.Args[2:])
+
+	client, err := avro.NewSchemaRegistryClient(config.LoadSchemaRegistryConfig())
+	if err != nil {
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+
+	if !run(avro.NewSchemaManager(client)) {
+		os.Exit(1)
+	}
+}
+
+// loadSubjects returns the single subject given on the command line, or the subjects of the task configurations.
+func loadSubjects(configDir, subject, file string) ([]avro.SubjectFile, error) {
+	if subject != "" {
+		return []avro.SubjectFile{{Subject: subject, File: file}}, nil
+	}
+
+	taskConfigs, err := config.LoadTaskConfigs(configDir)
+	if err != nil {
+		return nil, err
+	}
+	return avro.TaskSubjects(taskConfigs)
+}
+
+func register(manager *avro.SchemaManager, subjects []avro.SubjectFile) bool {
+	ok := true
+	for _, s := range subjects {
+		id, err := manager.Register(s.Subject, s.File)
+		if err != nil {
+			log.Printf("Failed to register subject %s: %v", s.Subject, err)
+			ok = false
+			continue
+		}
+		log.Printf("Registered %s for subject %s with id %d", s.File, s.Subject, id)
+	}
+	return ok
+}
+
+func setCompatibility(manager *avro.SchemaManager, subjects []avro.SubjectFile, level string) bool {
+	ok := true
+	for _, s := range subjects {
+		if err := manager.SetCompatibility(s.Subject, level); err != nil {
+			log.Printf("Failed to set compatibility: %v", err)
+			ok = false
+			continue
+		}
+		log.Printf("Set compatibility of subject %s to %s", s.Subject, level)
+	}
+	return ok
+}
+
+func check(manager *avro.SchemaManager, subjects []avro.SubjectFile) bool {
+	ok := true
+	for _, s := range subjects {
+		compatible, err := manager.CheckCompatibility(s.Subject, s.File)
+		switch {
+		case err != nil:
+			log.Printf("Failed to check subject %s: %v", s.Subject, err)
+			ok = false
+		case !compatible:
+			log.Printf("Schema %s is NOT compatible with subject %s", s.File, s.Subject)
+			ok = false
+		default:
+			log.Printf("Schema %s is compatible with subject %s", s.File, s.Subject)
+		}
+	}
+	return ok
+}
+
+func verify(manager *avro.SchemaManager, configDir string) bool {
+	taskConfigs, err := config.LoadTaskConfigs(configDir)
+	if err != nil {
+		log.Fatalf("Failed to load task configurations: %v", err)
+	}
+
+	if !manager.VerifyTasks(taskConfigs, tasks.TaskModel, config.LoadSchemaRegistryConfig().AutoRegisterSchemas) {
+		return false
+	}
+	log.Println("Task models match their registered schemas")
+	return true
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/kafka"
+)
+
+func main() {
+	configDir := flag.String("config", "config", "directory of task configuration files")
+	check := flag.Bool("check", false, "only report missing and drifted topics, exit 1 if any")
+	flag.Parse()
+
+	kafkaCfg := config.LoadKafkaConfig()
+	taskConfigs, err := config.LoadTaskConfigs(*configDir)
+	if err != nil {
+		log.Fatalf("Failed to load task configurations: %v", err)
+	}
+
+	report, err := kafka.ProvisionTopics(context.Background(), kafkaCfg, taskConfigs, !*check)
+	if err != nil {
+		log.Fatalf("Failed to provision topics: %v", err)
+	}
+
+	for _, topic := range report.Created {
+		log.Printf("Created topic %s", topic)
+	}
+	for _, topic := range report.Existing {
+		log.Printf("Topic %s created by another client, settings not checked", topic)
+	}
+	for _, topic := range report.Missing {
+		log.Printf("Missing topic %s", topic)
+	}
+	for _, drift := range report.Drift {
+		log.Printf("Drift: %s", drift)
+	}
+
+	if *check && (len(report.Missing) > 0 || len(report.Drift) > 0) {
+		os.Exit(1)
+	}
+	log.Println("Topics are up to date")
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS consumer_dedup (
+  dedup_key VARBINARY(255) PRIMARY KEY,
+  version VARCHAR(64) NOT NULL,
+  handled_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX (handled_at)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS users_mirror (
+  id INT UNSIGNED PRIMARY KEY,
+  status VARCHAR(20) NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  country_code VARCHAR(255) NOT NULL,
+  country_name VARCHAR(255) NOT NULL,
+  created_at DATETIME,
+  updated_at DATETIME
+);
@@ -8,13 +8,18 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avrov2"
 )
 
-// NewAvroSerializer returns an Avro serializer using the provided configuration.
-func NewAvroSerializer(cfg config.SchemaRegistryConfig) (*avrov2.Serializer, error) {
-	client, err := schemaregistry.NewClient(schemaregistry.NewConfigWithAuthentication(
+// NewSchemaRegistryClient returns a schema registry client using the provided configuration.
+func NewSchemaRegistryClient(cfg config.SchemaRegistryConfig) (schemaregistry.Client, error) {
+	return schemaregistry.NewClient(schemaregistry.NewConfigWithAuthentication(
 		cfg.SchemaRegistryUrl,
 		cfg.SchemaRegistryUsername,
 		cfg.SchemaRegistryPassword,
 	))
+}
+
+// NewAvroSerializer returns an Avro serializer using the provided configuration.
+func NewAvroSerializer(cfg config.SchemaRegistryConfig) (*avrov2.Serializer, error) {
+	client, err := NewSchemaRegistryClient(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -30,11 +35,7 @@ func NewAvroSerializer(cfg config.SchemaRegistryConfig) (*avrov2.Serializer, err
 
 // NewAvroDeserializer returns an Avro deserializer using the provided configuration.
 func NewAvroDeserializer(cfg config.SchemaRegistryConfig) (*avrov2.Deserializer, error) {
-	client, err := schemaregistry.NewClient(schemaregistry.NewConfigWithAuthentication(
-		cfg.SchemaRegistryUrl,
-		cfg.SchemaRegistryUsername,
-		cfg.SchemaRegistryPassword,
-	))
+	client, err := NewSchemaRegistryClient(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,347 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/rest"
+	hamba "github.com/hamba/avro/v2"
+)
+
+// Format is the task format serialized with Avro, the default.
+const Format = "avro"
+
+const (
+	// latestVersion asks the registry to compare against the latest registered version.
+	latestVersion = -1
+	// subjectNotFound and versionNotFound are the registry error codes of an unknown subject or version.
+	subjectNotFound = 40401
+	versionNotFound = 40402
+)
+
+// RegistryInterface is the subset of the schema registry client used to manage subjects.
+type RegistryInterface interface {
+	Register(subject string, schema schemaregistry.SchemaInfo, normalize bool) (int, error)
+	TestCompatibility(subject string, version int, schema schemaregistry.SchemaInfo) (bool, error)
+	UpdateCompatibility(subject string, update schemaregistry.Compatibility) (schemaregistry.Compatibility, error)
+	GetLatestSchemaMetadata(subject string) (schemaregistry.SchemaMetadata, error)
+	GetSchemaMetadata(subject string, version int) (schemaregistry.SchemaMetadata, error)
+}
+
+// SubjectFile is a registry subject and the local schema file that defines it.
+type SubjectFile struct {
+	Subject string
+	File    string
+}
+
+// Subject naming strategies selectable with the task "subject_strategy" setting.
+const (
+	TopicNameStrategy       = "topic_name"
+	RecordNameStrategy      = "record_name"
+	TopicRecordNameStrategy = "topic_record_name"
+)
+
+// ValueSubject returns the registry subject of a task schema.
+func ValueSubject(schema string) string {
+	return schema + "-value"
+}
+
+// KeySubject returns the registry subject of a key schema.
+func KeySubject(schema string) string {
+	return schema + "-key"
+}
+
+// Subject returns the subject of schema for a message on topic under strategy.
+// topic_name names the subject after the schema with a -value or -key suffix,
+// record_name uses the schema as the fully qualified record name and
+// topic_record_name prefixes that record name with the topic.
+func Subject(strategy, topic, schema string, key bool) (string, error) {
+	switch strategy {
+	case "", TopicNameStrategy:
+		if key {
+			return KeySubject(schema), nil
+		}
+		return ValueSubject(schema), nil
+	case RecordNameStrategy:
+		return schema, nil
+	case TopicRecordNameStrategy:
+		return topic + "-" + schema, nil
+	default:
+		return "", fmt.Errorf("unsupported subject strategy %q, expected %s, %s or %s", strategy, TopicNameStrategy, RecordNameStrategy, TopicRecordNameStrategy)
+	}
+}
+
+// KeySchema returns the key schema of a task, the task schema when not set under
+// topic_name. Record strategies need a key schema of their own, as the key would
+// otherwise share the value subject.
+func KeySchema(cfg config.TaskConfig) (string, error) {
+	if cfg.Key.Schema != "" {
+		return cfg.Key.Schema, nil
+	}
+	if cfg.SubjectStrategy == "" || cfg.SubjectStrategy == TopicNameStrategy {
+		return cfg.Schema, nil
+	}
+	return "", fmt.Errorf("key schema is required with the %s subject strategy", cfg.SubjectStrategy)
+}
+
+// taskSchema is a subject a task publishes to, with its local schema file and pinned version.
+type taskSchema struct {
+	subject string
+	file    string
+	version int
+	key     bool
+}
+
+// taskSchemas returns the subjects of a task, its routes and its key. Under
+// topic_record_name, route topics with field references are skipped as their
+// subjects are only known when publishing.
+func taskSchemas(cfg config.TaskConfig) ([]taskSchema, error) {
+	var schemas []taskSchema
+	add := func(topic, schema, file string, version int, key bool) error {
+		if schema == "" {
+			return nil
+		}
+		subject, err := Subject(cfg.SubjectStrategy, topic, schema, key)
+		if err != nil {
+			return err
+		}
+		schemas = append(schemas, taskSchema{subject: subject, file: file, version: version, key: key})
+		return nil
+	}
+
+	if err := add(cfg.Topic, cfg.Schema, cfg.SchemaFile, cfg.SchemaVersion, false); err != nil {
+		return nil, err
+	}
+	for _, route := range cfg.Routes {
+		if cfg.SubjectStrategy == TopicRecordNameStrategy && strings.Contains(route.Topic, "{") {
+			continue
+		}
+		schema, file := route.Schema, route.SchemaFile
+		if schema == "" {
+			schema, file = cfg.Schema, cfg.SchemaFile
+		}
+		if err := add(route.Topic, schema, file, 0, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Key.Field != "" {
+		keySchema, err := KeySchema(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(cfg.Topic, keySchema, cfg.Key.SchemaFile, 0, true); err != nil {
+			return nil, err
+		}
+		for _, route := range cfg.Routes {
+			if cfg.SubjectStrategy == TopicRecordNameStrategy && !strings.Contains(route.Topic, "{") {
+				if err := add(route.Topic, keySchema, cfg.Key.SchemaFile, 0, true); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return schemas, nil
+}
+
+// TaskSubjects returns the subjects of the task, route and key schemas that declare
+// a local schema file. A subject declared with two different files is an error.
+func TaskSubjects(tasks []config.TaskConfig) ([]SubjectFile, error) {
+	files := make(map[string]string)
+	for _, task := range tasks {
+		schemas, err := taskSchemas(task)
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", task.Name, err)
+		}
+		for _, schema := range schemas {
+			if schema.file == "" {
+				continue
+			}
+			if existing, ok := files[schema.subject]; ok && existing != schema.file {
+				return nil, fmt.Errorf("subject %s is declared with schema files %s and %s by task %s", schema.subject, existing, schema.file, task.Name)
+			}
+			files[schema.subject] = schema.file
+		}
+	}
+
+	subjects := make([]SubjectFile, 0, len(files))
+	for subject, file := range files {
+		subjects = append(subjects, SubjectFile{Subject: subject, File: file})
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].Subject < subjects[j].Subject })
+	return subjects, nil
+}
+
+// SchemaManager registers local Avro schema files and checks their compatibility.
+type SchemaManager struct {
+	client RegistryInterface
+}
+
+func NewSchemaManager(client RegistryInterface) *SchemaManager {
+	return &SchemaManager{client: client}
+}
+
+// Register registers the schema file under subject and returns its id.
+func (m *SchemaManager) Register(subject, file string) (int, error) {
+	info, err := ReadSchemaFile(file)
+	if err != nil {
+		return 0, err
+	}
+	id, err := m.client.Register(subject, info, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register %s for subject %s: %w", file, subject, err)
+	}
+	return id, nil
+}
+
+// SetCompatibility sets the compatibility level of subject, e.g. BACKWARD or FULL_TRANSITIVE.
+func (m *SchemaManager) SetCompatibility(subject, level string) error {
+	var compatibility schemaregistry.Compatibility
+	if err := compatibility.ParseString(level); err != nil || level == "" {
+		return fmt.Errorf("invalid compatibility level %q", level)
+	}
+	if _, err := m.client.UpdateCompatibility(subject, compatibility); err != nil {
+		return fmt.Errorf("failed to set compatibility of subject %s: %w", subject, err)
+	}
+	return nil
+}
+
+// CheckCompatibility reports whether the schema file is compatible with the latest
+// version of subject. A subject without versions accepts any schema.
+func (m *SchemaManager) CheckCompatibility(subject, file string) (bool, error) {
+	info, err := ReadSchemaFile(file)
+	if err != nil {
+		return false, err
+	}
+	compatible, err := m.client.TestCompatibility(subject, latestVersion, info)
+	if err != nil {
+		if hasErrorCode(err, subjectNotFound) || hasErrorCode(err, versionNotFound) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to check %s against subject %s: %w", file, subject, err)
+	}
+	return compatible, nil
+}
+
+// VerifyTask verifies a task model against the registered schemas of the task and
+// its routes. The task schema is read at SchemaVersion when pinned, route schemas
+// and unpinned schemas at their latest version. Issues are returned by subject.
+// With autoRegister, unpinned subjects that are not registered yet are skipped
+// with a warning: the serializer registers the model schema on the first message.
+func (m *SchemaManager) VerifyTask(cfg config.TaskConfig, model reflect.Type, autoRegister bool) (map[string][]FieldIssue, error) {
+	schemas, err := taskSchemas(cfg)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]int)
+	for _, schema := range schemas {
+		if _, ok := versions[schema.subject]; !schema.key && !ok {
+			versions[schema.subject] = schema.version
+		}
+	}
+
+	issues := make(map[string][]FieldIssue)
+	for subject, version := range versions {
+		schema, err := m.fetchSchema(subject, version)
+		if err != nil {
+			if autoRegister && version <= 0 && hasErrorCode(err, subjectNotFound) {
+				log.Printf("Subject %s is not registered, registered on the first message, model not verified", subject)
+				continue
+			}
+			return nil, err
+		}
+		if subjectIssues := VerifyModel(model, schema); len(subjectIssues) > 0 {
+			issues[subject] = subjectIssues
+		}
+	}
+	return issues, nil
+}
+
+// VerifyTasks verifies the model of every task, looked up by task name, and logs
+// the issues found. It returns false when a model has a fatal issue or cannot be verified.
+// Tasks using another format than Avro are skipped, see VerifyTask for autoRegister.
+func (m *SchemaManager) VerifyTasks(cfgs []config.TaskConfig, modelOf func(name string) (reflect.Type, error), autoRegister bool) bool {
+	ok := true
+	for _, cfg := range cfgs {
+		if cfg.Format != "" && cfg.Format != Format {
+			log.Printf("Task %s uses the %s format, model not verified", cfg.Name, cfg.Format)
+			continue
+		}
+
+		model, err := modelOf(cfg.Name)
+		if err != nil {
+			log.Printf("Failed to verify task %s: %v", cfg.Name, err)
+			ok = false
+			continue
+		}
+
+		issues, err := m.VerifyTask(cfg, model, autoRegister)
+		if err != nil {
+			log.Printf("Failed to verify task %s: %v", cfg.Name, err)
+			ok = false
+			continue
+		}
+
+		for subject, subjectIssues := range issues {
+			for _, issue := range subjectIssues {
+				log.Printf("Task %s, model %s, subject %s: %s", cfg.Name, model, subject, issue)
+			}
+			if HasFatal(subjectIssues) {
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// hasErrorCode reports whether err is a registry error with code.
+func hasErrorCode(err error, code int) bool {
+	var restErr *rest.Error
+	return errors.As(err, &restErr) && restErr.Code == code
+}
+
+// fetchSchema returns the parsed schema of subject at version, the latest when version is 0.
+func (m *SchemaManager) fetchSchema(subject string, version int) (hamba.Schema, error) {
+	var metadata schemaregistry.SchemaMetadata
+	var err error
+	if version > 0 {
+		metadata, err = m.client.GetSchemaMetadata(subject, version)
+	} else {
+		metadata, err = m.client.GetLatestSchemaMetadata(subject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema of subject %s: %w", subject, err)
+	}
+
+	schema, err := hamba.ParseWithCache(metadata.Schema, "", &hamba.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema of subject %s version %d: %w", subject, metadata.Version, err)
+	}
+	return schema, nil
+}
+
+// ReadSchemaFile reads a schema file. Files ending in .proto are Protobuf schemas,
+// files ending in .json JSON Schemas and any other file an Avro schema.
+func ReadSchemaFile(file string) (schemaregistry.SchemaInfo, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return schemaregistry.SchemaInfo{}, fmt.Errorf("failed to read schema file: %w", err)
+	}
+	schemaType := "AVRO"
+	switch filepath.Ext(file) {
+	case ".proto":
+		schemaType = "PROTOBUF"
+	case ".json":
+		schemaType = "JSON"
+	}
+	return schemaregistry.SchemaInfo{Schema: string(data), SchemaType: schemaType}, nil
+}
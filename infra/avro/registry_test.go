@@ -0,0 +1,326 @@
+package avro
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRegistry struct {
+	mock.Mock
+}
+
+func (m *MockRegistry) Register(subject string, schema schemaregistry.SchemaInfo, normalize bool) (int, error) {
+	args := m.Called(subject, schema, normalize)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRegistry) TestCompatibility(subject string, version int, schema schemaregistry.SchemaInfo) (bool, error) {
+	args := m.Called(subject, version, schema)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRegistry) UpdateCompatibility(subject string, update schemaregistry.Compatibility) (schemaregistry.Compatibility, error) {
+	args := m.Called(subject, update)
+	return update, args.Error(0)
+}
+
+func (m *MockRegistry) GetLatestSchemaMetadata(subject string) (schemaregistry.SchemaMetadata, error) {
+	args := m.Called(subject)
+	return args.Get(0).(schemaregistry.SchemaMetadata), args.Error(1)
+}
+
+func (m *MockRegistry) GetSchemaMetadata(subject string, version int) (schemaregistry.SchemaMetadata, error) {
+	args := m.Called(subject, version)
+	return args.Get(0).(schemaregistry.SchemaMetadata), args.Error(1)
+}
+
+const testSchema = `{"type": "record", "name": "Users", "fields": [{"name": "user_id", "type": "long"}]}`
+
+func writeSchemaFile(t *testing.T) string {
+	file := filepath.Join(t.TempDir(), "user-schema-value.avsc")
+	assert.NoError(t, os.WriteFile(file, []byte(testSchema), 0644))
+	return file
+}
+
+func TestTaskSubjects(t *testing.T) {
+	// Arrange
+	tasks := []config.TaskConfig{
+		{Name: "user", Schema: "user-schema", SchemaFile: "user.avsc", Routes: []config.RouteConfig{
+			{Topic: "user-country", Schema: "user-country-schema", SchemaFile: "user-country.avsc"},
+			{Topic: "user-active"},
+		}},
+		{Name: "user-copy", Schema: "user-schema", SchemaFile: "user.avsc"},
+		{Name: "undeclared", Schema: "other-schema"},
+	}
+
+	// Act
+	subjects, err := TaskSubjects(tasks)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []SubjectFile{
+		{Subject: "user-country-schema-value", File: "user-country.avsc"},
+		{Subject: "user-schema-value", File: "user.avsc"},
+	}, subjects)
+}
+
+func TestTaskSubjects_StrategiesAndKeys(t *testing.T) {
+	// Arrange
+	tasks := []config.TaskConfig{
+		{Name: "user", Topic: "users", Schema: "kafka.example.Users", SchemaFile: "user.avsc", SubjectStrategy: TopicRecordNameStrategy,
+			Key: config.KeyConfig{Field: "user_id", Schema: "kafka.example.UserKey", SchemaFile: "user-key.avsc"},
+			Routes: []config.RouteConfig{
+				{Field: "status", Value: "deleted", Topic: "users-deleted"},
+				{Field: "country.code", Topic: "users-{country.code}"},
+			}},
+		{Name: "order", Schema: "order-schema", Key: config.KeyConfig{Field: "order_id", SchemaFile: "order-key.avsc"}},
+	}
+
+	// Act
+	subjects, err := TaskSubjects(tasks)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []SubjectFile{
+		{Subject: "order-schema-key", File: "order-key.avsc"},
+		{Subject: "users-deleted-kafka.example.UserKey", File: "user-key.avsc"},
+		{Subject: "users-deleted-kafka.example.Users", File: "user.avsc"},
+		{Subject: "users-kafka.example.UserKey", File: "user-key.avsc"},
+		{Subject: "users-kafka.example.Users", File: "user.avsc"},
+	}, subjects)
+}
+
+func TestSubject(t *testing.T) {
+	// Act
+	topicName, _ := Subject("", "users", "user-schema", false)
+	topicNameKey, _ := Subject(TopicNameStrategy, "users", "user-schema", true)
+	recordName, _ := Subject(RecordNameStrategy, "users", "kafka.example.Users", true)
+	topicRecordName, _ := Subject(TopicRecordNameStrategy, "users", "kafka.example.Users", false)
+	_, err := Subject("by_date", "users", "user-schema", false)
+
+	// Assert
+	assert.Equal(t, "user-schema-value", topicName)
+	assert.Equal(t, "user-schema-key", topicNameKey)
+	assert.Equal(t, "kafka.example.Users", recordName)
+	assert.Equal(t, "users-kafka.example.Users", topicRecordName)
+	assert.EqualError(t, err, `unsupported subject strategy "by_date", expected topic_name, record_name or topic_record_name`)
+}
+
+func TestKeySchema(t *testing.T) {
+	// Act
+	defaulted, defaultErr := KeySchema(config.TaskConfig{Schema: "user-schema"})
+	declared, declaredErr := KeySchema(config.TaskConfig{Schema: "kafka.example.Users", SubjectStrategy: RecordNameStrategy, Key: config.KeyConfig{Schema: "kafka.example.UserKey"}})
+	_, missingErr := KeySchema(config.TaskConfig{Schema: "kafka.example.Users", SubjectStrategy: RecordNameStrategy})
+
+	// Assert
+	assert.NoError(t, defaultErr)
+	assert.Equal(t, "user-schema", defaulted)
+	assert.NoError(t, declaredErr)
+	assert.Equal(t, "kafka.example.UserKey", declared)
+	assert.EqualError(t, missingErr, "key schema is required with the record_name subject strategy")
+}
+
+func TestTaskSubjects_Conflict(t *testing.T) {
+	// Arrange
+	tasks := []config.TaskConfig{
+		{Name: "a", Schema: "user-schema", SchemaFile: "a.avsc"},
+		{Name: "b", Schema: "user-schema", SchemaFile: "b.avsc"},
+	}
+
+	// Act
+	_, err := TaskSubjects(tasks)
+
+	// Assert
+	assert.EqualError(t, err, "subject user-schema-value is declared with schema files a.avsc and b.avsc by task b")
+}
+
+func TestSchemaManager_Register(t *testing.T) {
+	// Arrange
+	registry := new(MockRegistry)
+	info := schemaregistry.SchemaInfo{Schema: testSchema, SchemaType: "AVRO"}
+	registry.On("Register", "user-schema-value", info, false).Return(7, nil)
+	manager := NewSchemaManager(registry)
+
+	// Act
+	id, err := manager.Register("user-schema-value", writeSchemaFile(t))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 7, id)
+	registry.AssertExpectations(t)
+}
+
+func TestSchemaManager_RegisterProtobuf(t *testing.T) {
+	// Arrange
+	schema := `syntax = "proto3"; message Users { int64 user_id = 1; }`
+	file := filepath.Join(t.TempDir(), "user-schema-value.proto")
+	assert.NoError(t, os.WriteFile(file, []byte(schema), 0644))
+	registry := new(MockRegistry)
+	info := schemaregistry.SchemaInfo{Schema: schema, SchemaType: "PROTOBUF"}
+	registry.On("Register", "user-schema-value", info, false).Return(8, nil)
+	manager := NewSchemaManager(registry)
+
+	// Act
+	id, err := manager.Register("user-schema-value", file)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 8, id)
+	registry.AssertExpectations(t)
+}
+
+func TestSchemaManager_SetCompatibility(t *testing.T) {
+	// Arrange
+	registry := new(MockRegistry)
+	registry.On("UpdateCompatibility", "user-schema-value", schemaregistry.Compatibility(schemaregistry.BackwardTransitive)).Return(nil)
+	manager := NewSchemaManager(registry)
+
+	// Act
+	err := manager.SetCompatibility("user-schema-value", "BACKWARD_TRANSITIVE")
+	invalidErr := manager.SetCompatibility("user-schema-value", "SIDEWAYS")
+	emptyErr := manager.SetCompatibility("user-schema-value", "")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.ErrorContains(t, invalidErr, `invalid compatibility level "SIDEWAYS"`)
+	assert.ErrorContains(t, emptyErr, `invalid compatibility level ""`)
+	registry.AssertExpectations(t)
+}
+
+func TestSchemaManager_CheckCompatibility(t *testing.T) {
+	// Arrange
+	file := writeSchemaFile(t)
+	info := schemaregistry.SchemaInfo{Schema: testSchema, SchemaType: "AVRO"}
+	registry := new(MockRegistry)
+	registry.On("TestCompatibility", "compatible-value", -1, info).Return(true, nil)
+	registry.On("TestCompatibility", "incompatible-value", -1, info).Return(false, nil)
+	registry.On("TestCompatibility", "new-value", -1, info).Return(false, &rest.Error{Code: 40401, Message: "Subject not found"})
+	registry.On("TestCompatibility", "down-value", -1, info).Return(false, errors.New("connection refused"))
+	manager := NewSchemaManager(registry)
+
+	// Act & Assert
+	compatible, err := manager.CheckCompatibility("compatible-value", file)
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	compatible, err = manager.CheckCompatibility("incompatible-value", file)
+	assert.NoError(t, err)
+	assert.False(t, compatible)
+
+	compatible, err = manager.CheckCompatibility("new-value", file)
+	assert.NoError(t, err)
+	assert.True(t, compatible)
+
+	_, err = manager.CheckCompatibility("down-value", file)
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+func TestSchemaManager_VerifyTask(t *testing.T) {
+	// Arrange
+	type user struct {
+		ID int64 `avro:"user_id"`
+	}
+	registry := new(MockRegistry)
+	registry.On("GetSchemaMetadata", "user-schema-value", 3).Return(schemaregistry.SchemaMetadata{
+		SchemaInfo: schemaregistry.SchemaInfo{Schema: testSchema},
+		Version:    3,
+	}, nil)
+	registry.On("GetLatestSchemaMetadata", "user-country-schema-value").Return(schemaregistry.SchemaMetadata{
+		SchemaInfo: schemaregistry.SchemaInfo{Schema: `{"type": "record", "name": "Users", "fields": [{"name": "country_code", "type": "string"}]}`},
+		Version:    1,
+	}, nil)
+	manager := NewSchemaManager(registry)
+	cfg := config.TaskConfig{
+		Name:          "user",
+		Schema:        "user-schema",
+		SchemaVersion: 3,
+		Routes:        []config.RouteConfig{{Field: "country.code", Topic: "user-{country.code}", Schema: "user-country-schema"}},
+	}
+
+	// Act
+	issues, err := manager.VerifyTask(cfg, reflect.TypeOf(user{}), false)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]FieldIssue{
+		"user-country-schema-value": {
+			{Field: "country_code", Problem: "missing in model", Fatal: true},
+			{Field: "user_id", Problem: "not in schema, ignored when serializing"},
+		},
+	}, issues)
+	registry.AssertExpectations(t)
+}
+
+func TestSchemaManager_VerifyTaskRegistryError(t *testing.T) {
+	// Arrange
+	registry := new(MockRegistry)
+	registry.On("GetLatestSchemaMetadata", "user-schema-value").Return(schemaregistry.SchemaMetadata{}, errors.New("connection refused"))
+	manager := NewSchemaManager(registry)
+
+	// Act
+	_, err := manager.VerifyTask(config.TaskConfig{Name: "user", Schema: "user-schema"}, reflect.TypeOf(struct{}{}), false)
+
+	// Assert
+	assert.ErrorContains(t, err, "failed to get schema of subject user-schema-value")
+}
+
+func TestSchemaManager_VerifyTaskUnregisteredSubject(t *testing.T) {
+	notFound := &rest.Error{Code: 40401, Message: "Subject not found"}
+	testCases := []struct {
+		name          string
+		cfg           config.TaskConfig
+		autoRegister  bool
+		expectedErr   string
+		expectedCalls string
+	}{
+		{name: "auto-registered", cfg: config.TaskConfig{Name: "user", Schema: "user-schema"}, autoRegister: true, expectedCalls: "GetLatestSchemaMetadata"},
+		{name: "not auto-registered", cfg: config.TaskConfig{Name: "user", Schema: "user-schema"}, expectedErr: "failed to get schema of subject user-schema-value", expectedCalls: "GetLatestSchemaMetadata"},
+		{name: "pinned version", cfg: config.TaskConfig{Name: "user", Schema: "user-schema", SchemaVersion: 2}, autoRegister: true, expectedErr: "failed to get schema of subject user-schema-value", expectedCalls: "GetSchemaMetadata"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			registry := new(MockRegistry)
+			registry.On("GetLatestSchemaMetadata", "user-schema-value").Return(schemaregistry.SchemaMetadata{}, notFound).Maybe()
+			registry.On("GetSchemaMetadata", "user-schema-value", 2).Return(schemaregistry.SchemaMetadata{}, notFound).Maybe()
+			manager := NewSchemaManager(registry)
+
+			// Act
+			issues, err := manager.VerifyTask(tc.cfg, reflect.TypeOf(struct{}{}), tc.autoRegister)
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Empty(t, issues)
+			}
+			registry.AssertNumberOfCalls(t, tc.expectedCalls, 1)
+		})
+	}
+}
+
+func TestSchemaManager_VerifyTasksSkipsOtherFormats(t *testing.T) {
+	// Arrange
+	registry := new(MockRegistry)
+	manager := NewSchemaManager(registry)
+	modelOf := func(name string) (reflect.Type, error) { return reflect.TypeOf(struct{}{}), nil }
+
+	// Act
+	ok := manager.VerifyTasks([]config.TaskConfig{{Name: "user", Schema: "user-schema", Format: "protobuf"}}, modelOf, false)
+
+	// Assert
+	assert.True(t, ok)
+	registry.AssertNotCalled(t, "GetLatestSchemaMetadata", mock.Anything)
+}
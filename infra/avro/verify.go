@@ -0,0 +1,226 @@
+package avro
+
+import (
+	"encoding"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	hamba "github.com/hamba/avro/v2"
+)
+
+// DefaultTag is the struct tag holding the Avro default of a model field.
+const DefaultTag = "avro_default"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	ratType           = reflect.TypeOf(big.Rat{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// FieldIssue is a difference between a Go model and an Avro record schema.
+// Fatal issues make serialization fail, the others are reported only.
+type FieldIssue struct {
+	Field   string
+	Problem string
+	Fatal   bool
+}
+
+func (i FieldIssue) String() string {
+	return i.Field + ": " + i.Problem
+}
+
+// HasFatal reports whether any of the issues makes serialization fail.
+func HasFatal(issues []FieldIssue) bool {
+	for _, issue := range issues {
+		if issue.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyModel compares the avro tags of a model struct with a record schema:
+// field names, types, optionality and defaults.
+func VerifyModel(model reflect.Type, schema hamba.Schema) []FieldIssue {
+	for model.Kind() == reflect.Ptr {
+		model = model.Elem()
+	}
+	return verifyType(model, schema, "")
+}
+
+// modelField is an exported struct field and the Avro field name it maps to.
+type modelField struct {
+	name  string
+	field reflect.StructField
+}
+
+// modelFields returns the fields of a struct by Avro name, as the serializer maps them:
+// the avro tag, or the Go field name when untagged.
+func modelFields(t reflect.Type) []modelField {
+	var fields []modelField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag, _, _ := strings.Cut(field.Tag.Get("avro"), ",")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = field.Name
+		}
+		fields = append(fields, modelField{name: tag, field: field})
+	}
+	return fields
+}
+
+func verifyRecord(t reflect.Type, record *hamba.RecordSchema, path string) []FieldIssue {
+	var issues []FieldIssue
+
+	fields := make(map[string]reflect.StructField)
+	for _, f := range modelFields(t) {
+		fields[f.name] = f.field
+	}
+
+	seen := make(map[string]bool)
+	for _, schemaField := range record.Fields() {
+		name := joinPath(path, schemaField.Name())
+		seen[schemaField.Name()] = true
+
+		field, ok := fields[schemaField.Name()]
+		if !ok {
+			if schemaField.HasDefault() {
+				issues = append(issues, FieldIssue{Field: name, Problem: "missing in model, the schema default is used"})
+			} else {
+				issues = append(issues, FieldIssue{Field: name, Problem: "missing in model", Fatal: true})
+			}
+			continue
+		}
+
+		issues = append(issues, verifyType(field.Type, schemaField.Type(), name)...)
+		issues = append(issues, verifyDefault(field, schemaField, name)...)
+	}
+
+	for _, f := range modelFields(t) {
+		if !seen[f.name] {
+			issues = append(issues, FieldIssue{Field: joinPath(path, f.name), Problem: "not in schema, ignored when serializing"})
+		}
+	}
+
+	return issues
+}
+
+func verifyDefault(field reflect.StructField, schemaField *hamba.Field, name string) []FieldIssue {
+	declared, ok := field.Tag.Lookup(DefaultTag)
+	if !ok {
+		return nil
+	}
+	if !schemaField.HasDefault() {
+		return []FieldIssue{{Field: name, Problem: fmt.Sprintf("model default %q, none in schema", declared)}}
+	}
+	if actual := fmt.Sprint(schemaField.Default()); actual != declared {
+		return []FieldIssue{{Field: name, Problem: fmt.Sprintf("model default %q, schema default %q", declared, actual)}}
+	}
+	return nil
+}
+
+func verifyType(t reflect.Type, schema hamba.Schema, path string) []FieldIssue {
+	if ref, ok := schema.(*hamba.RefSchema); ok {
+		schema = ref.Schema()
+	}
+	if t.Kind() == reflect.Interface {
+		return nil // any value is checked when serializing
+	}
+
+	if union, ok := schema.(*hamba.UnionSchema); ok {
+		if !union.Nullable() {
+			return nil // only optional unions are verified
+		}
+		if t.Kind() != reflect.Ptr {
+			return []FieldIssue{{Field: path, Problem: fmt.Sprintf("optional in schema, %s is not a pointer", t), Fatal: true}}
+		}
+		_, typ := union.Indices()
+		return verifyType(t.Elem(), union.Types()[typ], path)
+	}
+
+	var issues []FieldIssue
+	if t.Kind() == reflect.Ptr {
+		issues = append(issues, FieldIssue{Field: path, Problem: "pointer in model, required in schema: nil values fail to serialize"})
+		t = t.Elem()
+	}
+
+	switch schema.Type() {
+	case hamba.Record:
+		if t.Kind() != reflect.Struct || t == timeType {
+			return append(issues, mismatch(t, schema, path))
+		}
+		return append(issues, verifyRecord(t, schema.(*hamba.RecordSchema), path)...)
+	case hamba.Array:
+		if t.Kind() != reflect.Slice {
+			return append(issues, mismatch(t, schema, path))
+		}
+		return append(issues, verifyType(t.Elem(), schema.(*hamba.ArraySchema).Items(), path+"[]")...)
+	case hamba.Map:
+		if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+			return append(issues, mismatch(t, schema, path))
+		}
+		return append(issues, verifyType(t.Elem(), schema.(*hamba.MapSchema).Values(), path+"{}")...)
+	}
+
+	if !primitiveMatches(t, schema) {
+		issues = append(issues, mismatch(t, schema, path))
+	}
+	return issues
+}
+
+// primitiveMatches reports whether the serializer encodes Go type t as the schema type.
+func primitiveMatches(t reflect.Type, schema hamba.Schema) bool {
+	switch schema.Type() {
+	case hamba.Null:
+		return true
+	case hamba.Boolean:
+		return t.Kind() == reflect.Bool
+	case hamba.String, hamba.Enum:
+		return t.Kind() == reflect.String || t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+	case hamba.Int:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+			return true
+		}
+		return t == timeType || t == durationType
+	case hamba.Long:
+		if t == timeType || t == durationType {
+			return true
+		}
+		switch t.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Uint32:
+			return true
+		}
+	case hamba.Float:
+		return t.Kind() == reflect.Float32
+	case hamba.Double:
+		return t.Kind() == reflect.Float64 || t.Kind() == reflect.Float32
+	case hamba.Bytes:
+		return (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8) || t == ratType
+	case hamba.Fixed:
+		fixed := schema.(*hamba.FixedSchema)
+		return (t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8 && t.Len() == fixed.Size()) || t == ratType
+	}
+	return false
+}
+
+func mismatch(t reflect.Type, schema hamba.Schema, path string) FieldIssue {
+	return FieldIssue{Field: path, Problem: fmt.Sprintf("%s cannot be serialized as %s", t, schema.Type()), Fatal: true}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
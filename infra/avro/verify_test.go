@@ -0,0 +1,126 @@
+package avro
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"kafka-go-example/models"
+
+	hamba "github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const verifySchema = `{
+  "type": "record",
+  "name": "Users",
+  "fields": [
+    {"name": "user_id", "type": "long"},
+    {"name": "name", "type": "string"},
+    {"name": "status", "type": "string", "default": "active"},
+    {"name": "nickname", "type": ["null", "string"], "default": null},
+    {"name": "created_at", "type": "string"},
+    {"name": "tags", "type": {"type": "array", "items": "string"}},
+    {"name": "country", "type": {
+      "type": "record",
+      "name": "Country",
+      "fields": [
+        {"name": "code", "type": "string"},
+        {"name": "name", "type": "string"}
+      ]
+    }}
+  ]
+}`
+
+type verifyCountry struct {
+	Code string `avro:"code"`
+	Name string `avro:"name"`
+}
+
+type verifyUser struct {
+	ID        int64         `avro:"user_id"`
+	Name      string        `avro:"name"`
+	Status    string        `avro:"status" avro_default:"active"`
+	Nickname  *string       `avro:"nickname"`
+	CreatedAt time.Time     `avro:"created_at"`
+	Tags      []string      `avro:"tags"`
+	Country   verifyCountry `avro:"country"`
+	internal  string
+}
+
+func TestVerifyModel_Matches(t *testing.T) {
+	// Arrange
+	schema := hamba.MustParse(verifySchema)
+
+	// Act
+	issues := VerifyModel(reflect.TypeOf(verifyUser{}), schema)
+
+	// Assert
+	assert.Empty(t, issues)
+}
+
+func TestVerifyModel_Mismatches(t *testing.T) {
+	// Arrange
+	schema := hamba.MustParse(verifySchema)
+	type country struct {
+		Code int `avro:"code"`
+	}
+	type user struct {
+		ID       string   `avro:"user_id"`
+		Nickname string   `avro:"nickname"`
+		Status   string   `avro:"status" avro_default:"inactive"`
+		Tags     []int    `avro:"tags"`
+		Country  *country `avro:"country"`
+		Email    string   `avro:"email"`
+	}
+
+	// Act
+	issues := VerifyModel(reflect.TypeOf(&user{}), schema)
+
+	// Assert
+	assert.Equal(t, []FieldIssue{
+		{Field: "user_id", Problem: "string cannot be serialized as long", Fatal: true},
+		{Field: "name", Problem: "missing in model", Fatal: true},
+		{Field: "status", Problem: `model default "inactive", schema default "active"`},
+		{Field: "nickname", Problem: "optional in schema, string is not a pointer", Fatal: true},
+		{Field: "created_at", Problem: "missing in model", Fatal: true},
+		{Field: "tags[]", Problem: "int cannot be serialized as string", Fatal: true},
+		{Field: "country", Problem: "pointer in model, required in schema: nil values fail to serialize"},
+		{Field: "country.code", Problem: "int cannot be serialized as string", Fatal: true},
+		{Field: "country.name", Problem: "missing in model", Fatal: true},
+		{Field: "email", Problem: "not in schema, ignored when serializing"},
+	}, issues)
+	assert.True(t, HasFatal(issues))
+}
+
+func TestVerifyModel_MissingFieldWithDefault(t *testing.T) {
+	// Arrange
+	schema := hamba.MustParse(`{"type": "record", "name": "R", "fields": [
+		{"name": "id", "type": "long"},
+		{"name": "status", "type": "string", "default": "active"}
+	]}`)
+	type model struct {
+		ID int64 `avro:"id"`
+	}
+
+	// Act
+	issues := VerifyModel(reflect.TypeOf(model{}), schema)
+
+	// Assert
+	assert.Equal(t, []FieldIssue{{Field: "status", Problem: "missing in model, the schema default is used"}}, issues)
+	assert.False(t, HasFatal(issues))
+}
+
+func TestVerifyModel_UserSchema(t *testing.T) {
+	// Arrange
+	data, err := os.ReadFile("../../docker/kafka/user-schema-value.avsc")
+	assert.NoError(t, err)
+	schema := hamba.MustParse(string(data))
+
+	// Act
+	issues := VerifyModel(reflect.TypeOf(models.User{}), schema)
+
+	// Assert
+	assert.Empty(t, issues)
+}
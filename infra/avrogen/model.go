@@ -0,0 +1,239 @@
+package avrogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+
+	"kafka-go-example/infra/avro"
+
+	hamba "github.com/hamba/avro/v2"
+)
+
+// initialisms are the name parts written in upper case in Go identifiers.
+var initialisms = map[string]bool{
+	"api": true, "db": true, "http": true, "id": true, "ip": true,
+	"json": true, "sku": true, "sql": true, "url": true, "uuid": true,
+}
+
+// ModelOptions configures GenerateModels.
+type ModelOptions struct {
+	Package    string   // Go package name, "models" when empty
+	TypeName   string   // Go type of the top-level record, the record name when empty
+	Source     string   // Schema file or subject named in the generated header
+	DBTags     bool     // Add db tags named after the Avro fields
+	TimeFields []string // String fields holding RFC 3339 timestamps, generated as time.Time
+}
+
+// modelGenerator writes one Go struct per Avro record.
+type modelGenerator struct {
+	opts       ModelOptions
+	timeFields map[string]bool
+	structs    []string
+	defined    map[string]string // Go type name by record full name
+	usesTime   bool
+}
+
+// GenerateModels returns Go source declaring a struct for the record schema and
+// every nested record, with avro tags and, optionally, db tags.
+func GenerateModels(schema string, opts ModelOptions) ([]byte, error) {
+	parsed, err := hamba.ParseWithCache(schema, "", &hamba.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	record, ok := parsed.(*hamba.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("schema is a %s, expected a record", parsed.Type())
+	}
+	if opts.Package == "" {
+		opts.Package = "models"
+	}
+
+	g := &modelGenerator{
+		opts:       opts,
+		timeFields: make(map[string]bool),
+		defined:    make(map[string]string),
+	}
+	for _, field := range opts.TimeFields {
+		g.timeFields[field] = true
+	}
+	if _, err := g.record(record, opts.TypeName); err != nil {
+		return nil, err
+	}
+
+	var src bytes.Buffer
+	if opts.Source != "" {
+		fmt.Fprintf(&src, "// Code generated by model-gen from %s. DO NOT EDIT.\n\n", opts.Source)
+	} else {
+		src.WriteString("// Code generated by model-gen. DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(&src, "package %s\n\n", opts.Package)
+	if g.usesTime {
+		src.WriteString("import \"time\"\n\n")
+	}
+	src.WriteString(strings.Join(g.structs, "\n"))
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+// record declares the struct of a record once, named name or after the record,
+// and returns its Go type name.
+func (g *modelGenerator) record(record *hamba.RecordSchema, name string) (string, error) {
+	if existing, ok := g.defined[record.FullName()]; ok {
+		return existing, nil
+	}
+	if name == "" {
+		name = goName(record.Name())
+	}
+	for fullName, existing := range g.defined {
+		if existing == name {
+			return "", fmt.Errorf("records %s and %s both map to type %s", fullName, record.FullName(), name)
+		}
+	}
+	g.defined[record.FullName()] = name
+
+	// Reserve the position of this struct before nested records are appended
+	index := len(g.structs)
+	g.structs = append(g.structs, "")
+
+	var body strings.Builder
+	writeComment(&body, record.Doc(), "")
+	fmt.Fprintf(&body, "type %s struct {\n", name)
+	for _, field := range record.Fields() {
+		typ, err := g.typeOf(field.Type(), field.Name())
+		if err != nil {
+			return "", fmt.Errorf("%s.%s: %w", record.Name(), field.Name(), err)
+		}
+
+		tags := []string{}
+		if g.opts.DBTags {
+			tags = append(tags, fmt.Sprintf("db:%q", field.Name()))
+		}
+		tags = append(tags, fmt.Sprintf("avro:%q", field.Name()))
+		if def, ok := scalarDefault(field); ok {
+			tags = append(tags, fmt.Sprintf("%s:%q", avro.DefaultTag, def))
+		}
+
+		writeComment(&body, field.Doc(), "\t")
+		fmt.Fprintf(&body, "\t%s %s `%s`\n", goName(field.Name()), typ, strings.Join(tags, " "))
+	}
+	body.WriteString("}\n")
+
+	g.structs[index] = body.String()
+	return name, nil
+}
+
+// typeOf returns the Go type of an Avro type; field is the name of the Avro field.
+func (g *modelGenerator) typeOf(schema hamba.Schema, field string) (string, error) {
+	if ref, ok := schema.(*hamba.RefSchema); ok {
+		schema = ref.Schema()
+	}
+
+	if logical, ok := schema.(hamba.LogicalTypeSchema); ok && logical.Logical() != nil {
+		switch logical.Logical().Type() {
+		case hamba.Date, hamba.TimestampMillis, hamba.TimestampMicros, hamba.LocalTimestampMillis, hamba.LocalTimestampMicros:
+			g.usesTime = true
+			return "time.Time", nil
+		case hamba.TimeMillis, hamba.TimeMicros:
+			g.usesTime = true
+			return "time.Duration", nil
+		}
+	}
+
+	switch s := schema.(type) {
+	case *hamba.RecordSchema:
+		return g.record(s, "")
+	case *hamba.UnionSchema:
+		if !s.Nullable() {
+			return "any", nil
+		}
+		_, typ := s.Indices()
+		elem, err := g.typeOf(s.Types()[typ], field)
+		if err != nil {
+			return "", err
+		}
+		return "*" + elem, nil
+	case *hamba.ArraySchema:
+		items, err := g.typeOf(s.Items(), field)
+		return "[]" + items, err
+	case *hamba.MapSchema:
+		values, err := g.typeOf(s.Values(), field)
+		return "map[string]" + values, err
+	case *hamba.FixedSchema:
+		return fmt.Sprintf("[%d]byte", s.Size()), nil
+	}
+
+	switch schema.Type() {
+	case hamba.String:
+		if g.timeFields[field] {
+			g.usesTime = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case hamba.Enum:
+		return "string", nil
+	case hamba.Boolean:
+		return "bool", nil
+	case hamba.Int:
+		return "int32", nil
+	case hamba.Long:
+		return "int64", nil
+	case hamba.Float:
+		return "float32", nil
+	case hamba.Double:
+		return "float64", nil
+	case hamba.Bytes:
+		return "[]byte", nil
+	case hamba.Null:
+		return "any", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", schema.Type())
+}
+
+// scalarDefault returns the default of a field with a primitive default value.
+func scalarDefault(field *hamba.Field) (string, bool) {
+	if !field.HasDefault() {
+		return "", false
+	}
+	switch def := field.Default().(type) {
+	case string, bool, int, int32, int64, float32, float64:
+		return fmt.Sprint(def), true
+	}
+	return "", false
+}
+
+// goName converts an Avro name such as "user_id" to an exported Go identifier, "UserID".
+func goName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+
+	var b strings.Builder
+	for _, part := range parts {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	if b.Len() == 0 {
+		return "Field"
+	}
+	return b.String()
+}
+
+func writeComment(b *strings.Builder, doc, indent string) {
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		if line != "" {
+			fmt.Fprintf(b, "%s// %s\n", indent, strings.TrimSpace(line))
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package avrogen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	hamba "github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const testOrderSchema = `{
+  "type": "record",
+  "name": "order",
+  "namespace": "shop",
+  "doc": "Order is a customer order.",
+  "fields": [
+    {"name": "order_id", "type": "long"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "PAID"]}, "default": "NEW"},
+    {"name": "note", "type": ["null", "string"], "default": null, "doc": "Note left by the customer"},
+    {"name": "quantity", "type": "int", "default": 1},
+    {"name": "total", "type": "double"},
+    {"name": "lines", "type": {"type": "array", "items": {
+      "type": "record", "name": "Line", "fields": [{"name": "sku", "type": "string"}]
+    }}},
+    {"name": "labels", "type": {"type": "map", "values": "string"}},
+    {"name": "first_line", "type": ["null", "Line"], "default": null},
+    {"name": "placed_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "updated_at", "type": "string"},
+    {"name": "checksum", "type": {"type": "fixed", "name": "MD5", "size": 16}},
+    {"name": "payload", "type": "bytes"}
+  ]
+}`
+
+const expectedOrderModels = `// Code generated by model-gen from order.avsc. DO NOT EDIT.
+
+package shop
+
+import "time"
+
+// Order is a customer order.
+type Order struct {
+	OrderID int64  ` + "`db:\"order_id\" avro:\"order_id\"`" + `
+	Status  string ` + "`db:\"status\" avro:\"status\" avro_default:\"NEW\"`" + `
+	// Note left by the customer
+	Note      *string           ` + "`db:\"note\" avro:\"note\"`" + `
+	Quantity  int32             ` + "`db:\"quantity\" avro:\"quantity\" avro_default:\"1\"`" + `
+	Total     float64           ` + "`db:\"total\" avro:\"total\"`" + `
+	Lines     []Line            ` + "`db:\"lines\" avro:\"lines\"`" + `
+	Labels    map[string]string ` + "`db:\"labels\" avro:\"labels\"`" + `
+	FirstLine *Line             ` + "`db:\"first_line\" avro:\"first_line\"`" + `
+	PlacedAt  time.Time         ` + "`db:\"placed_at\" avro:\"placed_at\"`" + `
+	UpdatedAt time.Time         ` + "`db:\"updated_at\" avro:\"updated_at\"`" + `
+	Checksum  [16]byte          ` + "`db:\"checksum\" avro:\"checksum\"`" + `
+	Payload   []byte            ` + "`db:\"payload\" avro:\"payload\"`" + `
+}
+
+type Line struct {
+	SKU string ` + "`db:\"sku\" avro:\"sku\"`" + `
+}
+`
+
+func TestGenerateModels(t *testing.T) {
+	// Act
+	src, err := GenerateModels(testOrderSchema, ModelOptions{
+		Package:    "shop",
+		Source:     "order.avsc",
+		DBTags:     true,
+		TimeFields: []string{"updated_at"},
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, expectedOrderModels, string(src))
+}
+
+func TestGenerateModels_Errors(t *testing.T) {
+	// Act
+	_, invalidErr := GenerateModels(`{"type": "record"`, ModelOptions{})
+	_, notRecordErr := GenerateModels(`"string"`, ModelOptions{})
+	_, conflictErr := GenerateModels(`{"type": "record", "name": "a.Item", "fields": [
+		{"name": "other", "type": {"type": "record", "name": "b.Item", "fields": []}}
+	]}`, ModelOptions{})
+
+	// Assert
+	assert.ErrorContains(t, invalidErr, "failed to parse schema")
+	assert.ErrorContains(t, notRecordErr, "expected a record")
+	assert.ErrorContains(t, conflictErr, "records a.Item and b.Item both map to type Item")
+}
+
+func TestGenerateModels_UserSchemaRoundTrip(t *testing.T) {
+	// Arrange
+	data, err := os.ReadFile("../../docker/kafka/user-schema-value.avsc")
+	assert.NoError(t, err)
+	dir := t.TempDir()
+
+	// Act
+	src, err := GenerateModels(string(data), ModelOptions{
+		TypeName:   "User",
+		DBTags:     true,
+		TimeFields: []string{"created_at", "updated_at"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "user.go"), src, 0644))
+	regenerated, err := GenerateSchema(dir, "User", SchemaOptions{Name: "Users", Namespace: "kafka.example"})
+
+	// Assert
+	assert.NoError(t, err)
+	original := hamba.MustParse(string(data))
+	roundTrip, err := hamba.ParseWithCache(string(regenerated), "", &hamba.SchemaCache{})
+	assert.NoError(t, err)
+	assert.Equal(t, original.String(), roundTrip.String())
+}
@@ -0,0 +1,332 @@
+package avrogen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kafka-go-example/infra/avro"
+)
+
+// timeTypes are the accepted encodings of time.Time. "string" is the
+// RFC 3339 text the serializer writes for time.Time by default.
+var timeTypes = map[string]bool{
+	"string":                 true,
+	"timestamp-millis":       true,
+	"timestamp-micros":       true,
+	"local-timestamp-millis": true,
+	"local-timestamp-micros": true,
+}
+
+// SchemaOptions configures GenerateSchema.
+type SchemaOptions struct {
+	Name      string // Record name, the Go type name when empty
+	Namespace string // Record namespace
+	TimeType  string // Encoding of time.Time, "string" when empty
+}
+
+type recordSchema struct {
+	Type      string        `json:"type"`
+	Name      string        `json:"name"`
+	Namespace string        `json:"namespace,omitempty"`
+	Doc       string        `json:"doc,omitempty"`
+	Fields    []fieldSchema `json:"fields"`
+}
+
+type fieldSchema struct {
+	Name    string          `json:"name"`
+	Doc     string          `json:"doc,omitempty"`
+	Type    any             `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+type arraySchema struct {
+	Type  string `json:"type"`
+	Items any    `json:"items"`
+}
+
+type mapSchema struct {
+	Type   string `json:"type"`
+	Values any    `json:"values"`
+}
+
+type logicalSchema struct {
+	Type        string `json:"type"`
+	LogicalType string `json:"logicalType"`
+}
+
+// schemaGenerator converts the struct declarations of a package into Avro types.
+type schemaGenerator struct {
+	types    map[string]*ast.TypeSpec
+	docs     map[string]string
+	timeType string
+	defined  map[string]bool
+}
+
+// GenerateSchema returns the Avro schema of the struct typeName declared in the
+// Go package in dir. Field names come from avro tags, pointers become optional
+// unions with null, defaults come from avro_default tags and docs from comments.
+func GenerateSchema(dir, typeName string, opts SchemaOptions) ([]byte, error) {
+	if opts.TimeType == "" {
+		opts.TimeType = "string"
+	}
+	if !timeTypes[opts.TimeType] {
+		return nil, fmt.Errorf("unsupported time type %q, expected one of %s", opts.TimeType, strings.Join(sortedKeys(timeTypes), ", "))
+	}
+
+	g := &schemaGenerator{
+		types:    make(map[string]*ast.TypeSpec),
+		docs:     make(map[string]string),
+		timeType: opts.TimeType,
+		defined:  make(map[string]bool),
+	}
+	if err := g.parseDir(dir); err != nil {
+		return nil, err
+	}
+	if _, ok := g.types[typeName]; !ok {
+		return nil, fmt.Errorf("type %s not found in %s", typeName, dir)
+	}
+
+	record, err := g.record(typeName, opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	record.Namespace = opts.Namespace
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// parseDir collects the type declarations and their doc comments of the package in dir.
+func (g *schemaGenerator) parseDir(dir string) error {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", dir, err)
+	}
+
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					g.types[typeSpec.Name.Name] = typeSpec
+					doc := typeSpec.Doc
+					if doc == nil && len(gen.Specs) == 1 {
+						doc = gen.Doc
+					}
+					g.docs[typeSpec.Name.Name] = commentText(doc)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// record returns the record schema of a struct type, named name or the type name.
+func (g *schemaGenerator) record(typeName, name string) (*recordSchema, error) {
+	structType, ok := g.types[typeName].Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct", typeName)
+	}
+	if name == "" {
+		name = typeName
+	}
+	g.defined[typeName] = true
+
+	record := &recordSchema{Type: "record", Name: name, Doc: g.docs[typeName], Fields: []fieldSchema{}}
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			return nil, fmt.Errorf("%s: embedded fields are not supported", typeName)
+		}
+
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid tag %s", typeName, field.Tag.Value)
+			}
+			tag = reflect.StructTag(value)
+		}
+
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(tag.Get("avro"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = ident.Name
+			}
+
+			path := typeName + "." + ident.Name
+			typ, err := g.typeOf(field.Type, path)
+			if err != nil {
+				return nil, err
+			}
+
+			doc := commentText(field.Doc)
+			if doc == "" {
+				doc = commentText(field.Comment)
+			}
+			f := fieldSchema{Name: name, Doc: doc, Type: typ}
+			if value, ok := tag.Lookup(avro.DefaultTag); ok {
+				f.Type, f.Default, err = withDefault(typ, value)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			} else if _, optional := field.Type.(*ast.StarExpr); optional {
+				f.Default = json.RawMessage("null")
+			}
+			record.Fields = append(record.Fields, f)
+		}
+	}
+	return record, nil
+}
+
+// typeOf returns the Avro type of a Go type expression.
+func (g *schemaGenerator) typeOf(expr ast.Expr, path string) (any, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string", nil
+		case "bool":
+			return "boolean", nil
+		case "int", "int64", "uint32":
+			return "long", nil
+		case "int8", "int16", "int32", "uint8", "uint16":
+			return "int", nil
+		case "float32":
+			return "float", nil
+		case "float64":
+			return "double", nil
+		}
+		if _, ok := g.types[t.Name]; !ok {
+			return nil, fmt.Errorf("%s: unsupported type %s", path, t.Name)
+		}
+		if g.defined[t.Name] {
+			return t.Name, nil // named types are defined once and referenced afterwards
+		}
+		return g.record(t.Name, "")
+	case *ast.StarExpr:
+		typ, err := g.typeOf(t.X, path)
+		if err != nil {
+			return nil, err
+		}
+		return []any{"null", typ}, nil
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" && t.Len == nil {
+			return "bytes", nil
+		}
+		if t.Len != nil {
+			return nil, fmt.Errorf("%s: arrays are not supported", path)
+		}
+		items, err := g.typeOf(t.Elt, path)
+		if err != nil {
+			return nil, err
+		}
+		return arraySchema{Type: "array", Items: items}, nil
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return nil, fmt.Errorf("%s: map keys must be strings", path)
+		}
+		values, err := g.typeOf(t.Value, path)
+		if err != nil {
+			return nil, err
+		}
+		return mapSchema{Type: "map", Values: values}, nil
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" && t.Sel.Name == "Time" {
+			if g.timeType == "string" {
+				return "string", nil
+			}
+			return logicalSchema{Type: "long", LogicalType: g.timeType}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: unsupported type %s", path, exprString(expr))
+}
+
+// withDefault returns the field type and JSON default for an avro_default tag value.
+// An optional field with a default lists its type before null, as Avro requires the
+// default to match the first type of a union.
+func withDefault(typ any, value string) (any, json.RawMessage, error) {
+	if union, ok := typ.([]any); ok {
+		if value == "null" {
+			return typ, json.RawMessage("null"), nil
+		}
+		typ = []any{union[1], "null"}
+		def, err := defaultValue(union[1], value)
+		return typ, def, err
+	}
+	def, err := defaultValue(typ, value)
+	return typ, def, err
+}
+
+func defaultValue(typ any, value string) (json.RawMessage, error) {
+	switch typ {
+	case "string", "bytes":
+		data, err := json.Marshal(value)
+		return data, err
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid boolean default %q", value)
+		}
+	case "int", "long":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid integer default %q", value)
+		}
+	case "float", "double":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid number default %q", value)
+		}
+	default:
+		if !json.Valid([]byte(value)) {
+			return nil, fmt.Errorf("default %q must be JSON for complex types", value)
+		}
+	}
+	return json.RawMessage(value), nil
+}
+
+func commentText(group *ast.CommentGroup) string {
+	return strings.TrimSpace(group.Text())
+}
+
+func exprString(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.SelectorExpr:
+		return exprString(t.X) + "." + t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	default:
+		return fmt.Sprintf("%T", expr)
+	}
+}
+
+// sortedKeys returns the keys of a map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,192 @@
+package avrogen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/models"
+
+	hamba "github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const testModels = `package testmodels
+
+import "time"
+
+// Order is a customer order.
+type Order struct {
+	ID       int64             ` + "`avro:\"order_id\"`" + `
+	Status   string            ` + "`avro:\"status\" avro_default:\"new\"`" + ` // Order status
+	// Note left by the customer
+	Note     *string           ` + "`avro:\"note\"`" + `
+	Coupon   *string           ` + "`avro:\"coupon\" avro_default:\"none\"`" + `
+	Total    float64           ` + "`avro:\"total\"`" + `
+	Lines    []Line            ` + "`avro:\"lines\"`" + `
+	Labels   map[string]string ` + "`avro:\"labels\"`" + `
+	Billing  Address           ` + "`avro:\"billing\"`" + `
+	Shipping *Address          ` + "`avro:\"shipping\"`" + `
+	PlacedAt time.Time         ` + "`avro:\"placed_at\"`" + `
+	Internal string            ` + "`avro:\"-\"`" + `
+	cache    string
+}
+
+type Line struct {
+	SKU      string ` + "`avro:\"sku\"`" + `
+	Quantity int32  ` + "`avro:\"quantity\" avro_default:\"1\"`" + `
+}
+
+type Address struct {
+	City string ` + "`avro:\"city\"`" + `
+}
+
+type Unsupported struct {
+	Done chan bool ` + "`avro:\"done\"`" + `
+}
+`
+
+const expectedOrderSchema = `{
+  "type": "record",
+  "name": "Order",
+  "namespace": "shop",
+  "doc": "Order is a customer order.",
+  "fields": [
+    {
+      "name": "order_id",
+      "type": "long"
+    },
+    {
+      "name": "status",
+      "doc": "Order status",
+      "type": "string",
+      "default": "new"
+    },
+    {
+      "name": "note",
+      "doc": "Note left by the customer",
+      "type": [
+        "null",
+        "string"
+      ],
+      "default": null
+    },
+    {
+      "name": "coupon",
+      "type": [
+        "string",
+        "null"
+      ],
+      "default": "none"
+    },
+    {
+      "name": "total",
+      "type": "double"
+    },
+    {
+      "name": "lines",
+      "type": {
+        "type": "array",
+        "items": {
+          "type": "record",
+          "name": "Line",
+          "fields": [
+            {
+              "name": "sku",
+              "type": "string"
+            },
+            {
+              "name": "quantity",
+              "type": "int",
+              "default": 1
+            }
+          ]
+        }
+      }
+    },
+    {
+      "name": "labels",
+      "type": {
+        "type": "map",
+        "values": "string"
+      }
+    },
+    {
+      "name": "billing",
+      "type": {
+        "type": "record",
+        "name": "Address",
+        "fields": [
+          {
+            "name": "city",
+            "type": "string"
+          }
+        ]
+      }
+    },
+    {
+      "name": "shipping",
+      "type": [
+        "null",
+        "Address"
+      ],
+      "default": null
+    },
+    {
+      "name": "placed_at",
+      "type": {
+        "type": "long",
+        "logicalType": "timestamp-millis"
+      }
+    }
+  ]
+}
+`
+
+func writeTestModels(t *testing.T) string {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "models.go"), []byte(testModels), 0644))
+	return dir
+}
+
+func TestGenerateSchema(t *testing.T) {
+	// Arrange
+	dir := writeTestModels(t)
+
+	// Act
+	schema, err := GenerateSchema(dir, "Order", SchemaOptions{Namespace: "shop", TimeType: "timestamp-millis"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, expectedOrderSchema, string(schema))
+	_, err = hamba.ParseWithCache(string(schema), "", &hamba.SchemaCache{})
+	assert.NoError(t, err)
+}
+
+func TestGenerateSchema_Errors(t *testing.T) {
+	// Arrange
+	dir := writeTestModels(t)
+
+	// Act
+	_, notFoundErr := GenerateSchema(dir, "Missing", SchemaOptions{})
+	_, unsupportedErr := GenerateSchema(dir, "Unsupported", SchemaOptions{})
+	_, timeErr := GenerateSchema(dir, "Order", SchemaOptions{TimeType: "date"})
+
+	// Assert
+	assert.ErrorContains(t, notFoundErr, "type Missing not found")
+	assert.ErrorContains(t, unsupportedErr, "Unsupported.Done: unsupported type")
+	assert.ErrorContains(t, timeErr, `unsupported time type "date"`)
+}
+
+func TestGenerateSchema_UserModel(t *testing.T) {
+	// Act
+	data, err := GenerateSchema("../../models", "User", SchemaOptions{Name: "Users", Namespace: "kafka.example"})
+
+	// Assert
+	assert.NoError(t, err)
+	schema, err := hamba.ParseWithCache(string(data), "", &hamba.SchemaCache{})
+	assert.NoError(t, err)
+	assert.Empty(t, avro.VerifyModel(reflect.TypeOf(models.User{}), schema))
+}
@@ -16,6 +16,22 @@ func init() {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	viper.SetDefault("KAFKA_BOOTSTRAP_SERVERS", "localhost:9092")
+	viper.SetDefault("KAFKA_PRODUCER_CONFIG", "")
+	viper.SetDefault("KAFKA_CONSUMER_ASSIGNMENT_STRATEGY", "")
+	viper.SetDefault("KAFKA_CONSUMER_INSTANCE_ID", "")
+	viper.SetDefault("KAFKA_CONSUMER_SESSION_TIMEOUT", "6s")
+	viper.SetDefault("KAFKA_CONSUMER_COMMIT_MODE", "sync")
+	viper.SetDefault("KAFKA_CONSUMER_COMMIT_BATCH_SIZE", 100)
+	viper.SetDefault("KAFKA_CONSUMER_COMMIT_INTERVAL", "5s")
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_ATTEMPTS", 2)
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_BACKOFF", "200ms")
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_MAX_BACKOFF", "5s")
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_DELAYS", "")
+	viper.SetDefault("KAFKA_CONSUMER_DEAD_LETTER", false)
+	viper.SetDefault("KAFKA_CONSUMER_PERMANENT_ERRORS", "skip")
+	viper.SetDefault("KAFKA_CONSUMER_WORKERS", 1)
+	viper.SetDefault("KAFKA_CONSUMER_ORDERING", "partition")
+	viper.SetDefault("KAFKA_CONSUMER_WORKER_QUEUE_SIZE", 100)
 	viper.SetDefault("SCHEMA_REGISTRY_URL", "http://localhost:8081")
 	viper.SetDefault("SCHEMA_REGISTRY_AUTO_REGISTER_SCHEMAS", false)
 	viper.SetDefault("SCHEMA_REGISTRY_USE_LATEST_VERSION", true)
@@ -24,6 +40,11 @@ func init() {
 	viper.SetDefault("MYSQL_USER", "user")
 	viper.SetDefault("MYSQL_PASSWORD", "password")
 	viper.SetDefault("MYSQL_DATABASE", "example")
+	viper.SetDefault("DRY_RUN", false)
+	viper.SetDefault("DRY_RUN_OUTPUT", "")
+	viper.SetDefault("DRY_RUN_DECODE", false)
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("SHUTDOWN_HOOK_TIMEOUT", "10s")
 
 	if _, err := os.Stat(".env"); err == nil {
 		viper.SetConfigFile(".env")
@@ -36,6 +57,28 @@ func init() {
 type KafkaConfig struct {
 	BootstrapServers string `mapstructure:"KAFKA_BOOTSTRAP_SERVERS"`
 	Group            string `mapstructure:"KAFKA_CONSUMER_GROUP"`
+	ProducerConfig   string `mapstructure:"KAFKA_PRODUCER_CONFIG"` // librdkafka producer properties, e.g. "linger.ms=5,compression.type=lz4"
+
+	AssignmentStrategy string        `mapstructure:"KAFKA_CONSUMER_ASSIGNMENT_STRATEGY"` // partition.assignment.strategy, e.g. "cooperative-sticky"
+	InstanceID         string        `mapstructure:"KAFKA_CONSUMER_INSTANCE_ID"`         // group.instance.id for static membership, unique per consumer
+	SessionTimeout     time.Duration `mapstructure:"KAFKA_CONSUMER_SESSION_TIMEOUT"`     // How long the group waits for a missing consumer, or a restarting static member
+}
+
+// ConsumerConfig controls when consumers commit the offsets of handled messages
+// and how failed messages are retried.
+type ConsumerConfig struct {
+	CommitMode      string        `mapstructure:"KAFKA_CONSUMER_COMMIT_MODE"`       // sync or async
+	CommitBatchSize int           `mapstructure:"KAFKA_CONSUMER_COMMIT_BATCH_SIZE"` // sync: messages handled between commits
+	CommitInterval  time.Duration `mapstructure:"KAFKA_CONSUMER_COMMIT_INTERVAL"`   // async: time between background commits
+	RetryAttempts   int           `mapstructure:"KAFKA_CONSUMER_RETRY_ATTEMPTS"`    // In-place retries of a failed message
+	RetryBackoff    time.Duration `mapstructure:"KAFKA_CONSUMER_RETRY_BACKOFF"`     // Wait before the first in-place retry, doubled for each retry
+	RetryMaxBackoff time.Duration `mapstructure:"KAFKA_CONSUMER_RETRY_MAX_BACKOFF"` // Longest wait between in-place retries
+	RetryDelays     string        `mapstructure:"KAFKA_CONSUMER_RETRY_DELAYS"`      // Delays of the retry topics, e.g. "30s,5m"
+	DeadLetter      bool          `mapstructure:"KAFKA_CONSUMER_DEAD_LETTER"`       // Forward messages failing every retry to the dead-letter topic
+	PermanentErrors string        `mapstructure:"KAFKA_CONSUMER_PERMANENT_ERRORS"`  // skip or stop: permanent failures without a dead-letter topic
+	Workers         int           `mapstructure:"KAFKA_CONSUMER_WORKERS"`           // Messages handled concurrently, 1 handles them in the poll loop
+	Ordering        string        `mapstructure:"KAFKA_CONSUMER_ORDERING"`          // partition or key: what workers keep in order
+	WorkerQueueSize int           `mapstructure:"KAFKA_CONSUMER_WORKER_QUEUE_SIZE"` // Messages queued per worker before its partitions are paused
 }
 
 type SchemaRegistryConfig struct {
@@ -55,12 +98,86 @@ type DatabaseConfig struct {
 	Password     string `mapstructure:"MYSQL_PASSWORD"`
 }
 
+// DryRunConfig holds the process wide dry-run settings, applied to every task.
+type DryRunConfig struct {
+	Enabled bool   `mapstructure:"DRY_RUN"`
+	Output  string `mapstructure:"DRY_RUN_OUTPUT"`
+	Decode  bool   `mapstructure:"DRY_RUN_DECODE"`
+}
+
+// ShutdownConfig bounds how long the process waits for running work on shutdown,
+// and then for the shutdown hooks releasing its resources.
+type ShutdownConfig struct {
+	Timeout     time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	HookTimeout time.Duration `mapstructure:"SHUTDOWN_HOOK_TIMEOUT"`
+}
+
+// Sink types selectable with the task "sink.type" setting.
+const (
+	SinkTypeKafka   = "kafka"
+	SinkTypeFile    = "file"
+	SinkTypeStdout  = "stdout"
+	SinkTypeWebhook = "webhook"
+)
+
+// SinkConfig selects where a task publishes its messages. Kafka is used when Type is empty.
+type SinkConfig struct {
+	Type          string            `yaml:"type" mapstructure:"type"`                     // kafka, file, stdout or webhook
+	Decode        bool              `yaml:"decode" mapstructure:"decode"`                 // Decode payloads to JSON values
+	Path          string            `yaml:"path" mapstructure:"path"`                     // File sink output path
+	MaxBytes      int64             `yaml:"max_bytes" mapstructure:"max_bytes"`           // File size that triggers rotation
+	MaxFiles      int               `yaml:"max_files" mapstructure:"max_files"`           // Rotated files to keep
+	URL           string            `yaml:"url" mapstructure:"url"`                       // Webhook endpoint
+	Headers       map[string]string `yaml:"headers" mapstructure:"headers"`               // Webhook request headers, values are env-expanded
+	BatchSize     int               `yaml:"batch_size" mapstructure:"batch_size"`         // Webhook messages per request
+	FlushInterval time.Duration     `yaml:"flush_interval" mapstructure:"flush_interval"` // Webhook max time a message is buffered
+	MaxRetries    int               `yaml:"max_retries" mapstructure:"max_retries"`       // Webhook retries per request
+	Timeout       time.Duration     `yaml:"timeout" mapstructure:"timeout"`               // Webhook request timeout
+}
+
+// RouteConfig sends the rows whose Field equals Value to Topic.
+// An empty Value matches any row, and Topic may reference fields, e.g. "user-{country.code}".
+type RouteConfig struct {
+	Field      string `yaml:"field" mapstructure:"field"`             // Dotted avro field path, e.g. "country.code"
+	Value      string `yaml:"value" mapstructure:"value"`             // Value to match, any value when empty
+	Topic      string `yaml:"topic" mapstructure:"topic"`             // Destination topic
+	Schema     string `yaml:"schema" mapstructure:"schema"`           // Overrides the task schema when set
+	SchemaFile string `yaml:"schema_file" mapstructure:"schema_file"` // Local .avsc file of Schema
+}
+
+// KeyConfig selects the model field used as message key and the schema it is serialized with.
+type KeyConfig struct {
+	Field      string `yaml:"field" mapstructure:"field"`             // Dotted avro field path, e.g. "user_id"
+	Schema     string `yaml:"schema" mapstructure:"schema"`           // Key schema, the task schema with the topic_name strategy when empty
+	SchemaFile string `yaml:"schema_file" mapstructure:"schema_file"` // Local schema file of Schema
+}
+
+// TopicSpec declares how a task's topics are created. Topics are only
+// provisioned when Partitions is set.
+type TopicSpec struct {
+	Partitions        int               `yaml:"partitions" mapstructure:"partitions"`                 // Partition count
+	ReplicationFactor int               `yaml:"replication_factor" mapstructure:"replication_factor"` // Replicas per partition, broker default when 0
+	Configs           map[string]string `yaml:"configs" mapstructure:"configs"`                       // Topic configs, e.g. cleanup.policy, retention.ms
+}
+
 type TaskConfig struct {
-	Name      string        `yaml:"name" mapstructure:"name"`             // Explicitly map "name"
-	QueryFile string        `yaml:"query_file" mapstructure:"query_file"` // Explicitly map "query"
-	Topic     string        `yaml:"topic" mapstructure:"topic"`           // Explicitly map "topic"
-	Schema    string        `yaml:"schema" mapstructure:"schema"`         // Explicitly map "schema"
-	Interval  time.Duration `yaml:"interval" mapstructure:"interval"`     // Explicitly map "interval"
+	Name            string            `yaml:"name" mapstructure:"name"`                         // Explicitly map "name"
+	QueryFile       string            `yaml:"query_file" mapstructure:"query_file"`             // Explicitly map "query"
+	Topic           string            `yaml:"topic" mapstructure:"topic"`                       // Explicitly map "topic"
+	Schema          string            `yaml:"schema" mapstructure:"schema"`                     // Explicitly map "schema"
+	SchemaFile      string            `yaml:"schema_file" mapstructure:"schema_file"`           // Local .avsc file registered for Schema
+	SchemaVersion   int               `yaml:"schema_version" mapstructure:"schema_version"`     // Registered version of Schema the model is verified against, latest when 0
+	Format          string            `yaml:"format" mapstructure:"format"`                     // avro, protobuf or jsonschema, avro when empty
+	SubjectStrategy string            `yaml:"subject_strategy" mapstructure:"subject_strategy"` // topic_name, record_name or topic_record_name, topic_name when empty
+	Key             KeyConfig         `yaml:"key" mapstructure:"key"`                           // Message key, messages have no key when Key.Field is empty
+	Interval        time.Duration     `yaml:"interval" mapstructure:"interval"`                 // Explicitly map "interval"
+	DryRun          bool              `yaml:"dry_run" mapstructure:"dry_run"`                   // Render messages instead of producing them
+	DryRunOutput    string            `yaml:"dry_run_output" mapstructure:"dry_run_output"`     // Dry-run output file, stdout when empty
+	DryRunDecode    bool              `yaml:"dry_run_decode" mapstructure:"dry_run_decode"`     // Decode dry-run payloads back to JSON
+	Sink            SinkConfig        `yaml:"sink" mapstructure:"sink"`                         // Where messages are published, Kafka by default
+	Routes          []RouteConfig     `yaml:"routes" mapstructure:"routes"`                     // Content based routing, first match wins, Topic is the default
+	Producer        map[string]string `yaml:"producer" mapstructure:"producer"`                 // librdkafka producer properties overriding KAFKA_PRODUCER_CONFIG
+	TopicSpec       TopicSpec         `yaml:"topic_spec" mapstructure:"topic_spec"`             // Provisioning of the task and static route topics
 }
 
 // LoadKafkaConfig loads KafkaConfig using viper.
@@ -72,6 +189,49 @@ func LoadKafkaConfig() KafkaConfig {
 	return cfg
 }
 
+// ProducerProperties parses ProducerConfig into librdkafka properties.
+func (c KafkaConfig) ProducerProperties() (map[string]string, error) {
+	properties := make(map[string]string)
+	for _, pair := range strings.Split(c.ProducerConfig, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid producer property %q, expected key=value", pair)
+		}
+		properties[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return properties, nil
+}
+
+// LoadConsumerConfig loads ConsumerConfig using viper.
+func LoadConsumerConfig() ConsumerConfig {
+	var cfg ConsumerConfig
+	if err := viper.Unmarshal(&cfg); err != nil {
+		log.Printf("Failed to parse ConsumerConfig: %v", err)
+	}
+	return cfg
+}
+
+// RetryTopicDelays parses RetryDelays, one delay per retry topic.
+func (c ConsumerConfig) RetryTopicDelays() ([]time.Duration, error) {
+	var delays []time.Duration
+	for _, value := range strings.Split(c.RetryDelays, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay <= 0 {
+			return nil, fmt.Errorf("invalid retry delay %q, expected a positive duration", value)
+		}
+		delays = append(delays, delay)
+	}
+	return delays, nil
+}
+
 // LoadSchemaRegistryConfig loads SchemaRegistryConfig using viper.
 func LoadSchemaRegistryConfig() SchemaRegistryConfig {
 	var cfg SchemaRegistryConfig
@@ -90,11 +250,72 @@ func LoadDatabaseConfig() DatabaseConfig {
 	return cfg
 }
 
+// LoadDryRunConfig loads DryRunConfig using viper.
+func LoadDryRunConfig() DryRunConfig {
+	var cfg DryRunConfig
+	if err := viper.Unmarshal(&cfg); err != nil {
+		log.Printf("Failed to parse DryRunConfig: %v", err)
+	}
+	return cfg
+}
+
+// LoadShutdownConfig loads ShutdownConfig using viper.
+func LoadShutdownConfig() ShutdownConfig {
+	var cfg ShutdownConfig
+	if err := viper.Unmarshal(&cfg); err != nil {
+		log.Printf("Failed to parse ShutdownConfig: %v", err)
+	}
+	return cfg
+}
+
+// ApplyDryRun applies the global dry-run settings to a task configuration.
+// Task level settings take precedence over the global output file.
+func ApplyDryRun(task TaskConfig, global DryRunConfig) TaskConfig {
+	if !global.Enabled {
+		return task
+	}
+	task.DryRun = true
+	task.DryRunDecode = task.DryRunDecode || global.Decode
+	if task.DryRunOutput == "" {
+		task.DryRunOutput = global.Output
+	}
+	return task
+}
+
+// MirrorConfig mirrors the records of a topic into a MySQL table. Records are
+// upserted by KeyColumns and deleted on tombstones.
+type MirrorConfig struct {
+	Name       string            `yaml:"name" mapstructure:"name"`               // Mirror name used in logs
+	Topic      string            `yaml:"topic" mapstructure:"topic"`             // Topic to mirror
+	Model      string            `yaml:"model" mapstructure:"model"`             // Model the records are decoded into, named as tasks, e.g. "user"
+	Table      string            `yaml:"table" mapstructure:"table"`             // Target table
+	KeyColumns []string          `yaml:"key_columns" mapstructure:"key_columns"` // Primary or unique key of Table, mapped in Columns
+	Columns    map[string]string `yaml:"columns" mapstructure:"columns"`         // Table column to dotted model field path, e.g. country_code: "country.code"
+	Dedup      DedupConfig       `yaml:"dedup" mapstructure:"dedup"`             // Duplicate records to skip, none when empty
+}
+
+// DedupConfig skips the records of a key already handled: versions up to the
+// last one handled, or event ids already handled.
+type DedupConfig struct {
+	Field     string        `yaml:"field" mapstructure:"field"`         // Dotted version field of the model, e.g. "updated_at"
+	Header    string        `yaml:"header" mapstructure:"header"`       // Event id header set by the producer, instead of Field
+	Store     string        `yaml:"store" mapstructure:"store"`         // memory or mysql, memory by default
+	Size      int           `yaml:"size" mapstructure:"size"`           // Memory: keys kept, 10000 by default
+	Table     string        `yaml:"table" mapstructure:"table"`         // MySQL: table of the handled versions, consumer_dedup by default
+	Retention time.Duration `yaml:"retention" mapstructure:"retention"` // MySQL: how long handled versions are kept, 168h by default
+}
+
+// newTaskViper returns a viper instance for task files. Keys are not split on "."
+// so librdkafka property names such as "linger.ms" survive unmarshalling.
+func newTaskViper() *viper.Viper {
+	return viper.NewWithOptions(viper.KeyDelimiter("::"))
+}
+
 // LoadTaskConfigs loads task configurations from YAML files in the specified directory using viper.
 func LoadTaskConfigs(configDir string) ([]TaskConfig, error) {
 	var configs []TaskConfig
 
-	v := viper.New()
+	v := newTaskViper()
 	v.SetConfigType("yaml")
 
 	// Iterate over YAML files in the directory
@@ -125,7 +346,7 @@ func LoadTaskConfigs(configDir string) ([]TaskConfig, error) {
 
 // LoadSingleTaskConfig loads a single task configuration from a YAML file.
 func LoadSingleTaskConfig(filePath string) (TaskConfig, error) {
-	v := viper.New()
+	v := newTaskViper()
 	v.SetConfigFile(filePath)
 	v.SetConfigType("yaml")
 
@@ -143,6 +364,31 @@ func LoadSingleTaskConfig(filePath string) (TaskConfig, error) {
 	return cfg, nil
 }
 
+// LoadMirrorConfigs loads the mirror configurations from the YAML files in configDir.
+func LoadMirrorConfigs(configDir string) ([]MirrorConfig, error) {
+	files, err := listYAMLFiles(configDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []MirrorConfig
+	for _, file := range files {
+		v := newTaskViper()
+		v.SetConfigFile(file)
+		v.SetConfigType("yaml")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+
+		var cfg MirrorConfig
+		if err := v.Unmarshal(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal config file %s: %w", file, err)
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
 // listYAMLFiles lists all YAML files in the specified directory.
 func listYAMLFiles(dir string) ([]string, error) {
 	var files []string
@@ -19,6 +19,22 @@ func resetViperForTest() {
 	// Set default values (same as in init function)
 	viper.SetDefault("KAFKA_BOOTSTRAP_SERVERS", "localhost:9092")
 	viper.SetDefault("KAFKA_CONSUMER_GROUP", "") // Add this default
+	viper.SetDefault("KAFKA_PRODUCER_CONFIG", "")
+	viper.SetDefault("KAFKA_CONSUMER_ASSIGNMENT_STRATEGY", "")
+	viper.SetDefault("KAFKA_CONSUMER_INSTANCE_ID", "")
+	viper.SetDefault("KAFKA_CONSUMER_SESSION_TIMEOUT", "6s")
+	viper.SetDefault("KAFKA_CONSUMER_COMMIT_MODE", "sync")
+	viper.SetDefault("KAFKA_CONSUMER_COMMIT_BATCH_SIZE", 100)
+	viper.SetDefault("KAFKA_CONSUMER_COMMIT_INTERVAL", "5s")
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_ATTEMPTS", 2)
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_BACKOFF", "200ms")
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_MAX_BACKOFF", "5s")
+	viper.SetDefault("KAFKA_CONSUMER_RETRY_DELAYS", "")
+	viper.SetDefault("KAFKA_CONSUMER_DEAD_LETTER", false)
+	viper.SetDefault("KAFKA_CONSUMER_PERMANENT_ERRORS", "skip")
+	viper.SetDefault("KAFKA_CONSUMER_WORKERS", 1)
+	viper.SetDefault("KAFKA_CONSUMER_ORDERING", "partition")
+	viper.SetDefault("KAFKA_CONSUMER_WORKER_QUEUE_SIZE", 100)
 	viper.SetDefault("SCHEMA_REGISTRY_URL", "http://localhost:8081")
 	viper.SetDefault("SCHEMA_REGISTRY_USERNAME", "") // Add this default
 	viper.SetDefault("SCHEMA_REGISTRY_PASSWORD", "") // Add this default
@@ -30,6 +46,11 @@ func resetViperForTest() {
 	viper.SetDefault("MYSQL_DATABASE", "example")
 	viper.SetDefault("MYSQL_USER", "user")
 	viper.SetDefault("MYSQL_PASSWORD", "password")
+	viper.SetDefault("DRY_RUN", false)
+	viper.SetDefault("DRY_RUN_OUTPUT", "")
+	viper.SetDefault("DRY_RUN_DECODE", false)
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("SHUTDOWN_HOOK_TIMEOUT", "10s")
 
 	// Configure viper to read environment variables
 	viper.AutomaticEnv()
@@ -40,6 +61,10 @@ func TestLoadKafkaConfig(t *testing.T) {
 	// Arrange
 	os.Setenv("KAFKA_BOOTSTRAP_SERVERS", "test-server:9092")
 	os.Setenv("KAFKA_CONSUMER_GROUP", "test-group")
+	os.Setenv("KAFKA_PRODUCER_CONFIG", "linger.ms=5")
+	defer os.Unsetenv("KAFKA_PRODUCER_CONFIG")
+	os.Setenv("KAFKA_CONSUMER_ASSIGNMENT_STRATEGY", "cooperative-sticky")
+	defer os.Unsetenv("KAFKA_CONSUMER_ASSIGNMENT_STRATEGY")
 	resetViperForTest()
 
 	// Act
@@ -48,6 +73,73 @@ func TestLoadKafkaConfig(t *testing.T) {
 	// Assert
 	assert.Equal(t, "test-server:9092", cfg.BootstrapServers)
 	assert.Equal(t, "test-group", cfg.Group)
+	assert.Equal(t, "linger.ms=5", cfg.ProducerConfig)
+	assert.Equal(t, "cooperative-sticky", cfg.AssignmentStrategy)
+	assert.Empty(t, cfg.InstanceID)
+	assert.Equal(t, 6*time.Second, cfg.SessionTimeout)
+}
+
+func TestLoadConsumerConfig(t *testing.T) {
+	// Arrange
+	os.Setenv("KAFKA_CONSUMER_COMMIT_MODE", "async")
+	os.Setenv("KAFKA_CONSUMER_RETRY_DELAYS", "30s,5m")
+	defer os.Unsetenv("KAFKA_CONSUMER_COMMIT_MODE")
+	defer os.Unsetenv("KAFKA_CONSUMER_RETRY_DELAYS")
+	os.Setenv("KAFKA_CONSUMER_WORKERS", "4")
+	os.Setenv("KAFKA_CONSUMER_ORDERING", "key")
+	defer os.Unsetenv("KAFKA_CONSUMER_WORKERS")
+	defer os.Unsetenv("KAFKA_CONSUMER_ORDERING")
+	resetViperForTest()
+
+	// Act
+	cfg := LoadConsumerConfig()
+
+	// Assert
+	assert.Equal(t, ConsumerConfig{
+		CommitMode:      "async",
+		CommitBatchSize: 100,
+		CommitInterval:  5 * time.Second,
+		RetryAttempts:   2,
+		RetryBackoff:    200 * time.Millisecond,
+		RetryMaxBackoff: 5 * time.Second,
+		RetryDelays:     "30s,5m",
+		PermanentErrors: "skip",
+		Workers:         4,
+		Ordering:        "key",
+		WorkerQueueSize: 100,
+	}, cfg)
+}
+
+func TestConsumerConfig_RetryTopicDelays(t *testing.T) {
+	// Act
+	delays, err := ConsumerConfig{RetryDelays: " 30s, 5m ,"}.RetryTopicDelays()
+	_, invalidErr := ConsumerConfig{RetryDelays: "30s,soon"}.RetryTopicDelays()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{30 * time.Second, 5 * time.Minute}, delays)
+	assert.EqualError(t, invalidErr, `invalid retry delay "soon", expected a positive duration`)
+}
+
+func TestKafkaConfig_ProducerProperties(t *testing.T) {
+	// Arrange
+	cfg := KafkaConfig{ProducerConfig: " linger.ms=5, compression.type = lz4 ,"}
+
+	// Act
+	properties, err := cfg.ProducerProperties()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"linger.ms": "5", "compression.type": "lz4"}, properties)
+}
+
+func TestKafkaConfig_ProducerPropertiesInvalid(t *testing.T) {
+	// Act
+	properties, err := KafkaConfig{ProducerConfig: "linger.ms"}.ProducerProperties()
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, properties)
 }
 
 // TestLoadSchemaRegistryConfig tests loading schema registry config from environment
@@ -155,6 +247,65 @@ interval: "1m"`)
 	assert.Equal(t, time.Minute, task2.Interval)
 }
 
+func TestLoadDryRunConfig(t *testing.T) {
+	// Arrange
+	os.Setenv("DRY_RUN", "true")
+	os.Setenv("DRY_RUN_OUTPUT", "/tmp/dry-run.jsonl")
+	os.Setenv("DRY_RUN_DECODE", "true")
+	defer func() {
+		os.Unsetenv("DRY_RUN")
+		os.Unsetenv("DRY_RUN_OUTPUT")
+		os.Unsetenv("DRY_RUN_DECODE")
+	}()
+	resetViperForTest()
+
+	// Act
+	cfg := LoadDryRunConfig()
+
+	// Assert
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, "/tmp/dry-run.jsonl", cfg.Output)
+	assert.True(t, cfg.Decode)
+}
+
+func TestLoadShutdownConfig(t *testing.T) {
+	// Arrange
+	resetViperForTest()
+
+	// Act
+	defaults := LoadShutdownConfig()
+	os.Setenv("SHUTDOWN_TIMEOUT", "45s")
+	defer os.Unsetenv("SHUTDOWN_TIMEOUT")
+	os.Setenv("SHUTDOWN_HOOK_TIMEOUT", "5s")
+	defer os.Unsetenv("SHUTDOWN_HOOK_TIMEOUT")
+	resetViperForTest()
+	cfg := LoadShutdownConfig()
+
+	// Assert
+	assert.Equal(t, 30*time.Second, defaults.Timeout)
+	assert.Equal(t, 10*time.Second, defaults.HookTimeout)
+	assert.Equal(t, 45*time.Second, cfg.Timeout)
+	assert.Equal(t, 5*time.Second, cfg.HookTimeout)
+}
+
+func TestApplyDryRun(t *testing.T) {
+	// Disabled globally: task settings are kept
+	task := TaskConfig{Name: "task", DryRun: true, DryRunOutput: "task.jsonl"}
+	assert.Equal(t, task, ApplyDryRun(task, DryRunConfig{}))
+
+	// Enabled globally: task output wins over the global output
+	cfg := ApplyDryRun(task, DryRunConfig{Enabled: true, Output: "global.jsonl", Decode: true})
+	assert.True(t, cfg.DryRun)
+	assert.True(t, cfg.DryRunDecode)
+	assert.Equal(t, "task.jsonl", cfg.DryRunOutput)
+
+	// Enabled globally: global output is used when the task has none
+	cfg = ApplyDryRun(TaskConfig{Name: "task"}, DryRunConfig{Enabled: true, Output: "global.jsonl"})
+	assert.True(t, cfg.DryRun)
+	assert.False(t, cfg.DryRunDecode)
+	assert.Equal(t, "global.jsonl", cfg.DryRunOutput)
+}
+
 func TestLoadTaskConfigs_DirectoryNotFound(t *testing.T) {
 	// Act
 	configs, err := LoadTaskConfigs("non-existent-directory")
@@ -164,6 +315,43 @@ func TestLoadTaskConfigs_DirectoryNotFound(t *testing.T) {
 	assert.Nil(t, configs)
 }
 
+func TestLoadMirrorConfigs(t *testing.T) {
+	// Arrange
+	tempDir, err := os.MkdirTemp("", "mirror-config-test-*")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	content := []byte(`name: "user-mirror"
+topic: "user-topic"
+model: "user"
+table: "users_mirror"
+key_columns: ["id"]
+columns:
+  id: "user_id"
+  country_code: "country.code"
+dedup:
+  field: "updated_at"
+  store: "mysql"
+  retention: "72h"`)
+	err = os.WriteFile(filepath.Join(tempDir, "users.yaml"), content, 0644)
+	assert.NoError(t, err)
+
+	// Act
+	configs, err := LoadMirrorConfigs(tempDir)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []MirrorConfig{{
+		Name:       "user-mirror",
+		Topic:      "user-topic",
+		Model:      "user",
+		Table:      "users_mirror",
+		KeyColumns: []string{"id"},
+		Columns:    map[string]string{"id": "user_id", "country_code": "country.code"},
+		Dedup:      DedupConfig{Field: "updated_at", Store: "mysql", Retention: 72 * time.Hour},
+	}}, configs)
+}
+
 func TestLoadSingleTaskConfig(t *testing.T) {
 	// Arrange
 	// Create temporary file with test YAML content
@@ -175,7 +363,33 @@ func TestLoadSingleTaskConfig(t *testing.T) {
 query_file: "queries/single.sql"
 topic: "single-topic"
 schema: "single-schema"
-interval: "5m"`)
+interval: "5m"
+dry_run: true
+dry_run_output: "single.jsonl"
+dry_run_decode: true
+sink:
+  type: "webhook"
+  url: "http://partner/hook"
+  headers:
+    Authorization: "Bearer ${TOKEN}"
+  batch_size: 50
+  flush_interval: "2s"
+routes:
+  - field: "status"
+    value: "active"
+    topic: "user-active"
+  - field: "country.code"
+    topic: "user-{country.code}"
+    schema: "user-country-schema"
+producer:
+  linger.ms: 5
+  compression.type: "lz4"
+topic_spec:
+  partitions: 6
+  replication_factor: 3
+  configs:
+    cleanup.policy: "compact"
+    retention.ms: 604800000`)
 
 	err = os.WriteFile(tmpFile.Name(), yamlContent, 0644)
 	assert.NoError(t, err)
@@ -190,6 +404,24 @@ interval: "5m"`)
 	assert.Equal(t, "single-topic", cfg.Topic)
 	assert.Equal(t, "single-schema", cfg.Schema)
 	assert.Equal(t, 5*time.Minute, cfg.Interval)
+	assert.True(t, cfg.DryRun)
+	assert.Equal(t, "single.jsonl", cfg.DryRunOutput)
+	assert.True(t, cfg.DryRunDecode)
+	assert.Equal(t, "webhook", cfg.Sink.Type)
+	assert.Equal(t, "http://partner/hook", cfg.Sink.URL)
+	assert.Equal(t, map[string]string{"authorization": "Bearer ${TOKEN}"}, cfg.Sink.Headers)
+	assert.Equal(t, 50, cfg.Sink.BatchSize)
+	assert.Equal(t, 2*time.Second, cfg.Sink.FlushInterval)
+	assert.Equal(t, []RouteConfig{
+		{Field: "status", Value: "active", Topic: "user-active"},
+		{Field: "country.code", Topic: "user-{country.code}", Schema: "user-country-schema"},
+	}, cfg.Routes)
+	assert.Equal(t, map[string]string{"linger.ms": "5", "compression.type": "lz4"}, cfg.Producer)
+	assert.Equal(t, TopicSpec{
+		Partitions:        6,
+		ReplicationFactor: 3,
+		Configs:           map[string]string{"cleanup.policy": "compact", "retention.ms": "604800000"},
+	}, cfg.TopicSpec)
 }
 
 func TestLoadSingleTaskConfig_FileNotFound(t *testing.T) {
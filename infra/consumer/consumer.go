@@ -0,0 +1,480 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/serde"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// pollTimeoutMs is how long each poll waits for an event before checking the context.
+const pollTimeoutMs = 100
+
+// Commit modes of config.ConsumerConfig. Sync commits after every batch of handled
+// messages and whenever polling is idle; async commits in the background at an interval.
+const (
+	CommitSync  = "sync"
+	CommitAsync = "async"
+)
+
+// Orderings of config.ConsumerConfig. With several workers, messages of the same
+// partition, or of the same key, are handled by the same worker in offset order.
+const (
+	OrderPartition = "partition"
+	OrderKey       = "key"
+)
+
+// Policies of config.ConsumerConfig for permanent failures, handler errors wrapped
+// with Permanent and undecodable payloads, when there is no dead-letter topic.
+// Skip logs the failure and commits past the message; stop stops Run.
+const (
+	PermanentSkip = "skip"
+	PermanentStop = "stop"
+)
+
+const (
+	defaultCommitBatchSize = 100
+	defaultCommitInterval  = 5 * time.Second
+	defaultWorkerQueueSize = 100
+)
+
+// ConsumerInterface is the part of *kafka.Consumer used by Consumer.
+type ConsumerInterface interface {
+	SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error
+	Poll(timeoutMs int) kafka.Event
+	CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Pause(partitions []kafka.TopicPartition) error
+	Resume(partitions []kafka.TopicPartition) error
+	Seek(partition kafka.TopicPartition, ignoredTimeoutMs int) error
+	AssignmentLost() bool
+	Close() error
+}
+
+// Message is a consumed message with its value decoded into T. Topic is the
+// source topic of messages consumed from its retry topics.
+type Message[T any] struct {
+	Topic     string
+	Partition int32
+	Offset    kafka.Offset
+	Key       []byte
+	Value     T
+	Tombstone bool // the message has no value, Value is the zero T
+	Headers   []kafka.Header
+	Timestamp time.Time
+}
+
+// HandlerFunc handles the messages of a topic. Failed messages are retried, then
+// forwarded to the retry and dead-letter topics configured. A message that cannot
+// be forwarded stops the consumer without being committed, so it is consumed
+// again on restart. Return Permanent(err) for failures retrying cannot fix.
+// With several workers, handlers run concurrently for different partitions or keys.
+type HandlerFunc[T any] func(ctx context.Context, msg Message[T]) error
+
+// PartitionsFunc is called with the partitions assigned to or revoked from the consumer.
+type PartitionsFunc func(partitions []kafka.TopicPartition) error
+
+// handler decodes and handles a raw message of topic.
+type handler func(ctx context.Context, topic string, msg *kafka.Message) error
+
+// Consumer polls the topics of its registered handlers and dispatches each
+// message to the handler of its topic, decoded into the handler type. Offsets
+// are committed once messages are handled, so delivery is at least once.
+type Consumer struct {
+	consumer     ConsumerInterface
+	deserializer serde.DeserializerInterface
+	producer     ProducerInterface
+	cfg          config.ConsumerConfig
+	handlers     map[string]handler
+	offsets      *offsetTracker
+	onAssign     PartitionsFunc
+	onRevoke     PartitionsFunc
+
+	delays      []time.Duration        // delays of the retry topics
+	retryTopics map[string]retrySource // source of each retry topic
+	paused      map[partitionKey]pausedPartition
+
+	commitMu   sync.Mutex // serializes commits
+	committing atomic.Bool
+	commits    sync.WaitGroup // background commits
+
+	workers    []*worker
+	dispatched map[partitionKey]*partitionJobs // messages queued or being handled by workers, by partition
+	working    sync.WaitGroup                  // running workers
+	stop       context.CancelFunc
+	failMu     sync.Mutex
+	failure    error // first failure of a worker or rebalance
+}
+
+// NewConsumer returns a consumer decoding values with deserializer, see
+// serde.NewSchemaDeserializer to decode any format. The Kafka consumer must
+// have auto commit disabled. producer forwards failed messages, it may be nil
+// when no retry topic or dead-letter topic is configured.
+func NewConsumer(consumer ConsumerInterface, deserializer serde.DeserializerInterface, producer ProducerInterface, cfg config.ConsumerConfig) *Consumer {
+	if cfg.CommitMode == "" {
+		cfg.CommitMode = CommitSync
+	}
+	if cfg.CommitBatchSize <= 0 {
+		cfg.CommitBatchSize = defaultCommitBatchSize
+	}
+	if cfg.CommitInterval <= 0 {
+		cfg.CommitInterval = defaultCommitInterval
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Ordering == "" {
+		cfg.Ordering = OrderPartition
+	}
+	if cfg.PermanentErrors == "" {
+		cfg.PermanentErrors = PermanentSkip
+	}
+	if cfg.WorkerQueueSize <= 0 {
+		cfg.WorkerQueueSize = defaultWorkerQueueSize
+	}
+	return &Consumer{
+		consumer:     consumer,
+		deserializer: deserializer,
+		producer:     producer,
+		cfg:          cfg,
+		handlers:     make(map[string]handler),
+		offsets:      newOffsetTracker(),
+		retryTopics:  make(map[string]retrySource),
+		paused:       make(map[partitionKey]pausedPartition),
+		dispatched:   make(map[partitionKey]*partitionJobs),
+	}
+}
+
+// Register registers fn for the messages of topic, decoded into T. Empty values,
+// such as tombstones, are passed as the zero T. Payloads that cannot be decoded
+// are not retried. Register panics if topic already has a handler, and must be
+// called before Run.
+func Register[T any](c *Consumer, topic string, fn HandlerFunc[T]) {
+	if _, ok := c.handlers[topic]; ok {
+		panic(fmt.Sprintf("consumer: topic %s already has a handler", topic))
+	}
+	c.handlers[topic] = func(ctx context.Context, topic string, msg *kafka.Message) error {
+		var value T
+		if len(msg.Value) > 0 {
+			if err := c.deserializer.DeserializeInto(topic, msg.Value, &value); err != nil {
+				return Permanent(fmt.Errorf("failed to deserialize payload: %w", err))
+			}
+		}
+		return fn(ctx, Message[T]{
+			Topic:     topic,
+			Partition: msg.TopicPartition.Partition,
+			Offset:    msg.TopicPartition.Offset,
+			Key:       msg.Key,
+			Value:     value,
+			Tombstone: len(msg.Value) == 0,
+			Headers:   msg.Headers,
+			Timestamp: msg.Timestamp,
+		})
+	}
+}
+
+// OnAssign registers fn to initialise the state of newly assigned partitions,
+// before their messages are handled. An error stops the consumer.
+func (c *Consumer) OnAssign(fn PartitionsFunc) {
+	c.onAssign = fn
+}
+
+// OnRevoke registers fn to flush the state of revoked partitions, once their
+// messages are handled and before their offsets are committed. fn is also called
+// when the assignment is lost, without committing. An error stops the consumer
+// without committing the partitions.
+func (c *Consumer) OnRevoke(fn PartitionsFunc) {
+	c.onRevoke = fn
+}
+
+// Topics returns the topics with a handler.
+func (c *Consumer) Topics() []string {
+	topics := make([]string, 0, len(c.handlers))
+	for topic := range c.handlers {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Run subscribes to the topics with a handler and handles messages until ctx is
+// done, then commits the handled messages. It returns an error if subscribing
+// fails, a message cannot be handled or the consumer fails fatally; the messages
+// handled before are committed. With several workers, the messages queued when
+// Run stops are consumed again on restart.
+func (c *Consumer) Run(ctx context.Context) error {
+	if c.cfg.CommitMode != CommitSync && c.cfg.CommitMode != CommitAsync {
+		return fmt.Errorf("unsupported commit mode %q, expected %s or %s", c.cfg.CommitMode, CommitSync, CommitAsync)
+	}
+	if c.cfg.Ordering != OrderPartition && c.cfg.Ordering != OrderKey {
+		return fmt.Errorf("unsupported ordering %q, expected %s or %s", c.cfg.Ordering, OrderPartition, OrderKey)
+	}
+	if c.cfg.PermanentErrors != PermanentSkip && c.cfg.PermanentErrors != PermanentStop {
+		return fmt.Errorf("unsupported permanent error policy %q, expected %s or %s", c.cfg.PermanentErrors, PermanentSkip, PermanentStop)
+	}
+	topics := c.Topics()
+	if len(topics) == 0 {
+		return errors.New("no handlers registered")
+	}
+	delays, err := c.cfg.RetryTopicDelays()
+	if err != nil {
+		return err
+	}
+	if (len(delays) > 0 || c.cfg.DeadLetter) && c.producer == nil {
+		return errors.New("retry and dead-letter topics require a producer")
+	}
+	c.delays = delays
+	for _, topic := range topics {
+		for tier := 1; tier <= len(delays); tier++ {
+			c.retryTopics[RetryTopic(topic, tier)] = retrySource{topic: topic, tier: tier}
+			topics = append(topics, RetryTopic(topic, tier))
+		}
+	}
+	if err := c.consumer.SubscribeTopics(topics, c.rebalance); err != nil {
+		return fmt.Errorf("failed to subscribe to topics: %w", err)
+	}
+	log.Printf("Subscribed to topics %v", topics)
+
+	ctx, c.stop = context.WithCancel(ctx)
+	defer c.stop()
+	if c.cfg.Workers > 1 {
+		c.startWorkers(ctx)
+	}
+	err = c.poll(ctx)
+	if c.workers != nil {
+		c.stopWorkers()
+	}
+	if err == nil {
+		err = c.failed()
+	}
+	c.commits.Wait()
+	if commitErr := c.commit(nil); commitErr != nil {
+		err = errors.Join(err, commitErr)
+	}
+	return err
+}
+
+// poll handles events until ctx is done or an event fails, committing as configured.
+func (c *Consumer) poll(ctx context.Context) error {
+	handled := 0
+	lastCommit := time.Now()
+	for ctx.Err() == nil {
+		if err := c.resumeDue(); err != nil {
+			return err
+		}
+		ev := c.consumer.Poll(pollTimeoutMs)
+		if err := c.handleEvent(ctx, ev); err != nil {
+			return err
+		}
+		if _, ok := ev.(*kafka.Message); ok {
+			handled++
+		}
+
+		switch c.cfg.CommitMode {
+		case CommitSync:
+			if handled >= c.cfg.CommitBatchSize || ev == nil {
+				handled = 0
+				if err := c.commit(nil); err != nil {
+					log.Printf("Commit failed, retrying after the next batch: %v", err)
+				}
+			}
+		case CommitAsync:
+			if time.Since(lastCommit) >= c.cfg.CommitInterval {
+				lastCommit = time.Now()
+				c.commitAsync()
+			}
+		}
+	}
+	return nil
+}
+
+// commit synchronously commits the handled messages of partitions, or of every partition when nil.
+func (c *Consumer) commit(partitions []kafka.TopicPartition) error {
+	c.commitMu.Lock()
+	defer c.commitMu.Unlock()
+
+	offsets := c.offsets.uncommitted(partitions)
+	if len(offsets) == 0 {
+		return nil
+	}
+	committed, err := c.consumer.CommitOffsets(offsets)
+	if err != nil {
+		return fmt.Errorf("failed to commit offsets: %w", err)
+	}
+	c.offsets.committed(committed)
+	for _, tp := range committed {
+		if tp.Error != nil {
+			return fmt.Errorf("failed to commit offset of %s: %w", tp, tp.Error)
+		}
+	}
+	return nil
+}
+
+// commitAsync commits the handled messages in the background, unless a commit is running.
+func (c *Consumer) commitAsync() {
+	if !c.committing.CompareAndSwap(false, true) {
+		return
+	}
+	c.commits.Add(1)
+	go func() {
+		defer c.commits.Done()
+		defer c.committing.Store(false)
+		if err := c.commit(nil); err != nil {
+			log.Printf("Background commit failed: %v", err)
+		}
+	}()
+}
+
+// rebalance starts assigned partitions afresh and commits the handled messages of
+// revoked partitions before they are reassigned, once the workers finished the
+// messages dispatched to them. Their in-place retries are cancelled, so a failing
+// message is left to the new owner instead of holding the poll loop. The partitions of the event are only those added
+// or removed under the cooperative protocol, and every partition otherwise.
+func (c *Consumer) rebalance(_ *kafka.Consumer, ev kafka.Event) error {
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		log.Printf("Assigned partitions %v", e.Partitions)
+		c.forget(e.Partitions)
+		if c.onAssign != nil {
+			if err := c.onAssign(e.Partitions); err != nil {
+				c.fail(fmt.Errorf("failed to initialise assigned partitions: %w", err))
+			}
+		}
+	case kafka.RevokedPartitions:
+		lost := c.consumer.AssignmentLost()
+		if lost {
+			log.Printf("Lost partitions %v, their handled messages are consumed again by their new owner", e.Partitions)
+		} else {
+			log.Printf("Revoked partitions %v", e.Partitions)
+		}
+		c.finishJobs(e.Partitions)
+		if err := c.revoke(e.Partitions, lost); err != nil {
+			c.fail(err)
+		}
+		c.forget(e.Partitions)
+	}
+	return nil
+}
+
+// revoke flushes the state of revoked partitions and commits them, unless they
+// were lost to another consumer.
+func (c *Consumer) revoke(partitions []kafka.TopicPartition, lost bool) error {
+	if c.onRevoke != nil {
+		if err := c.onRevoke(partitions); err != nil {
+			return fmt.Errorf("failed to flush revoked partitions: %w", err)
+		}
+	}
+	if lost {
+		return nil
+	}
+	if err := c.commit(partitions); err != nil {
+		log.Printf("Failed to commit revoked partitions: %v", err)
+	}
+	return nil
+}
+
+// forget drops the offsets and pauses tracked for partitions.
+func (c *Consumer) forget(partitions []kafka.TopicPartition) {
+	c.offsets.remove(partitions)
+	for _, tp := range partitions {
+		delete(c.paused, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+	}
+}
+
+// fail records the first failure of a worker or rebalance and stops the consumer.
+func (c *Consumer) fail(err error) {
+	c.failMu.Lock()
+	defer c.failMu.Unlock()
+	if c.failure == nil {
+		c.failure = err
+	}
+	c.stop()
+}
+
+// failed returns the failure recorded by fail.
+func (c *Consumer) failed() error {
+	c.failMu.Lock()
+	defer c.failMu.Unlock()
+	return c.failure
+}
+
+// RunUntilSignal runs the consumer until the process receives SIGINT or SIGTERM,
+// then closes it.
+func (c *Consumer) RunUntilSignal() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := c.Run(ctx)
+	log.Println("Closing consumer")
+	if closeErr := c.consumer.Close(); closeErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to close consumer: %w", closeErr))
+	}
+	return err
+}
+
+// handleEvent dispatches messages and returns handler and fatal consumer errors.
+func (c *Consumer) handleEvent(ctx context.Context, ev kafka.Event) error {
+	switch e := ev.(type) {
+	case nil:
+	case *kafka.Message:
+		return c.handleMessage(ctx, e)
+	case kafka.Error:
+		if e.IsFatal() {
+			return fmt.Errorf("fatal consumer error: %w", e)
+		}
+		log.Printf("Consumer error: %v", e)
+	}
+	return nil
+}
+
+// handleMessage handles a message, or dispatches it to a worker when there are
+// several. Retry messages that are not due yet are left to be consumed again once due.
+func (c *Consumer) handleMessage(ctx context.Context, msg *kafka.Message) error {
+	if c.isPaused(msg.TopicPartition) {
+		return nil
+	}
+	topic := *msg.TopicPartition.Topic
+	source, ok := c.retryTopics[topic]
+	if !ok {
+		source = retrySource{topic: topic}
+	} else if until := notBefore(msg); time.Now().Before(until) {
+		return c.delay(msg, until)
+	}
+
+	if c.workers != nil {
+		return c.dispatch(ctx, msg, source)
+	}
+	c.offsets.start(msg.TopicPartition)
+	return c.process(ctx, ctx, msg, source)
+}
+
+// process runs the handler of the message source topic, forwarding the message
+// when it fails, and marks the message handled. Retries stop once retries is
+// done, when the consumer stops or the partition is revoked.
+func (c *Consumer) process(ctx, retries context.Context, msg *kafka.Message, source retrySource) error {
+	h, ok := c.handlers[source.topic]
+	if !ok {
+		log.Printf("No handler for message on %s, skipped", msg.TopicPartition)
+	} else if err := c.attempt(ctx, retries, h, source.topic, msg); err != nil {
+		if retries.Err() != nil {
+			// stopping or revoked: the message is consumed again on restart or by the new owner
+			return nil
+		}
+		if err := c.forward(ctx, msg, source, err); err != nil {
+			return fmt.Errorf("failed to handle message on %s: %w", msg.TopicPartition, err)
+		}
+	}
+	c.offsets.done(msg.TopicPartition)
+	return nil
+}
@@ -0,0 +1,494 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/models"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeConsumer returns its events one poll at a time, then cancels the run once
+// no partition is paused. Rebalance events are passed to the rebalance callback,
+// as librdkafka does. Paused partitions deliver no message, and fetch again from
+// the offset they were rewound to when resumed.
+type fakeConsumer struct {
+	events      []kafka.Event
+	cancel      context.CancelFunc
+	subscribed  []string
+	rebalanceCb kafka.RebalanceCb
+	closed      bool
+	lost        bool
+	paused      map[string]bool
+	fetched     []*kafka.Message
+	seeks       []kafka.TopicPartition
+
+	mu      sync.Mutex
+	commits [][]kafka.TopicPartition
+	pauses  int
+}
+
+func partitionName(tp kafka.TopicPartition) string {
+	return fmt.Sprintf("%s/%d", *tp.Topic, tp.Partition)
+}
+
+func (f *fakeConsumer) SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error {
+	f.subscribed = topics
+	f.rebalanceCb = rebalanceCb
+	return nil
+}
+
+func (f *fakeConsumer) Poll(int) kafka.Event {
+	if len(f.events) == 0 {
+		if len(f.paused) == 0 {
+			f.cancel()
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	ev := f.events[0]
+	f.events = f.events[1:]
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions, kafka.RevokedPartitions:
+		f.rebalanceCb(nil, ev)
+		return nil
+	case *kafka.Message:
+		f.fetched = append(f.fetched, e)
+		if f.paused[partitionName(e.TopicPartition)] {
+			return nil
+		}
+	}
+	return ev
+}
+
+func (f *fakeConsumer) Pause(partitions []kafka.TopicPartition) error {
+	f.mu.Lock()
+	f.pauses++
+	f.mu.Unlock()
+	if f.paused == nil {
+		f.paused = make(map[string]bool)
+	}
+	for _, tp := range partitions {
+		f.paused[partitionName(tp)] = true
+	}
+	return nil
+}
+
+func (f *fakeConsumer) Resume(partitions []kafka.TopicPartition) error {
+	for _, tp := range partitions {
+		delete(f.paused, partitionName(tp))
+		var seek kafka.TopicPartition
+		for _, s := range f.seeks {
+			if partitionName(s) == partitionName(tp) {
+				seek = s
+			}
+		}
+		var refetched []kafka.Event
+		seen := make(map[kafka.Offset]bool)
+		for _, msg := range f.fetched {
+			offset := msg.TopicPartition.Offset
+			if partitionName(msg.TopicPartition) == partitionName(seek) && offset >= seek.Offset && !seen[offset] {
+				seen[offset] = true
+				refetched = append(refetched, msg)
+			}
+		}
+		f.events = append(refetched, f.events...)
+	}
+	return nil
+}
+
+func (f *fakeConsumer) Seek(partition kafka.TopicPartition, _ int) error {
+	f.seeks = append(f.seeks, partition)
+	return nil
+}
+
+func (f *fakeConsumer) CommitOffsets(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commits = append(f.commits, offsets)
+	return offsets, nil
+}
+
+// pauseCount returns how many times partitions were paused.
+func (f *fakeConsumer) pauseCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pauses
+}
+
+// committed returns the committed offsets by "topic/partition".
+func (f *fakeConsumer) committed() map[string]kafka.Offset {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	offsets := make(map[string]kafka.Offset)
+	for _, commit := range f.commits {
+		for _, tp := range commit {
+			offsets[partitionName(tp)] = tp.Offset
+		}
+	}
+	return offsets
+}
+
+func (f *fakeConsumer) AssignmentLost() bool {
+	return f.lost
+}
+
+func (f *fakeConsumer) Close() error {
+	f.closed = true
+	return nil
+}
+
+type MockDeserializer struct {
+	mock.Mock
+}
+
+func (m *MockDeserializer) DeserializeInto(topic string, payload []byte, msg interface{}) error {
+	args := m.Called(topic, payload, msg)
+	if fn, ok := args.Get(1).(func(interface{})); ok {
+		fn(msg)
+	}
+	return args.Error(0)
+}
+
+func newMessage(topic string, offset kafka.Offset, value []byte) *kafka.Message {
+	return &kafka.Message{
+		TopicPartition: newPartition(topic, 1, offset),
+		Key:            []byte("key"),
+		Value:          value,
+	}
+}
+
+func newPartition(topic string, partition int32, offset kafka.Offset) kafka.TopicPartition {
+	return kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}
+}
+
+func TestConsumer_DispatchesDecodedMessagesByTopic(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		newMessage("user-topic", 5, []byte("user")),
+		newMessage("name-topic", 6, []byte("name")),
+		newMessage("user-topic", 7, nil),
+	}}
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.AnythingOfType("*models.User")).
+		Return(nil, func(msg interface{}) { *msg.(*models.User) = models.User{ID: 42} })
+	deserializer.On("DeserializeInto", "name-topic", []byte("name"), mock.AnythingOfType("*string")).
+		Return(nil, func(msg interface{}) { *msg.(*string) = "Ada" })
+	c := NewConsumer(fake, deserializer, nil, config.ConsumerConfig{})
+	var users []Message[models.User]
+	var names []string
+	Register(c, "user-topic", func(_ context.Context, msg Message[models.User]) error {
+		users = append(users, msg)
+		return nil
+	})
+	Register(c, "name-topic", func(_ context.Context, msg Message[string]) error {
+		names = append(names, msg.Value)
+		return nil
+	})
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name-topic", "user-topic"}, fake.subscribed)
+	assert.Equal(t, []string{"Ada"}, names)
+	assert.Len(t, users, 2)
+	assert.Equal(t, models.User{ID: 42}, users[0].Value)
+	assert.Equal(t, kafka.Offset(5), users[0].Offset)
+	assert.Equal(t, int32(1), users[0].Partition)
+	assert.Equal(t, []byte("key"), users[0].Key)
+	assert.False(t, users[0].Tombstone)
+	assert.Equal(t, models.User{}, users[1].Value)
+	assert.True(t, users[1].Tombstone)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 8, "name-topic/1": 7}, fake.committed())
+	deserializer.AssertExpectations(t)
+}
+
+func TestConsumer_SkipsUnknownTopicsAndTransientErrors(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		newMessage("other-topic", 2, []byte("user")),
+		kafka.NewError(kafka.ErrTransport, "broker down", false),
+		newMessage("user-topic", 3, []byte("user")),
+	}}
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	c := NewConsumer(fake, deserializer, nil, config.ConsumerConfig{})
+	var offsets []kafka.Offset
+	Register(c, "user-topic", func(_ context.Context, msg Message[models.User]) error {
+		offsets = append(offsets, msg.Offset)
+		return nil
+	})
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []kafka.Offset{3}, offsets)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 4, "other-topic/1": 3}, fake.committed())
+}
+
+func TestConsumer_FailureStopsAfterCommittingEarlierMessages(t *testing.T) {
+	testCases := []struct {
+		name        string
+		decodeErr   error
+		handlerErr  error
+		expectedErr string
+	}{
+		{name: "deserializer", decodeErr: errors.New("bad payload"), expectedErr: "failed to deserialize payload: bad payload"},
+		{name: "handler", handlerErr: errors.New("handler failed"), expectedErr: "handler failed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+				newMessage("user-topic", 1, []byte("user")),
+				newMessage("user-topic", 2, []byte("bad")),
+				newMessage("user-topic", 3, []byte("user")),
+			}}
+			deserializer := &MockDeserializer{}
+			deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+			deserializer.On("DeserializeInto", "user-topic", []byte("bad"), mock.Anything).Return(tc.decodeErr, nil)
+			c := NewConsumer(fake, deserializer, nil, config.ConsumerConfig{PermanentErrors: PermanentStop})
+			var offsets []kafka.Offset
+			Register(c, "user-topic", func(_ context.Context, msg Message[models.User]) error {
+				offsets = append(offsets, msg.Offset)
+				if msg.Offset == 2 {
+					return tc.handlerErr
+				}
+				return nil
+			})
+
+			// Act
+			err := c.Run(ctx)
+
+			// Assert
+			assert.ErrorContains(t, err, tc.expectedErr)
+			assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 2}, fake.committed())
+			assert.NotContains(t, offsets, kafka.Offset(3))
+		})
+	}
+}
+
+func TestConsumer_CommitsSyncBatches(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		newMessage("user-topic", 10, nil),
+		newMessage("user-topic", 11, nil),
+		newMessage("user-topic", 12, nil),
+	}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{CommitMode: CommitSync, CommitBatchSize: 2})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, [][]kafka.TopicPartition{
+		{newPartition("user-topic", 1, 12)},
+		{newPartition("user-topic", 1, 13)},
+	}, fake.commits)
+}
+
+func TestConsumer_CommitsAsync(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var events []kafka.Event
+	for offset := kafka.Offset(0); offset < 50; offset++ {
+		events = append(events, newMessage("user-topic", offset, nil))
+	}
+	fake := &fakeConsumer{cancel: cancel, events: events}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{CommitMode: CommitAsync, CommitInterval: time.Nanosecond})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 50}, fake.committed())
+}
+
+func TestConsumer_CommitsRevokedPartitions(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	message := newMessage("user-topic", 7, nil)
+	message.TopicPartition.Partition = 2
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid), newPartition("user-topic", 2, kafka.OffsetInvalid)}},
+		newMessage("user-topic", 4, nil),
+		message,
+		kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid)}},
+	}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{CommitBatchSize: 10})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, [][]kafka.TopicPartition{
+		{newPartition("user-topic", 1, 5)},
+		{newPartition("user-topic", 2, 8)},
+	}, fake.commits)
+}
+
+func TestConsumer_RebalanceHooks(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assigned := []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid), newPartition("user-topic", 2, kafka.OffsetInvalid)}
+	revoked := []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid)}
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		kafka.AssignedPartitions{Partitions: assigned},
+		newMessage("user-topic", 4, nil),
+		kafka.RevokedPartitions{Partitions: revoked},
+	}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{CommitBatchSize: 10})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+	var calls []string
+	c.OnAssign(func(partitions []kafka.TopicPartition) error {
+		assert.Equal(t, assigned, partitions)
+		calls = append(calls, "assign")
+		return nil
+	})
+	c.OnRevoke(func(partitions []kafka.TopicPartition) error {
+		assert.Equal(t, revoked, partitions)
+		assert.Empty(t, fake.committed())
+		calls = append(calls, "revoke")
+		return nil
+	})
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"assign", "revoke"}, calls)
+	assert.Equal(t, [][]kafka.TopicPartition{{newPartition("user-topic", 1, 5)}}, fake.commits)
+}
+
+func TestConsumer_LostPartitionsAreNotCommitted(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, lost: true, events: []kafka.Event{
+		newMessage("user-topic", 4, nil),
+		kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid)}},
+	}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{CommitBatchSize: 10})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+	flushed := false
+	c.OnRevoke(func([]kafka.TopicPartition) error {
+		flushed = true
+		return nil
+	})
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, flushed)
+	assert.Empty(t, fake.commits)
+}
+
+func TestConsumer_RebalanceHookFailures(t *testing.T) {
+	testCases := []struct {
+		name        string
+		register    func(c *Consumer, fn PartitionsFunc)
+		expectedErr string
+	}{
+		{name: "assign", register: (*Consumer).OnAssign, expectedErr: "failed to initialise assigned partitions: state unavailable"},
+		{name: "revoke", register: (*Consumer).OnRevoke, expectedErr: "failed to flush revoked partitions: state unavailable"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			partitions := []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid)}
+			fake := &fakeConsumer{cancel: func() {}, events: []kafka.Event{
+				kafka.AssignedPartitions{Partitions: partitions},
+				newMessage("user-topic", 4, nil),
+				kafka.RevokedPartitions{Partitions: partitions},
+			}}
+			c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{CommitBatchSize: 10})
+			Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+			tc.register(c, func([]kafka.TopicPartition) error { return errors.New("state unavailable") })
+
+			// Act
+			err := c.Run(context.Background())
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Empty(t, fake.commits)
+		})
+	}
+}
+
+func TestConsumer_UnsupportedCommitMode(t *testing.T) {
+	// Arrange
+	c := NewConsumer(&fakeConsumer{}, &MockDeserializer{}, nil, config.ConsumerConfig{CommitMode: "auto"})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act
+	err := c.Run(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, `unsupported commit mode "auto", expected sync or async`)
+}
+
+func TestConsumer_FatalError(t *testing.T) {
+	// Arrange
+	fake := &fakeConsumer{events: []kafka.Event{kafka.NewError(kafka.ErrFatal, "fenced", true)}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act
+	err := c.Run(context.Background())
+
+	// Assert
+	assert.ErrorContains(t, err, "fatal consumer error")
+}
+
+func TestConsumer_NoHandlers(t *testing.T) {
+	// Act
+	err := NewConsumer(&fakeConsumer{}, &MockDeserializer{}, nil, config.ConsumerConfig{}).Run(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, "no handlers registered")
+}
+
+func TestRegister_DuplicateTopic(t *testing.T) {
+	// Arrange
+	c := NewConsumer(&fakeConsumer{}, &MockDeserializer{}, nil, config.ConsumerConfig{})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act & Assert
+	assert.Panics(t, func() {
+		Register(c, "user-topic", func(context.Context, Message[string]) error { return nil })
+	})
+}
@@ -0,0 +1,102 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// ReplayOptions select where and how many dead letters are replayed.
+type ReplayOptions struct {
+	Target string // Topic to produce to, the source topic of each message when empty
+	Max    int    // Messages to replay, all when 0
+}
+
+// ReplayDeadLetters produces the messages of a dead-letter topic back to their
+// source topic, without the forwarding headers, and commits each message once it
+// is produced. It returns the number of replayed messages once every assigned
+// partition reached its end, which requires enable.partition.eof, once Max
+// messages were replayed or when ctx is done.
+func ReplayDeadLetters(ctx context.Context, consumer ConsumerInterface, producer ProducerInterface, topic string, opts ReplayOptions) (int, error) {
+	assigned := make(map[partitionKey]bool) // partitions by whether they reached their end
+	rebalance := func(_ *kafka.Consumer, ev kafka.Event) error {
+		switch e := ev.(type) {
+		case kafka.AssignedPartitions:
+			for _, tp := range e.Partitions {
+				assigned[partitionKey{topic: *tp.Topic, partition: tp.Partition}] = false
+			}
+		case kafka.RevokedPartitions:
+			for _, tp := range e.Partitions {
+				delete(assigned, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+			}
+		}
+		return nil
+	}
+	if err := consumer.SubscribeTopics([]string{topic}, rebalance); err != nil {
+		return 0, fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	replayed := 0
+	for ctx.Err() == nil {
+		switch e := consumer.Poll(pollTimeoutMs).(type) {
+		case *kafka.Message:
+			assigned[partitionKey{topic: *e.TopicPartition.Topic, partition: e.TopicPartition.Partition}] = false
+			if err := replay(ctx, consumer, producer, e, opts.Target); err != nil {
+				return replayed, err
+			}
+			replayed++
+			if opts.Max > 0 && replayed >= opts.Max {
+				return replayed, nil
+			}
+		case kafka.PartitionEOF:
+			assigned[partitionKey{topic: *e.Topic, partition: e.Partition}] = true
+			if allAtEnd(assigned) {
+				return replayed, nil
+			}
+		case kafka.Error:
+			if e.IsFatal() {
+				return replayed, fmt.Errorf("fatal consumer error: %w", e)
+			}
+			log.Printf("Consumer error: %v", e)
+		}
+	}
+	return replayed, nil
+}
+
+// replay produces a dead letter to target, or to its source topic, and commits it.
+func replay(ctx context.Context, consumer ConsumerInterface, producer ProducerInterface, msg *kafka.Message, target string) error {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if target == "" {
+		target = headers[HeaderSourceTopic]
+	}
+	if target == "" {
+		return fmt.Errorf("message on %s has no %s header", msg.TopicPartition, HeaderSourceTopic)
+	}
+	for _, key := range forwardHeaders {
+		delete(headers, key)
+	}
+
+	if err := producer.ProduceMessage(ctx, target, msg.Value, msg.Key, headers); err != nil {
+		return fmt.Errorf("failed to replay message on %s to %s: %w", msg.TopicPartition, target, err)
+	}
+	next := msg.TopicPartition
+	next.Offset++
+	if _, err := consumer.CommitOffsets([]kafka.TopicPartition{next}); err != nil {
+		return fmt.Errorf("failed to commit offset of %s: %w", msg.TopicPartition, err)
+	}
+	return nil
+}
+
+func allAtEnd(assigned map[partitionKey]bool) bool {
+	for _, atEnd := range assigned {
+		if !atEnd {
+			return false
+		}
+	}
+	return true
+}
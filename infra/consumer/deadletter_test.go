@@ -0,0 +1,94 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newDeadLetter(partition int32, offset kafka.Offset, source string) *kafka.Message {
+	msg := newMessage("user-topic-dlq", offset, []byte("user"))
+	msg.TopicPartition.Partition = partition
+	msg.Headers = []kafka.Header{
+		{Key: "trace-id", Value: []byte("abc")},
+		{Key: HeaderSourceTopic, Value: []byte(source)},
+		{Key: HeaderSourcePartition, Value: []byte("0")},
+		{Key: HeaderSourceOffset, Value: []byte("3")},
+		{Key: HeaderError, Value: []byte("handler failed")},
+	}
+	return msg
+}
+
+func TestReplayDeadLetters_UntilEveryPartitionEnds(t *testing.T) {
+	// Arrange
+	fake := &fakeConsumer{cancel: func() {}, events: []kafka.Event{
+		kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{newPartition("user-topic-dlq", 0, kafka.OffsetInvalid), newPartition("user-topic-dlq", 1, kafka.OffsetInvalid)}},
+		newDeadLetter(0, 5, "user-topic"),
+		kafka.PartitionEOF(newPartition("user-topic-dlq", 0, 6)),
+		newDeadLetter(1, 2, "user-topic"),
+		kafka.PartitionEOF(newPartition("user-topic-dlq", 1, 3)),
+		newDeadLetter(1, 3, "user-topic"),
+	}}
+	producer := &MockProducer{}
+	producer.On("ProduceMessage", mock.Anything, "user-topic", []byte("user"), []byte("key"), map[string]string{"trace-id": "abc"}).Return(nil)
+
+	// Act
+	replayed, err := ReplayDeadLetters(context.Background(), fake, producer, "user-topic-dlq", ReplayOptions{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, []string{"user-topic-dlq"}, fake.subscribed)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic-dlq/0": 6, "user-topic-dlq/1": 3}, fake.committed())
+}
+
+func TestReplayDeadLetters_TargetAndMax(t *testing.T) {
+	// Arrange
+	fake := &fakeConsumer{cancel: func() {}, events: []kafka.Event{
+		newDeadLetter(0, 5, "user-topic"),
+		newDeadLetter(0, 6, "user-topic"),
+	}}
+	producer := &MockProducer{}
+	producer.On("ProduceMessage", mock.Anything, "user-topic-replay", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// Act
+	replayed, err := ReplayDeadLetters(context.Background(), fake, producer, "user-topic-dlq", ReplayOptions{Target: "user-topic-replay", Max: 1})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic-dlq/0": 6}, fake.committed())
+}
+
+func TestReplayDeadLetters_Failures(t *testing.T) {
+	testCases := []struct {
+		name        string
+		source      string
+		produceErr  error
+		expectedErr string
+	}{
+		{name: "missing source", expectedErr: "message on user-topic-dlq[0]@5 has no x-source-topic header"},
+		{name: "produce", source: "user-topic", produceErr: errors.New("broker down"), expectedErr: "failed to replay message on user-topic-dlq[0]@5 to user-topic: broker down"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			fake := &fakeConsumer{cancel: func() {}, events: []kafka.Event{newDeadLetter(0, 5, tc.source)}}
+			producer := &MockProducer{}
+			producer.On("ProduceMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tc.produceErr)
+
+			// Act
+			replayed, err := ReplayDeadLetters(context.Background(), fake, producer, "user-topic-dlq", ReplayOptions{})
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Equal(t, 0, replayed)
+			assert.Empty(t, fake.committed())
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package consumer
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"kafka-go-example/infra/serde"
+)
+
+const defaultDedupStoreSize = 10000
+
+// DedupStoreInterface records the last version handled per deduplication key.
+type DedupStoreInterface interface {
+	// Version returns the last version recorded for key, false when there is none.
+	Version(ctx context.Context, key string) (string, bool, error)
+	// SetVersion records version as the last handled for key.
+	SetVersion(ctx context.Context, key, version string) error
+}
+
+// DedupOptions identify duplicates, by a version field of the value or an event
+// id header, exactly one of them.
+type DedupOptions struct {
+	Field  string                      // Dotted version field of the value, e.g. updated_at: versions of a key up to the last handled are skipped
+	Header string                      // Event id header set by the producer: events of a key already handled are skipped
+	Keys   serde.DeserializerInterface // Decodes keys framed by the schema registry, whose bytes change with the schema id; keys are compared as bytes when nil
+}
+
+// Dedup wraps fn to skip the duplicates of the messages it handled. Messages are
+// keyed by topic and message key, decoded with Keys when it is set; with Field the last version handled per key
+// is recorded, with Header every event id. Versions compare as integers, then as
+// RFC 3339 times, then as strings. A message is recorded once fn returns nil, so a
+// message whose handling is interrupted is handled again. Tombstones, and
+// messages without a version, are always handled.
+func Dedup[T any](store DedupStoreInterface, opts DedupOptions, fn HandlerFunc[T]) (HandlerFunc[T], error) {
+	if (opts.Field == "") == (opts.Header == "") {
+		return nil, errors.New("deduplicate by a version field or an event id header")
+	}
+	if opts.Field != "" {
+		var zero T
+		if reflect.TypeOf(zero) != nil && reflect.TypeOf(zero).Kind() == reflect.Struct {
+			values, err := serde.ToMap(zero)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := serde.Lookup(values, opts.Field); !ok {
+				return nil, fmt.Errorf("cannot deduplicate by %q: %T has no such field", opts.Field, zero)
+			}
+		}
+	}
+
+	return func(ctx context.Context, msg Message[T]) error {
+		if msg.Tombstone {
+			return fn(ctx, msg)
+		}
+		key, version, err := dedupKey(msg, opts)
+		if err != nil {
+			return Permanent(err)
+		}
+		if version == "" {
+			return fn(ctx, msg)
+		}
+
+		last, found, err := store.Version(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get handled version: %w", err)
+		}
+		if found && (opts.Header != "" || compareVersions(version, last) <= 0) {
+			log.Printf("Skipping duplicate on %s [%d] at %v: version %s, handled %s", msg.Topic, msg.Partition, msg.Offset, version, last)
+			return nil
+		}
+
+		if err := fn(ctx, msg); err != nil {
+			return err
+		}
+		if err := store.SetVersion(ctx, key, version); err != nil {
+			return fmt.Errorf("failed to record handled version: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// dedupKey returns the deduplication key and version of msg. The version is
+// empty when msg has none.
+func dedupKey[T any](msg Message[T], opts DedupOptions) (string, string, error) {
+	key, err := messageKey(msg.Topic, msg.Key, opts.Keys)
+	if err != nil {
+		return "", "", err
+	}
+	if opts.Header != "" {
+		for _, header := range msg.Headers {
+			if header.Key == opts.Header && len(header.Value) > 0 {
+				// every event id is recorded, it is the version of its own key
+				return key + "/" + string(header.Value), string(header.Value), nil
+			}
+		}
+		return key, "", nil
+	}
+
+	values, err := serde.ToMap(msg.Value)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read version %q: %w", opts.Field, err)
+	}
+	version, ok := serde.Lookup(values, opts.Field)
+	if !ok || version == nil {
+		return key, "", nil
+	}
+	return key, fmt.Sprint(version), nil
+}
+
+// messageKey returns the deduplication key of a message key of topic. Keys framed
+// by the schema registry are decoded with keys and encoded as JSON, so a key
+// written under another schema id, or a new schema version, is the same key.
+func messageKey(topic string, payload []byte, keys serde.DeserializerInterface) (string, error) {
+	if keys == nil || len(payload) < 5 || payload[0] != 0 {
+		return topic + "/" + string(payload), nil
+	}
+	var decoded interface{}
+	if err := keys.DeserializeInto(topic, payload, &decoded); err != nil {
+		return "", fmt.Errorf("failed to deserialize key: %w", err)
+	}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode key: %w", err)
+	}
+	return topic + "/" + string(encoded), nil
+}
+
+// compareVersions returns -1, 0 or 1 as version a is older, equal or newer than b.
+func compareVersions(a, b string) int {
+	if x, err := strconv.ParseInt(a, 10, 64); err == nil {
+		if y, err := strconv.ParseInt(b, 10, 64); err == nil {
+			return cmp.Compare(x, y)
+		}
+	}
+	if x, err := time.Parse(time.RFC3339Nano, a); err == nil {
+		if y, err := time.Parse(time.RFC3339Nano, b); err == nil {
+			return x.Compare(y)
+		}
+	}
+	return cmp.Compare(a, b)
+}
+
+// MemoryDedupStore keeps the versions of the most recently handled keys in
+// memory. Versions are lost on restart and evicted keys are handled again.
+type MemoryDedupStore struct {
+	mu       sync.Mutex
+	size     int
+	order    *list.List               // keys from the most recently used
+	versions map[string]*list.Element // elements hold a *dedupEntry
+}
+
+type dedupEntry struct {
+	key     string
+	version string
+}
+
+// NewMemoryDedupStore returns a store of the versions of size keys, 10000 when 0.
+func NewMemoryDedupStore(size int) *MemoryDedupStore {
+	if size <= 0 {
+		size = defaultDedupStoreSize
+	}
+	return &MemoryDedupStore{size: size, order: list.New(), versions: make(map[string]*list.Element)}
+}
+
+func (s *MemoryDedupStore) Version(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	element, ok := s.versions[key]
+	if !ok {
+		return "", false, nil
+	}
+	s.order.MoveToFront(element)
+	return element.Value.(*dedupEntry).version, true, nil
+}
+
+func (s *MemoryDedupStore) SetVersion(_ context.Context, key, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if element, ok := s.versions[key]; ok {
+		element.Value.(*dedupEntry).version = version
+		s.order.MoveToFront(element)
+		return nil
+	}
+	s.versions[key] = s.order.PushFront(&dedupEntry{key: key, version: version})
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.versions, oldest.Value.(*dedupEntry).key)
+	}
+	return nil
+}
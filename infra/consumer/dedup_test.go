@@ -0,0 +1,217 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kafka-go-example/models"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func userVersion(key string, updatedAt time.Time) Message[models.User] {
+	return Message[models.User]{Topic: "user-topic", Key: []byte(key), Value: models.User{Name: key, UpdatedAt: updatedAt}}
+}
+
+func event(key, id string) Message[models.User] {
+	return Message[models.User]{Topic: "user-topic", Key: []byte(key), Headers: []kafka.Header{{Key: "event-id", Value: []byte(id)}}}
+}
+
+func TestDedup_SkipsDuplicates(t *testing.T) {
+	v1 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v2 := v1.Add(time.Second)
+	testCases := []struct {
+		name     string
+		opts     DedupOptions
+		messages []Message[models.User]
+		expected []int // indexes of the handled messages
+	}{
+		{
+			name:     "version field",
+			opts:     DedupOptions{Field: "updated_at"},
+			messages: []Message[models.User]{userVersion("a", v1), userVersion("a", v1), userVersion("b", v1), userVersion("a", v2), userVersion("a", v1)},
+			expected: []int{0, 2, 3},
+		},
+		{
+			name:     "event id header",
+			opts:     DedupOptions{Header: "event-id"},
+			messages: []Message[models.User]{event("a", "1"), event("a", "2"), event("a", "1"), event("b", "1"), {Topic: "user-topic", Key: []byte("a")}},
+			expected: []int{0, 1, 3, 4},
+		},
+		{
+			name:     "tombstones",
+			opts:     DedupOptions{Field: "updated_at"},
+			messages: []Message[models.User]{{Topic: "user-topic", Key: []byte("a"), Tombstone: true}, {Topic: "user-topic", Key: []byte("a"), Tombstone: true}},
+			expected: []int{0, 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			var handled []int
+			index := 0
+			handle, err := Dedup(NewMemoryDedupStore(0), tc.opts, func(_ context.Context, _ Message[models.User]) error {
+				handled = append(handled, index)
+				return nil
+			})
+			assert.NoError(t, err)
+
+			// Act
+			for i, msg := range tc.messages {
+				index = i
+				assert.NoError(t, handle(context.Background(), msg))
+			}
+
+			// Assert
+			assert.Equal(t, tc.expected, handled)
+		})
+	}
+}
+
+func TestDedup_DecodesRegistryKeys(t *testing.T) {
+	// Arrange
+	v1 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	keyV1 := []byte{0, 0, 0, 0, 1, 14} // user 7 under schema 1
+	keyV2 := []byte{0, 0, 0, 0, 2, 14} // user 7 under schema 2
+	keys := &MockDeserializer{}
+	for _, key := range [][]byte{keyV1, keyV2} {
+		keys.On("DeserializeInto", "user-topic", key, mock.Anything).Return(nil, func(msg interface{}) {
+			*msg.(*interface{}) = map[string]interface{}{"user_id": 7}
+		})
+	}
+	calls := 0
+	handle, err := Dedup(NewMemoryDedupStore(0), DedupOptions{Field: "updated_at", Keys: keys}, func(_ context.Context, _ Message[models.User]) error {
+		calls++
+		return nil
+	})
+	assert.NoError(t, err)
+	first := userVersion("a", v1)
+	first.Key = keyV1
+	duplicate := userVersion("a", v1)
+	duplicate.Key = keyV2
+	plain := userVersion("a", v1) // not framed by the registry, compared as bytes
+
+	// Act
+	firstErr := handle(context.Background(), first)
+	duplicateErr := handle(context.Background(), duplicate)
+	plainErr := handle(context.Background(), plain)
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.NoError(t, duplicateErr)
+	assert.NoError(t, plainErr)
+	assert.Equal(t, 2, calls)
+	keys.AssertNumberOfCalls(t, "DeserializeInto", 2)
+}
+
+func TestDedup_UndecodableKeyIsPermanent(t *testing.T) {
+	// Arrange
+	keys := &MockDeserializer{}
+	keys.On("DeserializeInto", "user-topic", mock.Anything, mock.Anything).Return(errors.New("schema not found"), nil)
+	handle, err := Dedup(NewMemoryDedupStore(0), DedupOptions{Header: "event-id", Keys: keys}, func(_ context.Context, _ Message[models.User]) error {
+		return nil
+	})
+	assert.NoError(t, err)
+	msg := event("a", "1")
+	msg.Key = []byte{0, 0, 0, 0, 9, 2}
+
+	// Act
+	err = handle(context.Background(), msg)
+
+	// Assert
+	assert.EqualError(t, err, "failed to deserialize key: schema not found")
+	assert.True(t, isPermanent(err))
+}
+
+func TestDedup_FailedMessagesAreHandledAgain(t *testing.T) {
+	// Arrange
+	calls := 0
+	handle, err := Dedup(NewMemoryDedupStore(0), DedupOptions{Header: "event-id"}, func(_ context.Context, _ Message[models.User]) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database is down")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// Act
+	firstErr := handle(context.Background(), event("a", "1"))
+	retryErr := handle(context.Background(), event("a", "1"))
+	duplicateErr := handle(context.Background(), event("a", "1"))
+
+	// Assert
+	assert.EqualError(t, firstErr, "database is down")
+	assert.NoError(t, retryErr)
+	assert.NoError(t, duplicateErr)
+	assert.Equal(t, 2, calls)
+}
+
+func TestDedup_InvalidOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        DedupOptions
+		expectedErr string
+	}{
+		{name: "none", expectedErr: "deduplicate by a version field or an event id header"},
+		{name: "both", opts: DedupOptions{Field: "updated_at", Header: "event-id"}, expectedErr: "deduplicate by a version field or an event id header"},
+		{name: "unknown field", opts: DedupOptions{Field: "version"}, expectedErr: `cannot deduplicate by "version": models.User has no such field`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			handle, err := Dedup(NewMemoryDedupStore(0), tc.opts, func(_ context.Context, _ Message[models.User]) error { return nil })
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Nil(t, handle)
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "9", b: "10", expected: -1},
+		{a: "2024-05-01T12:00:00.5Z", b: "2024-05-01T12:00:00Z", expected: 1},
+		{a: "2024-05-01T14:00:00+02:00", b: "2024-05-01T12:00:00Z", expected: 0},
+		{a: "v2", b: "v10", expected: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			// Act & Assert
+			assert.Equal(t, tc.expected, compareVersions(tc.a, tc.b))
+		})
+	}
+}
+
+func TestMemoryDedupStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewMemoryDedupStore(2)
+	assert.NoError(t, store.SetVersion(ctx, "a", "1"))
+	assert.NoError(t, store.SetVersion(ctx, "b", "1"))
+	_, _, _ = store.Version(ctx, "a")
+
+	// Act
+	err := store.SetVersion(ctx, "c", "1")
+
+	// Assert
+	assert.NoError(t, err)
+	_, foundA, _ := store.Version(ctx, "a")
+	_, foundB, _ := store.Version(ctx, "b")
+	version, foundC, _ := store.Version(ctx, "c")
+	assert.True(t, foundA)
+	assert.False(t, foundB)
+	assert.True(t, foundC)
+	assert.Equal(t, "1", version)
+}
@@ -0,0 +1,115 @@
+package consumer
+
+import (
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// partitionOffsets tracks the messages of a partition being handled.
+type partitionOffsets struct {
+	inFlight  map[kafka.Offset]struct{}
+	next      kafka.Offset // offset after the highest handled message
+	committed kafka.Offset
+}
+
+// offsetTracker tracks handled messages per partition. The offset to commit is
+// the lowest offset still being handled, or the offset after the highest handled
+// message, so messages are never committed before every earlier one is handled.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{partitions: make(map[partitionKey]*partitionOffsets)}
+}
+
+func (t *offsetTracker) partition(tp kafka.TopicPartition) *partitionOffsets {
+	key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+	p, ok := t.partitions[key]
+	if !ok {
+		p = &partitionOffsets{inFlight: make(map[kafka.Offset]struct{}), next: kafka.OffsetInvalid, committed: kafka.OffsetInvalid}
+		t.partitions[key] = p
+	}
+	return p
+}
+
+// start marks the message at tp as being handled.
+func (t *offsetTracker) start(tp kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.partition(tp).inFlight[tp.Offset] = struct{}{}
+}
+
+// done marks the message at tp as handled.
+func (t *offsetTracker) done(tp kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := t.partition(tp)
+	delete(p.inFlight, tp.Offset)
+	if tp.Offset+1 > p.next {
+		p.next = tp.Offset + 1
+	}
+}
+
+// uncommitted returns the offsets to commit of partitions, or of every partition when nil.
+func (t *offsetTracker) uncommitted(partitions []kafka.TopicPartition) []kafka.TopicPartition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var offsets []kafka.TopicPartition
+	add := func(key partitionKey, p *partitionOffsets) {
+		offset := p.next
+		for inFlight := range p.inFlight {
+			if inFlight < offset || offset == kafka.OffsetInvalid {
+				offset = inFlight
+			}
+		}
+		if offset > p.committed {
+			topic := key.topic
+			offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: key.partition, Offset: offset})
+		}
+	}
+	if partitions == nil {
+		for key, p := range t.partitions {
+			add(key, p)
+		}
+		return offsets
+	}
+	for _, tp := range partitions {
+		key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+		if p, ok := t.partitions[key]; ok {
+			add(key, p)
+		}
+	}
+	return offsets
+}
+
+// committed records committed offsets, ignoring partitions that failed.
+func (t *offsetTracker) committed(offsets []kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tp := range offsets {
+		if tp.Error != nil || tp.Offset < 0 {
+			continue
+		}
+		if p := t.partition(tp); tp.Offset > p.committed {
+			p.committed = tp.Offset
+		}
+	}
+}
+
+// remove forgets revoked partitions.
+func (t *offsetTracker) remove(partitions []kafka.TopicPartition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tp := range partitions {
+		delete(t.partitions, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+	}
+}
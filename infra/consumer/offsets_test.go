@@ -0,0 +1,51 @@
+package consumer
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOffsetTracker_CommitsLowestUnhandledOffset(t *testing.T) {
+	// Arrange
+	tracker := newOffsetTracker()
+	for offset := kafka.Offset(1); offset <= 3; offset++ {
+		tracker.start(newPartition("user-topic", 0, offset))
+	}
+	tracker.done(newPartition("user-topic", 0, 2))
+	tracker.done(newPartition("user-topic", 0, 3))
+
+	// Act
+	pending := tracker.uncommitted(nil)
+	tracker.done(newPartition("user-topic", 0, 1))
+	handled := tracker.uncommitted(nil)
+	tracker.committed(handled)
+	committed := tracker.uncommitted(nil)
+
+	// Assert
+	assert.Equal(t, []kafka.TopicPartition{newPartition("user-topic", 0, 1)}, pending)
+	assert.Equal(t, []kafka.TopicPartition{newPartition("user-topic", 0, 4)}, handled)
+	assert.Empty(t, committed)
+}
+
+func TestOffsetTracker_Partitions(t *testing.T) {
+	// Arrange
+	tracker := newOffsetTracker()
+	for partition := int32(0); partition < 3; partition++ {
+		tracker.start(newPartition("user-topic", partition, 5))
+		tracker.done(newPartition("user-topic", partition, 5))
+	}
+	failed := newPartition("user-topic", 2, 6)
+	failed.Error = kafka.NewError(kafka.ErrRebalanceInProgress, "rebalance", false)
+
+	// Act
+	selected := tracker.uncommitted([]kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid), newPartition("other-topic", 0, kafka.OffsetInvalid)})
+	tracker.committed([]kafka.TopicPartition{failed})
+	tracker.remove([]kafka.TopicPartition{newPartition("user-topic", 0, kafka.OffsetInvalid)})
+	remaining := tracker.uncommitted(nil)
+
+	// Assert
+	assert.Equal(t, []kafka.TopicPartition{newPartition("user-topic", 1, 6)}, selected)
+	assert.ElementsMatch(t, []kafka.TopicPartition{newPartition("user-topic", 1, 6), newPartition("user-topic", 2, 6)}, remaining)
+}
@@ -0,0 +1,205 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Headers of messages forwarded to retry and dead-letter topics. The source
+// headers locate the message that failed first and are kept across retries.
+const (
+	HeaderSourceTopic     = "x-source-topic"
+	HeaderSourcePartition = "x-source-partition"
+	HeaderSourceOffset    = "x-source-offset"
+	HeaderError           = "x-error"
+	HeaderRetryTier       = "x-retry-tier"
+	HeaderRetryDelay      = "x-retry-delay"
+	HeaderRetryNotBefore  = "x-retry-not-before" // Unix milliseconds
+)
+
+// forwardHeaders are removed when dead letters are replayed.
+var forwardHeaders = []string{
+	HeaderSourceTopic, HeaderSourcePartition, HeaderSourceOffset, HeaderError,
+	HeaderRetryTier, HeaderRetryDelay, HeaderRetryNotBefore,
+}
+
+// ProducerInterface forwards failed messages to retry and dead-letter topics.
+type ProducerInterface interface {
+	ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error
+}
+
+// PermanentError marks a failure that retrying cannot fix. The message is sent
+// to the dead-letter topic without being retried.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so the message is not retried.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func isPermanent(err error) bool {
+	var permanentErr *PermanentError
+	return errors.As(err, &permanentErr)
+}
+
+// RetryTopic returns the name of the retry topic of tier, numbered from 1.
+func RetryTopic(topic string, tier int) string {
+	return fmt.Sprintf("%s-retry-%d", topic, tier)
+}
+
+// DeadLetterTopic returns the name of the dead-letter topic of topic.
+func DeadLetterTopic(topic string) string {
+	return topic + "-dlq"
+}
+
+// retrySource is the source topic and tier of a retry topic.
+type retrySource struct {
+	topic string
+	tier  int
+}
+
+// pausedPartition is a partition paused until its next message is due, or until
+// the queue of worker has room for it.
+type pausedPartition struct {
+	tp     kafka.TopicPartition
+	until  time.Time
+	worker *worker
+}
+
+// attempt runs h, retrying failures that are not permanent in place with backoff.
+// It stops retrying when retries is done. The poll loop waits for these retries
+// on rebalances, and handles nothing else meanwhile without workers, so the
+// total backoff must stay well under max.poll.interval.ms.
+func (c *Consumer) attempt(ctx, retries context.Context, h handler, topic string, msg *kafka.Message) error {
+	backoff := c.cfg.RetryBackoff
+	for retry := 0; ; retry++ {
+		err := h(ctx, topic, msg)
+		if err == nil || isPermanent(err) || retry >= c.cfg.RetryAttempts {
+			return err
+		}
+		log.Printf("Failed to handle message on %s, retrying in %s: %v", msg.TopicPartition, backoff, err)
+		select {
+		case <-retries.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, c.cfg.RetryMaxBackoff)
+	}
+}
+
+// forward sends a message that failed with cause to the next retry topic, or to
+// the dead-letter topic once every tier failed or cause is permanent. Without a
+// dead-letter topic a permanent cause is skipped under PermanentSkip; otherwise
+// cause is returned when there is nowhere to forward the message.
+func (c *Consumer) forward(ctx context.Context, msg *kafka.Message, source retrySource, cause error) error {
+	headers := make(map[string]string, len(msg.Headers)+len(forwardHeaders))
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if _, ok := headers[HeaderSourceTopic]; !ok {
+		headers[HeaderSourceTopic] = source.topic
+		headers[HeaderSourcePartition] = strconv.Itoa(int(msg.TopicPartition.Partition))
+		headers[HeaderSourceOffset] = msg.TopicPartition.Offset.String()
+	}
+	headers[HeaderError] = cause.Error()
+	delete(headers, HeaderRetryTier)
+	delete(headers, HeaderRetryDelay)
+	delete(headers, HeaderRetryNotBefore)
+
+	var topic string
+	switch tier := source.tier + 1; {
+	case !isPermanent(cause) && tier <= len(c.delays):
+		delay := c.delays[tier-1]
+		topic = RetryTopic(source.topic, tier)
+		headers[HeaderRetryTier] = strconv.Itoa(tier)
+		headers[HeaderRetryDelay] = delay.String()
+		headers[HeaderRetryNotBefore] = strconv.FormatInt(time.Now().Add(delay).UnixMilli(), 10)
+	case c.cfg.DeadLetter:
+		topic = DeadLetterTopic(source.topic)
+	case isPermanent(cause) && c.cfg.PermanentErrors == PermanentSkip:
+		log.Printf("Skipped message on %s after a permanent failure: %v", msg.TopicPartition, cause)
+		return nil
+	default:
+		return cause
+	}
+
+	if err := c.producer.ProduceMessage(ctx, topic, msg.Value, msg.Key, headers); err != nil {
+		return fmt.Errorf("failed to forward message on %s to %s: %w", msg.TopicPartition, topic, err)
+	}
+	log.Printf("Forwarded message on %s to %s: %v", msg.TopicPartition, topic, cause)
+	return nil
+}
+
+// notBefore returns when a message of a retry topic is due, zero when it has no delay.
+func notBefore(msg *kafka.Message) time.Time {
+	for _, header := range msg.Headers {
+		if header.Key != HeaderRetryNotBefore {
+			continue
+		}
+		millis, err := strconv.ParseInt(string(header.Value), 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		return time.UnixMilli(millis)
+	}
+	return time.Time{}
+}
+
+// delay pauses the partition of msg until the message is due.
+func (c *Consumer) delay(msg *kafka.Message, until time.Time) error {
+	return c.pause(msg, pausedPartition{until: until})
+}
+
+// pause pauses the partition of msg and rewinds it to msg, so msg is consumed
+// again once resumeDue resumes the partition.
+func (c *Consumer) pause(msg *kafka.Message, paused pausedPartition) error {
+	tp := msg.TopicPartition
+	if err := c.consumer.Pause([]kafka.TopicPartition{tp}); err != nil {
+		return fmt.Errorf("failed to pause %s: %w", tp, err)
+	}
+	if err := c.consumer.Seek(tp, 0); err != nil {
+		return fmt.Errorf("failed to rewind %s: %w", tp, err)
+	}
+	paused.tp = tp
+	c.paused[partitionKey{topic: *tp.Topic, partition: tp.Partition}] = paused
+	return nil
+}
+
+// isPaused reports whether the partition of tp waits for a delayed message or a worker.
+// Messages fetched before it was paused are consumed again once it resumes.
+func (c *Consumer) isPaused(tp kafka.TopicPartition) bool {
+	_, ok := c.paused[partitionKey{topic: *tp.Topic, partition: tp.Partition}]
+	return ok
+}
+
+// resumeDue resumes the paused partitions whose delayed message is due and whose
+// worker has room.
+func (c *Consumer) resumeDue() error {
+	now := time.Now()
+	for key, paused := range c.paused {
+		if now.Before(paused.until) || (paused.worker != nil && !paused.worker.hasRoom()) {
+			continue
+		}
+		if err := c.consumer.Resume([]kafka.TopicPartition{paused.tp}); err != nil {
+			return fmt.Errorf("failed to resume %s: %w", paused.tp, err)
+		}
+		delete(c.paused, key)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/models"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockProducer struct {
+	mock.Mock
+}
+
+func (m *MockProducer) ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error {
+	args := m.Called(ctx, topic, payload, key, headers)
+	return args.Error(0)
+}
+
+// forwarded returns the headers of the message produced to topic.
+func (m *MockProducer) forwarded(t *testing.T, topic string) map[string]string {
+	for _, call := range m.Calls {
+		if call.Arguments.String(1) == topic {
+			return call.Arguments.Get(4).(map[string]string)
+		}
+	}
+	t.Fatalf("no message produced to %s", topic)
+	return nil
+}
+
+// runConsumer runs a consumer of user-topic over events with handle as handler.
+func runConsumer(events []kafka.Event, deserializer *MockDeserializer, producer ProducerInterface, cfg config.ConsumerConfig, handle HandlerFunc[models.User]) (*fakeConsumer, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, events: events}
+	c := NewConsumer(fake, deserializer, producer, cfg)
+	Register(c, "user-topic", handle)
+	return fake, c.Run(ctx)
+}
+
+func newRetryMessage(tier int, offset kafka.Offset, notBefore time.Time) *kafka.Message {
+	msg := newMessage(RetryTopic("user-topic", tier), offset, []byte("user"))
+	msg.Headers = []kafka.Header{
+		{Key: "trace-id", Value: []byte("abc")},
+		{Key: HeaderSourceTopic, Value: []byte("user-topic")},
+		{Key: HeaderSourcePartition, Value: []byte("0")},
+		{Key: HeaderSourceOffset, Value: []byte("3")},
+		{Key: HeaderError, Value: []byte("first failure")},
+		{Key: HeaderRetryTier, Value: []byte(strconv.Itoa(tier))},
+		{Key: HeaderRetryNotBefore, Value: []byte(strconv.FormatInt(notBefore.UnixMilli(), 10))},
+	}
+	return msg
+}
+
+func TestConsumer_RetriesInPlace(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	calls := 0
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{newMessage("user-topic", 4, []byte("user"))}, deserializer, nil,
+		config.ConsumerConfig{RetryAttempts: 2, RetryBackoff: time.Millisecond, RetryMaxBackoff: time.Millisecond},
+		func(context.Context, Message[models.User]) error {
+			calls++
+			if calls < 3 {
+				return errors.New("database unavailable")
+			}
+			return nil
+		})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 5}, fake.committed())
+}
+
+func TestConsumer_ForwardsToRetryTopic(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	producer := &MockProducer{}
+	producer.On("ProduceMessage", mock.Anything, "user-topic-retry-1", []byte("user"), []byte("key"), mock.Anything).Return(nil)
+	msg := newMessage("user-topic", 4, []byte("user"))
+	msg.Headers = []kafka.Header{{Key: "trace-id", Value: []byte("abc")}}
+	start := time.Now()
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{msg}, deserializer, producer,
+		config.ConsumerConfig{RetryDelays: "1m,10m", DeadLetter: true},
+		func(context.Context, Message[models.User]) error { return errors.New("database unavailable") })
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-topic", "user-topic-retry-1", "user-topic-retry-2"}, fake.subscribed)
+	headers := producer.forwarded(t, "user-topic-retry-1")
+	notBefore, parseErr := strconv.ParseInt(headers[HeaderRetryNotBefore], 10, 64)
+	assert.NoError(t, parseErr)
+	assert.WithinDuration(t, start.Add(time.Minute), time.UnixMilli(notBefore), 5*time.Second)
+	delete(headers, HeaderRetryNotBefore)
+	assert.Equal(t, map[string]string{
+		"trace-id":            "abc",
+		HeaderSourceTopic:     "user-topic",
+		HeaderSourcePartition: "1",
+		HeaderSourceOffset:    "4",
+		HeaderError:           "database unavailable",
+		HeaderRetryTier:       "1",
+		HeaderRetryDelay:      "1m0s",
+	}, headers)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 5}, fake.committed())
+}
+
+func TestConsumer_DeadLettersAfterLastRetryTopic(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	producer := &MockProducer{}
+	producer.On("ProduceMessage", mock.Anything, "user-topic-dlq", []byte("user"), []byte("key"), mock.Anything).Return(nil)
+	var topics []string
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{newRetryMessage(1, 8, time.Now().Add(-time.Second))}, deserializer, producer,
+		config.ConsumerConfig{RetryDelays: "1m", DeadLetter: true},
+		func(_ context.Context, msg Message[models.User]) error {
+			topics = append(topics, msg.Topic)
+			return errors.New("still failing")
+		})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-topic"}, topics)
+	assert.Equal(t, map[string]string{
+		"trace-id":            "abc",
+		HeaderSourceTopic:     "user-topic",
+		HeaderSourcePartition: "0",
+		HeaderSourceOffset:    "3",
+		HeaderError:           "still failing",
+	}, producer.forwarded(t, "user-topic-dlq"))
+	assert.Equal(t, map[string]kafka.Offset{"user-topic-retry-1/1": 9}, fake.committed())
+}
+
+func TestConsumer_DelaysRetryMessagesUntilDue(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	delay := 50 * time.Millisecond
+	start := time.Now()
+	var handled []time.Time
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{newRetryMessage(1, 8, start.Add(delay)), newRetryMessage(1, 9, start.Add(delay))}, deserializer, &MockProducer{},
+		config.ConsumerConfig{RetryDelays: "1m"},
+		func(context.Context, Message[models.User]) error {
+			handled = append(handled, time.Now())
+			return nil
+		})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, fake.seeks, 1)
+	assert.Equal(t, kafka.Offset(8), fake.seeks[0].Offset)
+	assert.Len(t, handled, 2)
+	assert.False(t, handled[0].Before(time.UnixMilli(start.Add(delay).UnixMilli())))
+	assert.Equal(t, map[string]kafka.Offset{"user-topic-retry-1/1": 10}, fake.committed())
+}
+
+func TestConsumer_DeadLettersUndecodablePayloadsWithoutRetrying(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("bad"), mock.Anything).Return(errors.New("unknown magic byte"), nil)
+	producer := &MockProducer{}
+	producer.On("ProduceMessage", mock.Anything, "user-topic-dlq", []byte("bad"), []byte("key"), mock.Anything).Return(nil)
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{newMessage("user-topic", 2, []byte("bad"))}, deserializer, producer,
+		config.ConsumerConfig{RetryAttempts: 3, RetryDelays: "1m", DeadLetter: true},
+		func(context.Context, Message[models.User]) error { return nil })
+
+	// Assert
+	assert.NoError(t, err)
+	deserializer.AssertNumberOfCalls(t, "DeserializeInto", 1)
+	producer.AssertNumberOfCalls(t, "ProduceMessage", 1)
+	assert.Equal(t, "failed to deserialize payload: unknown magic byte", producer.forwarded(t, "user-topic-dlq")[HeaderError])
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 3}, fake.committed())
+}
+
+func TestConsumer_SkipsPermanentFailuresWithoutDeadLetterTopic(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("bad"), mock.Anything).Return(errors.New("unknown magic byte"), nil)
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	var offsets []kafka.Offset
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{
+		newMessage("user-topic", 1, []byte("bad")),
+		newMessage("user-topic", 2, []byte("user")),
+		newMessage("user-topic", 3, []byte("user")),
+	}, deserializer, nil, config.ConsumerConfig{RetryAttempts: 3}, func(_ context.Context, msg Message[models.User]) error {
+		offsets = append(offsets, msg.Offset)
+		if msg.Offset == 2 {
+			return Permanent(errors.New("invalid user"))
+		}
+		return nil
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []kafka.Offset{2, 3}, offsets) // permanent failures are not retried
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 4}, fake.committed())
+}
+
+func TestConsumer_ForwardFailureStopsBeforeCommittingMessage(t *testing.T) {
+	// Arrange
+	deserializer := &MockDeserializer{}
+	deserializer.On("DeserializeInto", "user-topic", []byte("user"), mock.Anything).Return(nil, nil)
+	producer := &MockProducer{}
+	producer.On("ProduceMessage", mock.Anything, "user-topic-dlq", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("broker down"))
+
+	// Act
+	fake, err := runConsumer([]kafka.Event{newMessage("user-topic", 2, []byte("user"))}, deserializer, producer,
+		config.ConsumerConfig{DeadLetter: true},
+		func(context.Context, Message[models.User]) error { return errors.New("handler failed") })
+
+	// Assert
+	assert.ErrorContains(t, err, "failed to forward message on user-topic[1]@2 to user-topic-dlq: broker down")
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 2}, fake.committed())
+}
+
+func TestConsumer_RetryConfiguration(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         config.ConsumerConfig
+		expectedErr string
+	}{
+		{name: "invalid delays", cfg: config.ConsumerConfig{RetryDelays: "soon"}, expectedErr: `invalid retry delay "soon", expected a positive duration`},
+		{name: "missing producer", cfg: config.ConsumerConfig{DeadLetter: true}, expectedErr: "retry and dead-letter topics require a producer"},
+		{name: "permanent error policy", cfg: config.ConsumerConfig{PermanentErrors: "ignore"}, expectedErr: `unsupported permanent error policy "ignore", expected skip or stop`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, err := runConsumer(nil, &MockDeserializer{}, nil, tc.cfg, func(context.Context, Message[models.User]) error { return nil })
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
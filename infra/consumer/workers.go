@@ -0,0 +1,122 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// job is a message dispatched to a worker.
+type job struct {
+	msg       *kafka.Message
+	source    retrySource
+	partition *partitionJobs
+}
+
+// partitionJobs tracks the jobs dispatched for a partition. Its context is
+// cancelled when the partition is revoked, which stops their in-place retries.
+// Only the poll loop dispatches jobs and handles rebalances, so no lock is needed.
+type partitionJobs struct {
+	retries context.Context
+	cancel  context.CancelFunc
+	pending sync.WaitGroup
+}
+
+// worker handles the messages of its queue in order.
+type worker struct {
+	jobs chan job
+}
+
+// hasRoom reports whether a message can be queued without blocking. Only the
+// poll loop queues messages, so the answer holds until it queues one.
+func (w *worker) hasRoom() bool {
+	return len(w.jobs) < cap(w.jobs)
+}
+
+// startWorkers starts the workers handling dispatched messages until stopWorkers.
+func (c *Consumer) startWorkers(ctx context.Context) {
+	c.workers = make([]*worker, c.cfg.Workers)
+	for i := range c.workers {
+		w := &worker{jobs: make(chan job, c.cfg.WorkerQueueSize)}
+		c.workers[i] = w
+		c.working.Add(1)
+		go c.work(ctx, w)
+	}
+	log.Printf("Started %d workers ordered by %s", len(c.workers), c.cfg.Ordering)
+}
+
+// stopWorkers closes the worker queues and waits for the workers to finish.
+func (c *Consumer) stopWorkers() {
+	for _, w := range c.workers {
+		close(w.jobs)
+	}
+	c.working.Wait()
+}
+
+// work handles the messages queued to w. Once ctx is done, the remaining messages
+// are skipped and stay uncommitted.
+func (c *Consumer) work(ctx context.Context, w *worker) {
+	defer c.working.Done()
+	for j := range w.jobs {
+		if ctx.Err() == nil {
+			if err := c.process(ctx, j.partition.retries, j.msg, j.source); err != nil {
+				c.fail(err)
+			}
+		}
+		j.partition.pending.Done()
+	}
+}
+
+// dispatch queues a message to the worker of its partition or key. When the queue
+// is full, the partition is paused and rewound to the message until it has room.
+func (c *Consumer) dispatch(ctx context.Context, msg *kafka.Message, source retrySource) error {
+	w := c.workerOf(msg)
+	if !w.hasRoom() {
+		return c.pause(msg, pausedPartition{worker: w})
+	}
+	tp := msg.TopicPartition
+	key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+	p, ok := c.dispatched[key]
+	if !ok {
+		p = &partitionJobs{}
+		p.retries, p.cancel = context.WithCancel(ctx)
+		c.dispatched[key] = p
+	}
+	c.offsets.start(tp)
+	p.pending.Add(1)
+	w.jobs <- job{msg: msg, source: source, partition: p}
+	return nil
+}
+
+// finishJobs cancels the in-place retries of the messages dispatched for
+// partitions and waits for the workers to finish them.
+func (c *Consumer) finishJobs(partitions []kafka.TopicPartition) {
+	var revoked []*partitionJobs
+	for _, tp := range partitions {
+		key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+		if p, ok := c.dispatched[key]; ok {
+			p.cancel()
+			revoked = append(revoked, p)
+			delete(c.dispatched, key)
+		}
+	}
+	for _, p := range revoked {
+		p.pending.Wait()
+	}
+}
+
+// workerOf returns the worker of the message partition, or of its key when
+// ordering by key. Messages without a key are ordered by partition.
+func (c *Consumer) workerOf(msg *kafka.Message) *worker {
+	h := fnv.New32a()
+	if c.cfg.Ordering == OrderKey && msg.Key != nil {
+		h.Write(msg.Key)
+	} else {
+		fmt.Fprintf(h, "%s/%d", *msg.TopicPartition.Topic, msg.TopicPartition.Partition)
+	}
+	return c.workers[h.Sum32()%uint32(len(c.workers))]
+}
@@ -0,0 +1,185 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/models"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// runWorkers runs a consumer of user-topic over the events of fake until handle cancels it.
+func runWorkers(fake *fakeConsumer, cfg config.ConsumerConfig, handle func(cancel context.CancelFunc, msg Message[models.User]) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake.cancel = func() {}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, cfg)
+	Register(c, "user-topic", func(_ context.Context, msg Message[models.User]) error {
+		return handle(cancel, msg)
+	})
+	return c.Run(ctx)
+}
+
+func TestConsumer_WorkersPreserveKeyOrder(t *testing.T) {
+	// Arrange
+	var events []kafka.Event
+	for offset := kafka.Offset(0); offset < 30; offset++ {
+		msg := newMessage("user-topic", offset, nil)
+		msg.Key = []byte(fmt.Sprintf("user-%d", offset%5))
+		events = append(events, msg)
+	}
+	var mu sync.Mutex
+	handled := make(map[string][]kafka.Offset)
+	count := 0
+	fake := &fakeConsumer{events: events}
+
+	// Act
+	err := runWorkers(fake, config.ConsumerConfig{Workers: 4, Ordering: OrderKey, WorkerQueueSize: 2},
+		func(cancel context.CancelFunc, msg Message[models.User]) error {
+			time.Sleep(time.Duration(msg.Offset%3) * time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			handled[string(msg.Key)] = append(handled[string(msg.Key)], msg.Offset)
+			if count++; count == len(events) {
+				cancel()
+			}
+			return nil
+		})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, handled, 5)
+	for key, offsets := range handled {
+		assert.Len(t, offsets, 6, key)
+		assert.IsIncreasing(t, offsets, key)
+	}
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 30}, fake.committed())
+}
+
+func TestConsumer_WorkersPausePartitionWhileQueueIsFull(t *testing.T) {
+	// Arrange
+	var events []kafka.Event
+	for offset := kafka.Offset(0); offset < 4; offset++ {
+		events = append(events, newMessage("user-topic", offset, nil))
+	}
+	fake := &fakeConsumer{events: events}
+	var handled []kafka.Offset
+
+	// Act
+	err := runWorkers(fake, config.ConsumerConfig{Workers: 2, WorkerQueueSize: 1},
+		func(cancel context.CancelFunc, msg Message[models.User]) error {
+			for msg.Offset == 0 && fake.pauseCount() == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			handled = append(handled, msg.Offset)
+			if len(handled) == len(events) {
+				cancel()
+			}
+			return nil
+		})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Positive(t, fake.pauseCount())
+	assert.Equal(t, []kafka.Offset{0, 1, 2, 3}, handled)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 4}, fake.committed())
+}
+
+func TestConsumer_WorkerFailureStopsBeforeCommittingMessage(t *testing.T) {
+	// Arrange
+	events := []kafka.Event{
+		newMessage("user-topic", 0, nil),
+		newMessage("user-topic", 1, nil),
+		newMessage("user-topic", 2, nil),
+	}
+	fake := &fakeConsumer{events: events}
+	var handled []kafka.Offset
+
+	// Act
+	err := runWorkers(fake, config.ConsumerConfig{Workers: 2},
+		func(_ context.CancelFunc, msg Message[models.User]) error {
+			handled = append(handled, msg.Offset)
+			if msg.Offset == 1 {
+				return errors.New("handler failed")
+			}
+			return nil
+		})
+
+	// Assert
+	assert.EqualError(t, err, "failed to handle message on user-topic[1]@1: handler failed")
+	assert.Equal(t, []kafka.Offset{0, 1}, handled)
+	assert.Equal(t, map[string]kafka.Offset{"user-topic/1": 1}, fake.committed())
+}
+
+func TestConsumer_RevokeWaitsForWorkers(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		newMessage("user-topic", 4, nil),
+		kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid)}},
+	}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{Workers: 2})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+
+	// Act
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, [][]kafka.TopicPartition{{newPartition("user-topic", 1, 5)}}, fake.commits)
+}
+
+func TestConsumer_RevokeCancelsInPlaceRetries(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &fakeConsumer{cancel: cancel, events: []kafka.Event{
+		newMessage("user-topic", 4, nil),
+		kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{newPartition("user-topic", 1, kafka.OffsetInvalid)}},
+	}}
+	c := NewConsumer(fake, &MockDeserializer{}, nil, config.ConsumerConfig{
+		Workers:         2,
+		RetryAttempts:   3,
+		RetryBackoff:    time.Minute,
+		RetryMaxBackoff: time.Minute,
+	})
+	var attempts atomic.Int32
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error {
+		attempts.Add(1)
+		return errors.New("handler failed")
+	})
+
+	// Act
+	start := time.Now()
+	err := c.Run(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.Equal(t, int32(1), attempts.Load())
+	assert.Equal(t, [][]kafka.TopicPartition{{newPartition("user-topic", 1, 4)}}, fake.commits)
+}
+
+func TestConsumer_UnsupportedOrdering(t *testing.T) {
+	// Arrange
+	c := NewConsumer(&fakeConsumer{}, &MockDeserializer{}, nil, config.ConsumerConfig{Ordering: "topic"})
+	Register(c, "user-topic", func(context.Context, Message[models.User]) error { return nil })
+
+	// Act
+	err := c.Run(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, `unsupported ordering "topic", expected partition or key`)
+}
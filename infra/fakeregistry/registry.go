@@ -0,0 +1,565 @@
+package fakeregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kafka-go-example/infra/avro"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	hamba "github.com/hamba/avro/v2"
+)
+
+// Error codes of the registry REST API.
+const (
+	codeSubjectNotFound    = 40401
+	codeVersionNotFound    = 40402
+	codeSchemaNotFound     = 40403
+	codeConfigNotFound     = 40408
+	codeIncompatible       = 409
+	codeInvalidSchema      = 42201
+	codeInvalidVersion     = 42202
+	codeInvalidCompatLevel = 42203
+)
+
+const defaultCompatibility = "BACKWARD"
+
+// apiError is an error of the registry REST API, returned with status code / 100.
+type apiError struct {
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func newError(code int, format string, args ...interface{}) *apiError {
+	return &apiError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// schemaResponse is a registered schema as returned by the REST API.
+type schemaResponse struct {
+	Subject    string                     `json:"subject,omitempty"`
+	ID         int                        `json:"id,omitempty"`
+	Version    int                        `json:"version,omitempty"`
+	Schema     string                     `json:"schema"`
+	SchemaType string                     `json:"schemaType,omitempty"`
+	References []schemaregistry.Reference `json:"references,omitempty"`
+}
+
+type compatibilityRequest struct {
+	Compatibility string `json:"compatibility"`
+}
+
+// Registry is an in-memory schema registry serving the subset of the REST API
+// used by the serializers, deserializers and the schema command. Avro schemas are
+// checked for compatibility, other schema types are accepted as they are.
+type Registry struct {
+	mu       sync.Mutex
+	schemas  []schemaregistry.SchemaInfo // schemas by id - 1
+	subjects map[string][]int            // schema ids of each subject by version - 1
+	config   map[string]string           // compatibility levels by subject
+	global   string
+	mux      *http.ServeMux
+}
+
+// NewRegistry returns an empty registry with BACKWARD compatibility.
+func NewRegistry() *Registry {
+	r := &Registry{
+		subjects: make(map[string][]int),
+		config:   make(map[string]string),
+		global:   defaultCompatibility,
+		mux:      http.NewServeMux(),
+	}
+	r.mux.HandleFunc("GET /subjects", r.handleSubjects)
+	r.mux.HandleFunc("POST /subjects/{subject}", r.handleLookup)
+	r.mux.HandleFunc("GET /subjects/{subject}/versions", r.handleVersions)
+	r.mux.HandleFunc("POST /subjects/{subject}/versions", r.handleRegister)
+	r.mux.HandleFunc("GET /subjects/{subject}/versions/{version}", r.handleVersion)
+	r.mux.HandleFunc("GET /schemas/ids/{id}", r.handleSchema)
+	r.mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/{version}", r.handleCompatibility)
+	r.mux.HandleFunc("GET /config", r.handleGetGlobalConfig)
+	r.mux.HandleFunc("PUT /config", r.handlePutGlobalConfig)
+	r.mux.HandleFunc("GET /config/{subject}", r.handleGetConfig)
+	r.mux.HandleFunc("PUT /config/{subject}", r.handlePutConfig)
+	return r
+}
+
+// ServeHTTP serves the registry REST API.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// Register registers info under subject and returns its id and version. A schema
+// already registered under subject returns its existing version.
+func (r *Registry) Register(subject string, info schemaregistry.SchemaInfo) (int, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, version, err := r.register(subject, info)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, version, nil
+}
+
+// RegisterFile registers a schema file under subject, see avro.ReadSchemaFile for the schema types.
+func (r *Registry) RegisterFile(subject, file string) (int, int, error) {
+	info, err := avro.ReadSchemaFile(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	return r.Register(subject, info)
+}
+
+// Seed registers the .avsc files of a directory, or a single schema file of any
+// type, under subjects named after the files without their extension.
+func (r *Registry) Seed(path string) ([]string, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if stat.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.avsc")); err != nil {
+			return nil, err
+		}
+	}
+
+	subjects := make([]string, 0, len(files))
+	for _, file := range files {
+		subject := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if _, _, err := r.RegisterFile(subject, file); err != nil {
+			return nil, fmt.Errorf("failed to register %s: %w", file, err)
+		}
+		subjects = append(subjects, subject)
+	}
+	return subjects, nil
+}
+
+// register registers info under subject, r.mu must be held.
+func (r *Registry) register(subject string, info schemaregistry.SchemaInfo) (int, int, *apiError) {
+	info.SchemaType = schemaType(info.SchemaType)
+	if err := r.validate(info); err != nil {
+		return 0, 0, newError(codeInvalidSchema, "invalid schema: %v", err)
+	}
+	if version, id, ok := r.find(subject, info); ok {
+		return id, version, nil
+	}
+	compatible, err := r.compatible(subject, info, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !compatible {
+		return 0, 0, newError(codeIncompatible, "schema being registered is incompatible with an earlier schema for subject %q", subject)
+	}
+
+	id := 0
+	for i, schema := range r.schemas {
+		if sameSchema(schema, info) {
+			id = i + 1
+			break
+		}
+	}
+	if id == 0 {
+		r.schemas = append(r.schemas, info)
+		id = len(r.schemas)
+	}
+	r.subjects[subject] = append(r.subjects[subject], id)
+	return id, len(r.subjects[subject]), nil
+}
+
+// find returns the version and id of info under subject.
+func (r *Registry) find(subject string, info schemaregistry.SchemaInfo) (int, int, bool) {
+	for i, id := range r.subjects[subject] {
+		if sameSchema(r.schemas[id-1], info) {
+			return i + 1, id, true
+		}
+	}
+	return 0, 0, false
+}
+
+// validate parses Avro schemas with their references and JSON Schemas.
+func (r *Registry) validate(info schemaregistry.SchemaInfo) error {
+	switch info.SchemaType {
+	case "AVRO":
+		_, err := r.parseAvro(info)
+		return err
+	case "JSON":
+		if !json.Valid([]byte(info.Schema)) {
+			return errors.New("schema is not valid JSON")
+		}
+	}
+	return nil
+}
+
+// parseAvro parses an Avro schema after the schemas it references.
+func (r *Registry) parseAvro(info schemaregistry.SchemaInfo) (hamba.Schema, error) {
+	cache := &hamba.SchemaCache{}
+	if err := r.parseReferences(info.References, cache); err != nil {
+		return nil, err
+	}
+	return hamba.ParseWithCache(info.Schema, "", cache)
+}
+
+func (r *Registry) parseReferences(refs []schemaregistry.Reference, cache *hamba.SchemaCache) error {
+	for _, ref := range refs {
+		id, err := r.versionID(ref.Subject, ref.Version)
+		if err != nil {
+			return fmt.Errorf("reference %s: %w", ref.Name, err)
+		}
+		schema := r.schemas[id-1]
+		if err := r.parseReferences(schema.References, cache); err != nil {
+			return err
+		}
+		if _, err := hamba.ParseWithCache(schema.Schema, "", cache); err != nil {
+			return fmt.Errorf("reference %s: %w", ref.Name, err)
+		}
+	}
+	return nil
+}
+
+// compatible checks info against the versions of subject its compatibility level
+// requires. A version above 0 checks against that version only.
+func (r *Registry) compatible(subject string, info schemaregistry.SchemaInfo, version int) (bool, *apiError) {
+	ids := r.subjects[subject]
+	level := r.compatibility(subject)
+	if len(ids) == 0 || level == "NONE" || info.SchemaType != "AVRO" {
+		return true, nil
+	}
+	switch {
+	case version > 0:
+		ids = ids[version-1 : version]
+	case !strings.HasSuffix(level, "_TRANSITIVE"):
+		ids = ids[len(ids)-1:]
+	}
+
+	schema, err := r.parseAvro(info)
+	if err != nil {
+		return false, newError(codeInvalidSchema, "invalid schema: %v", err)
+	}
+	checker := hamba.NewSchemaCompatibility()
+	for _, id := range ids {
+		existing := r.schemas[id-1]
+		if existing.SchemaType != "AVRO" {
+			return false, nil
+		}
+		previous, err := r.parseAvro(existing)
+		if err != nil {
+			return false, newError(codeInvalidSchema, "invalid registered schema %d: %v", id, err)
+		}
+		backward := checker.Compatible(schema, previous) == nil
+		forward := checker.Compatible(previous, schema) == nil
+		switch strings.TrimSuffix(level, "_TRANSITIVE") {
+		case "BACKWARD":
+			if !backward {
+				return false, nil
+			}
+		case "FORWARD":
+			if !forward {
+				return false, nil
+			}
+		case "FULL":
+			if !backward || !forward {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// compatibility returns the level of subject, or the global level.
+func (r *Registry) compatibility(subject string) string {
+	if level, ok := r.config[subject]; ok {
+		return level
+	}
+	return r.global
+}
+
+// versionID returns the schema id of a version of subject, -1 being the latest.
+func (r *Registry) versionID(subject string, version int) (int, *apiError) {
+	ids, ok := r.subjects[subject]
+	if !ok {
+		return 0, newError(codeSubjectNotFound, "subject %q not found", subject)
+	}
+	if version == -1 {
+		version = len(ids)
+	}
+	if version < 1 || version > len(ids) {
+		return 0, newError(codeVersionNotFound, "version %d not found", version)
+	}
+	return ids[version-1], nil
+}
+
+func (r *Registry) handleSubjects(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subjects := make([]string, 0, len(r.subjects))
+	for subject := range r.subjects {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	writeJSON(w, subjects)
+}
+
+func (r *Registry) handleVersions(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subject := req.PathValue("subject")
+	ids, ok := r.subjects[subject]
+	if !ok {
+		writeError(w, newError(codeSubjectNotFound, "subject %q not found", subject))
+		return
+	}
+	versions := make([]int, len(ids))
+	for i := range ids {
+		versions[i] = i + 1
+	}
+	writeJSON(w, versions)
+}
+
+func (r *Registry) handleRegister(w http.ResponseWriter, req *http.Request) {
+	info, ok := readSchemaInfo(w, req)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id, _, err := r.register(req.PathValue("subject"), info)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, map[string]int{"id": id})
+}
+
+func (r *Registry) handleLookup(w http.ResponseWriter, req *http.Request) {
+	info, ok := readSchemaInfo(w, req)
+	if !ok {
+		return
+	}
+	info.SchemaType = schemaType(info.SchemaType)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subject := req.PathValue("subject")
+	if _, ok := r.subjects[subject]; !ok {
+		writeError(w, newError(codeSubjectNotFound, "subject %q not found", subject))
+		return
+	}
+	version, id, ok := r.find(subject, info)
+	if !ok {
+		writeError(w, newError(codeSchemaNotFound, "schema not found under subject %q", subject))
+		return
+	}
+	writeJSON(w, r.response(subject, version, id))
+}
+
+func (r *Registry) handleVersion(w http.ResponseWriter, req *http.Request) {
+	version, err := parseVersion(req.PathValue("version"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subject := req.PathValue("subject")
+	id, err := r.versionID(subject, version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if version == -1 {
+		version = len(r.subjects[subject])
+	}
+	writeJSON(w, r.response(subject, version, id))
+}
+
+func (r *Registry) handleSchema(w http.ResponseWriter, req *http.Request) {
+	id, convErr := strconv.Atoi(req.PathValue("id"))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if convErr != nil || id < 1 || id > len(r.schemas) {
+		writeError(w, newError(codeSchemaNotFound, "schema %s not found", req.PathValue("id")))
+		return
+	}
+	writeJSON(w, r.response("", 0, id))
+}
+
+func (r *Registry) handleCompatibility(w http.ResponseWriter, req *http.Request) {
+	version, err := parseVersion(req.PathValue("version"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	info, ok := readSchemaInfo(w, req)
+	if !ok {
+		return
+	}
+	info.SchemaType = schemaType(info.SchemaType)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subject := req.PathValue("subject")
+	if _, err := r.versionID(subject, version); err != nil {
+		writeError(w, err)
+		return
+	}
+	if version == -1 {
+		version = len(r.subjects[subject])
+	}
+	compatible, err := r.compatible(subject, info, version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, map[string]bool{"is_compatible": compatible})
+}
+
+func (r *Registry) handleGetGlobalConfig(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	writeJSON(w, map[string]string{"compatibilityLevel": r.global})
+}
+
+func (r *Registry) handlePutGlobalConfig(w http.ResponseWriter, req *http.Request) {
+	level, ok := readCompatibility(w, req)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.global = level
+	writeJSON(w, compatibilityRequest{Compatibility: level})
+}
+
+func (r *Registry) handleGetConfig(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subject := req.PathValue("subject")
+	level, ok := r.config[subject]
+	if !ok {
+		if req.URL.Query().Get("defaultToGlobal") != "true" {
+			writeError(w, newError(codeConfigNotFound, "subject %q does not have subject-level compatibility configured", subject))
+			return
+		}
+		level = r.global
+	}
+	writeJSON(w, map[string]string{"compatibilityLevel": level})
+}
+
+func (r *Registry) handlePutConfig(w http.ResponseWriter, req *http.Request) {
+	level, ok := readCompatibility(w, req)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.config[req.PathValue("subject")] = level
+	writeJSON(w, compatibilityRequest{Compatibility: level})
+}
+
+// response returns schema id, registered as version of subject when subject is set.
+func (r *Registry) response(subject string, version, id int) schemaResponse {
+	info := r.schemas[id-1]
+	return schemaResponse{
+		Subject:    subject,
+		ID:         id,
+		Version:    version,
+		Schema:     info.Schema,
+		SchemaType: info.SchemaType,
+		References: info.References,
+	}
+}
+
+// sameSchema compares schemas by type, references and content. Avro schemas are
+// compared in their parsed form, so formatting and derived schemas match files.
+func sameSchema(a, b schemaregistry.SchemaInfo) bool {
+	if a.SchemaType != b.SchemaType || !sameReferences(a.References, b.References) {
+		return false
+	}
+	if a.Schema == b.Schema {
+		return true
+	}
+	if a.SchemaType != "AVRO" || len(a.References) > 0 {
+		return false
+	}
+	schemaA, errA := hamba.ParseWithCache(a.Schema, "", &hamba.SchemaCache{})
+	schemaB, errB := hamba.ParseWithCache(b.Schema, "", &hamba.SchemaCache{})
+	return errA == nil && errB == nil && schemaA.String() == schemaB.String()
+}
+
+func sameReferences(a, b []schemaregistry.Reference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaType returns the type of a schema, Avro when empty.
+func schemaType(schemaType string) string {
+	if schemaType == "" {
+		return "AVRO"
+	}
+	return schemaType
+}
+
+func parseVersion(value string) (int, *apiError) {
+	if value == "latest" {
+		return -1, nil
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil || (version < 1 && version != -1) {
+		return 0, newError(codeInvalidVersion, "invalid version %q", value)
+	}
+	return version, nil
+}
+
+func readSchemaInfo(w http.ResponseWriter, req *http.Request) (schemaregistry.SchemaInfo, bool) {
+	var info schemaregistry.SchemaInfo
+	if err := json.NewDecoder(req.Body).Decode(&info); err != nil {
+		writeError(w, newError(codeInvalidSchema, "invalid request: %v", err))
+		return info, false
+	}
+	return info, true
+}
+
+func readCompatibility(w http.ResponseWriter, req *http.Request) (string, bool) {
+	var body compatibilityRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, newError(codeInvalidCompatLevel, "invalid request: %v", err))
+		return "", false
+	}
+	switch body.Compatibility {
+	case "NONE", "BACKWARD", "BACKWARD_TRANSITIVE", "FORWARD", "FORWARD_TRANSITIVE", "FULL", "FULL_TRANSITIVE":
+		return body.Compatibility, true
+	}
+	writeError(w, newError(codeInvalidCompatLevel, "invalid compatibility level %q", body.Compatibility))
+	return "", false
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	status := err.Code
+	if status > 999 {
+		status /= 100
+	}
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(err)
+}
@@ -0,0 +1,171 @@
+package fakeregistry
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/models"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+const schemaDir = "../../docker/kafka"
+
+var testUser = models.User{
+	ID:        42,
+	Status:    "active",
+	Name:      "Ada",
+	Country:   models.Country{Code: "GB", Name: "United Kingdom"},
+	CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 5, 2, 11, 30, 0, 0, time.UTC),
+}
+
+// startRegistry serves registry and returns a client of it.
+func startRegistry(t *testing.T, registry *Registry) schemaregistry.Client {
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+	client, err := avro.NewSchemaRegistryClient(config.SchemaRegistryConfig{SchemaRegistryUrl: server.URL})
+	assert.NoError(t, err)
+	return client
+}
+
+func TestRegistry_SeedAndRoundTrip(t *testing.T) {
+	testCases := []struct {
+		format string
+		file   string
+	}{
+		{format: serde.FormatAvro},
+		{format: serde.FormatProtobuf, file: "user-schema-value.proto"},
+		{format: serde.FormatJSONSchema, file: "user-schema-value.json"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			// Arrange
+			registry := NewRegistry()
+			seed := schemaDir
+			if tc.file != "" {
+				seed = filepath.Join(schemaDir, tc.file)
+			}
+			subjects, err := registry.Seed(seed)
+			assert.NoError(t, err)
+			client := startRegistry(t, registry)
+			serializer, err := serde.NewSerializer(client, tc.format, config.SchemaRegistryConfig{UseLatestVersion: true})
+			assert.NoError(t, err)
+			deserializer, err := serde.NewDeserializer(client, tc.format)
+			assert.NoError(t, err)
+
+			// Act
+			payload, err := serializer.Serialize("user-schema-value", &testUser)
+			assert.NoError(t, err)
+			var user models.User
+			err = deserializer.DeserializeInto("user-schema", payload, &user)
+
+			// Assert
+			assert.Equal(t, []string{"user-schema-value"}, subjects)
+			assert.NoError(t, err)
+			assert.Equal(t, testUser, user)
+		})
+	}
+}
+
+func TestRegistry_AutoRegisterMatchesParsedSchema(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	id, version, err := registry.Register("user-schema-key", schemaregistry.SchemaInfo{Schema: `{ "type": "long" }`})
+	assert.NoError(t, err)
+	client := startRegistry(t, registry)
+	serializer, err := serde.NewSerializer(client, serde.FormatAvro, config.SchemaRegistryConfig{AutoRegisterSchemas: true})
+	assert.NoError(t, err)
+
+	// Act
+	payload, err := serializer.Serialize("user-schema-key", &testUser.ID)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+	assert.Equal(t, []byte{0, 0, 0, 0, byte(id)}, payload[:5])
+	versions, err := client.GetAllVersions("user-schema-key")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, versions)
+}
+
+func TestRegistry_Compatibility(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	client := startRegistry(t, registry)
+	manager := avro.NewSchemaManager(client)
+	dir := t.TempDir()
+	v1 := filepath.Join(dir, "v1.avsc")
+	added := filepath.Join(dir, "added.avsc")
+	required := filepath.Join(dir, "required.avsc")
+	assert.NoError(t, os.WriteFile(v1, []byte(`{"type": "record", "name": "T", "fields": [{"name": "a", "type": "long"}]}`), 0o644))
+	assert.NoError(t, os.WriteFile(added, []byte(`{"type": "record", "name": "T", "fields": [{"name": "a", "type": "long"}, {"name": "b", "type": "string", "default": ""}]}`), 0o644))
+	assert.NoError(t, os.WriteFile(required, []byte(`{"type": "record", "name": "T", "fields": [{"name": "a", "type": "long"}, {"name": "c", "type": "string"}]}`), 0o644))
+
+	// Act
+	firstID, firstErr := manager.Register("t-value", v1)
+	againID, againErr := manager.Register("t-value", v1)
+	addedOK, addedErr := manager.CheckCompatibility("t-value", added)
+	requiredOK, requiredErr := manager.CheckCompatibility("t-value", required)
+	_, incompatibleErr := manager.Register("t-value", required)
+	compatibilityErr := manager.SetCompatibility("t-value", "NONE")
+	_, noneErr := manager.Register("t-value", required)
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.NoError(t, againErr)
+	assert.Equal(t, firstID, againID)
+	assert.NoError(t, addedErr)
+	assert.True(t, addedOK)
+	assert.NoError(t, requiredErr)
+	assert.False(t, requiredOK)
+	var restErr *rest.Error
+	assert.True(t, errors.As(incompatibleErr, &restErr))
+	assert.Equal(t, 409, restErr.Code)
+	assert.NoError(t, compatibilityErr)
+	assert.NoError(t, noneErr)
+	level, err := client.GetCompatibility("t-value")
+	assert.NoError(t, err)
+	assert.Equal(t, "NONE", level.String())
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	// Arrange
+	registry := NewRegistry()
+	_, err := registry.Seed(schemaDir)
+	assert.NoError(t, err)
+	client := startRegistry(t, registry)
+
+	// Act
+	_, subjectErr := client.GetLatestSchemaMetadata("missing-value")
+	_, versionErr := client.GetSchemaMetadata("user-schema-value", 2)
+	_, schemaErr := client.GetBySubjectAndID("", 99)
+	_, invalidErr := client.Register("bad-value", schemaregistry.SchemaInfo{Schema: `{"type": "nope"}`}, false)
+
+	// Assert
+	testCases := []struct {
+		err  error
+		code int
+	}{
+		{err: subjectErr, code: 40401},
+		{err: versionErr, code: 40402},
+		{err: schemaErr, code: 40403},
+		{err: invalidErr, code: 42201},
+	}
+	for _, tc := range testCases {
+		var restErr *rest.Error
+		if assert.True(t, errors.As(tc.err, &restErr), "%v", tc.err) {
+			assert.Equal(t, tc.code, restErr.Code)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kafka-go-example/infra/serde"
+)
+
+// Filter matches records on the value of one field.
+type Filter struct {
+	Path  string // Dotted path in the JSON record, e.g. value.country.code or headers.source
+	Value string
+	Not   bool
+}
+
+// ParseFilter parses "path=value" or "path!=value". An empty expression matches every record.
+func ParseFilter(expression string) (*Filter, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	filter := &Filter{}
+	path, value, ok := strings.Cut(expression, "!=")
+	if ok {
+		filter.Not = true
+	} else if path, value, ok = strings.Cut(expression, "="); !ok {
+		return nil, fmt.Errorf("invalid filter %q, expected path=value or path!=value", expression)
+	}
+	filter.Path = strings.TrimSpace(path)
+	filter.Value = strings.TrimSpace(value)
+	if filter.Path == "" {
+		return nil, fmt.Errorf("invalid filter %q, the path is empty", expression)
+	}
+	return filter, nil
+}
+
+// Match reports whether the field of the record written as JSON equals the
+// value, or differs from it for "!=". A missing field equals no value.
+func (f *Filter) Match(record Record) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode record: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return false, fmt.Errorf("failed to decode record: %w", err)
+	}
+
+	value, ok := serde.Lookup(fields, f.Path)
+	equal := ok && value != nil && fmt.Sprint(value) == f.Value
+	return equal != f.Not, nil
+}
@@ -0,0 +1,67 @@
+package inspect
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	record := Record{
+		Topic:     "user-topic",
+		Partition: 1,
+		Headers:   map[string]string{"source": "users"},
+		Key:       "7",
+		Value:     map[string]interface{}{"user_id": int64(7), "country": map[string]interface{}{"code": "NL"}},
+	}
+	testCases := []struct {
+		expression string
+		expected   bool
+	}{
+		{expression: "value.country.code=NL", expected: true},
+		{expression: "value.country.code=GB", expected: false},
+		{expression: "value.country.code!=GB", expected: true},
+		{expression: "value.user_id=7", expected: true},
+		{expression: "partition=1", expected: true},
+		{expression: "headers.source = users", expected: true},
+		{expression: "value.missing=", expected: false},
+		{expression: "value.missing!=NL", expected: true},
+		{expression: "", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			// Arrange
+			filter, err := ParseFilter(tc.expression)
+			assert.NoError(t, err)
+
+			// Act
+			match, err := filter.Match(record)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, match)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	testCases := []struct {
+		expression  string
+		expectedErr string
+	}{
+		{expression: "value.name", expectedErr: `invalid filter "value.name", expected path=value or path!=value`},
+		{expression: "=NL", expectedErr: `invalid filter "=NL", the path is empty`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			// Act
+			filter, err := ParseFilter(tc.expression)
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Nil(t, filter)
+		})
+	}
+}
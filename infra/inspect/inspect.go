@@ -0,0 +1,186 @@
+package inspect
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+const pollTimeoutMs = 100
+
+// ConsumerInterface is the part of *kafka.Consumer used to inspect topics.
+type ConsumerInterface interface {
+	ClientInterface
+	Assign(partitions []kafka.TopicPartition) error
+	IncrementalAssign(partitions []kafka.TopicPartition) error
+	GetRebalanceProtocol() string
+	SubscribeTopics(topics []string, rebalanceCb kafka.RebalanceCb) error
+	Poll(timeoutMs int) kafka.Event
+}
+
+// DecoderInterface decodes consumed messages into records.
+type DecoderInterface interface {
+	Decode(msg *kafka.Message) Record
+}
+
+// EncoderInterface writes records, as *json.Encoder does.
+type EncoderInterface interface {
+	Encode(v interface{}) error
+}
+
+// Options select the messages to print.
+type Options struct {
+	Topics     []string
+	Assign     bool    // Assign the partitions without joining a group
+	Partitions []int32 // Partitions to assign, all when empty
+	Start      Start
+	Max        int // Records to print, unlimited when zero
+	Filter     *Filter
+	Exit       bool // Stop at the end of every partition, requires enable.partition.eof
+}
+
+// inspector prints the records of a run.
+type inspector struct {
+	consumer ConsumerInterface
+	opts     Options
+	decoder  DecoderInterface
+	encoder  EncoderInterface
+
+	assigned map[partitionKey]bool // assigned partitions, true once at their end
+	err      error                 // first failure of a rebalance
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// Run prints the records of the topics of opts matching its filter until ctx is
+// done, Max records are printed or, with Exit, every partition is consumed to its
+// end. Partitions are assigned directly with Assign, otherwise the consumer joins
+// its group and every assignment starts at opts.Start. Returns the number of
+// records printed.
+func Run(ctx context.Context, consumer ConsumerInterface, opts Options, decoder DecoderInterface, encoder EncoderInterface) (int, error) {
+	in := &inspector{consumer: consumer, opts: opts, decoder: decoder, encoder: encoder, assigned: make(map[partitionKey]bool)}
+
+	if opts.Assign {
+		partitions, err := Partitions(consumer, opts.Topics, opts.Partitions)
+		if err != nil {
+			return 0, err
+		}
+		positions, err := Positions(consumer, partitions, opts.Start)
+		if err != nil {
+			return 0, err
+		}
+		if err := consumer.Assign(positions); err != nil {
+			return 0, fmt.Errorf("failed to assign partitions: %w", err)
+		}
+		in.track(positions)
+	} else {
+		if len(opts.Partitions) > 0 {
+			return 0, fmt.Errorf("partitions can only be selected when assigning them")
+		}
+		if err := consumer.SubscribeTopics(opts.Topics, in.rebalance); err != nil {
+			return 0, fmt.Errorf("failed to subscribe to topics: %w", err)
+		}
+	}
+
+	printed := 0
+	for ctx.Err() == nil {
+		switch e := consumer.Poll(pollTimeoutMs).(type) {
+		case *kafka.Message:
+			in.reached(e.TopicPartition, false)
+			record := decoder.Decode(e)
+			match, err := opts.Filter.Match(record)
+			if err != nil {
+				return printed, err
+			}
+			if !match {
+				continue
+			}
+			if err := encoder.Encode(record); err != nil {
+				return printed, fmt.Errorf("failed to write record: %w", err)
+			}
+			printed++
+			if opts.Max > 0 && printed >= opts.Max {
+				return printed, nil
+			}
+		case kafka.PartitionEOF:
+			in.reached(kafka.TopicPartition(e), true)
+			if opts.Exit && in.done() {
+				return printed, nil
+			}
+		case kafka.AssignedPartitions, kafka.RevokedPartitions:
+			in.rebalance(nil, e)
+		case kafka.Error:
+			if e.IsFatal() {
+				return printed, fmt.Errorf("fatal consumer error: %w", e)
+			}
+			log.Printf("Consumer error: %v", e)
+		}
+		if in.err != nil {
+			return printed, in.err
+		}
+	}
+	return printed, nil
+}
+
+// rebalance starts assigned partitions at opts.Start. Partitions starting at their
+// stored offsets, and revoked partitions, are left to the client.
+func (in *inspector) rebalance(_ *kafka.Consumer, ev kafka.Event) error {
+	switch e := ev.(type) {
+	case kafka.AssignedPartitions:
+		log.Printf("Assigned partitions %v", e.Partitions)
+		in.track(e.Partitions)
+		if in.opts.Start == (Start{Offset: kafka.OffsetStored}) {
+			return nil
+		}
+		positions, err := Positions(in.consumer, e.Partitions, in.opts.Start)
+		if err == nil {
+			if in.consumer.GetRebalanceProtocol() == "COOPERATIVE" {
+				err = in.consumer.IncrementalAssign(positions)
+			} else {
+				err = in.consumer.Assign(positions)
+			}
+		}
+		if err != nil && in.err == nil {
+			in.err = fmt.Errorf("failed to assign partitions: %w", err)
+		}
+	case kafka.RevokedPartitions:
+		log.Printf("Revoked partitions %v", e.Partitions)
+		for _, tp := range e.Partitions {
+			delete(in.assigned, partitionKey{topic: *tp.Topic, partition: tp.Partition})
+		}
+	}
+	return nil
+}
+
+// track adds assigned partitions, not at their end yet.
+func (in *inspector) track(partitions []kafka.TopicPartition) {
+	for _, tp := range partitions {
+		in.assigned[partitionKey{topic: *tp.Topic, partition: tp.Partition}] = false
+	}
+}
+
+// reached records whether a partition is at its end.
+func (in *inspector) reached(tp kafka.TopicPartition, end bool) {
+	key := partitionKey{topic: *tp.Topic, partition: tp.Partition}
+	if _, ok := in.assigned[key]; ok {
+		in.assigned[key] = end
+	}
+}
+
+// done reports whether every assigned partition is at its end.
+func (in *inspector) done() bool {
+	if len(in.assigned) == 0 {
+		return false
+	}
+	for _, end := range in.assigned {
+		if !end {
+			return false
+		}
+	}
+	return true
+}
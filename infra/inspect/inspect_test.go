@@ -0,0 +1,170 @@
+package inspect
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConsumer returns its events from Poll, then nothing.
+type fakeConsumer struct {
+	fakeClient
+	events     []kafka.Event
+	assigned   []kafka.TopicPartition
+	subscribed []string
+	protocol   string
+}
+
+func (f *fakeConsumer) Assign(partitions []kafka.TopicPartition) error {
+	f.assigned = partitions
+	return nil
+}
+
+func (f *fakeConsumer) IncrementalAssign(partitions []kafka.TopicPartition) error {
+	f.assigned = append(f.assigned, partitions...)
+	return nil
+}
+
+func (f *fakeConsumer) GetRebalanceProtocol() string {
+	return f.protocol
+}
+
+func (f *fakeConsumer) SubscribeTopics(topics []string, _ kafka.RebalanceCb) error {
+	f.subscribed = topics
+	return nil
+}
+
+func (f *fakeConsumer) Poll(_ int) kafka.Event {
+	if len(f.events) == 0 {
+		return nil
+	}
+	ev := f.events[0]
+	f.events = f.events[1:]
+	return ev
+}
+
+// fakeDecoder decodes values as strings.
+type fakeDecoder struct{}
+
+func (fakeDecoder) Decode(msg *kafka.Message) Record {
+	return Record{Topic: *msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition, Offset: int64(msg.TopicPartition.Offset), Value: string(msg.Value)}
+}
+
+// recorder keeps the encoded records.
+type recorder struct {
+	records []Record
+}
+
+func (r *recorder) Encode(v interface{}) error {
+	r.records = append(r.records, v.(Record))
+	return nil
+}
+
+var testTopic = "users"
+
+func message(partition int32, offset kafka.Offset, value string) *kafka.Message {
+	return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &testTopic, Partition: partition, Offset: offset}, Value: []byte(value)}
+}
+
+func eof(partition int32) kafka.PartitionEOF {
+	return kafka.PartitionEOF{Topic: &testTopic, Partition: partition}
+}
+
+func TestRun(t *testing.T) {
+	filter, err := ParseFilter("value!=skip")
+	assert.NoError(t, err)
+	testCases := []struct {
+		name            string
+		opts            Options
+		events          []kafka.Event
+		expectedOffsets []int64
+	}{
+		{
+			name:            "exit at the end of every partition",
+			opts:            Options{Topics: []string{testTopic}, Assign: true, Partitions: []int32{0, 1}, Start: Start{Offset: kafka.OffsetBeginning}, Exit: true},
+			events:          []kafka.Event{message(0, 1, "a"), eof(0), message(1, 2, "b"), eof(1), message(0, 3, "never")},
+			expectedOffsets: []int64{1, 2},
+		},
+		{
+			name:            "max records",
+			opts:            Options{Topics: []string{testTopic}, Assign: true, Start: Start{Offset: kafka.OffsetBeginning}, Max: 2},
+			events:          []kafka.Event{message(0, 1, "a"), message(1, 2, "b"), message(0, 3, "c")},
+			expectedOffsets: []int64{1, 2},
+		},
+		{
+			name:            "filtered records",
+			opts:            Options{Topics: []string{testTopic}, Assign: true, Start: Start{Offset: kafka.OffsetBeginning}, Filter: filter, Max: 1},
+			events:          []kafka.Event{message(0, 1, "skip"), message(0, 2, "b")},
+			expectedOffsets: []int64{2},
+		},
+		{
+			name: "group assignment",
+			opts: Options{Topics: []string{testTopic}, Start: Start{Offset: kafka.OffsetStored}, Exit: true},
+			events: []kafka.Event{
+				eof(0), // not assigned yet
+				kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{{Topic: &testTopic, Partition: 0}}},
+				message(0, 5, "a"), eof(0),
+			},
+			expectedOffsets: []int64{5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			fake := &fakeConsumer{events: tc.events}
+			out := &recorder{}
+
+			// Act
+			printed, err := Run(context.Background(), fake, tc.opts, fakeDecoder{}, out)
+
+			// Assert
+			assert.NoError(t, err)
+			var offsets []int64
+			for _, record := range out.records {
+				offsets = append(offsets, record.Offset)
+			}
+			assert.Equal(t, tc.expectedOffsets, offsets)
+			assert.Equal(t, len(tc.expectedOffsets), printed)
+		})
+	}
+}
+
+func TestRun_GroupAssignmentsStartAtStart(t *testing.T) {
+	testCases := []struct {
+		protocol string
+	}{
+		{protocol: "EAGER"},
+		{protocol: "COOPERATIVE"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.protocol, func(t *testing.T) {
+			// Arrange
+			fake := &fakeConsumer{protocol: tc.protocol, fakeClient: fakeClient{low: 0, high: 100}}
+			fake.events = []kafka.Event{
+				kafka.AssignedPartitions{Partitions: []kafka.TopicPartition{{Topic: &testTopic, Partition: 1}}},
+				message(1, 90, "a"),
+			}
+			opts := Options{Topics: []string{testTopic}, Start: Start{Last: 10}, Max: 1}
+
+			// Act
+			_, err := Run(context.Background(), fake, opts, fakeDecoder{}, &recorder{})
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, []string{testTopic}, fake.subscribed)
+			assert.Equal(t, []kafka.TopicPartition{{Topic: &testTopic, Partition: 1, Offset: 90}}, fake.assigned)
+		})
+	}
+}
+
+func TestRun_PartitionsRequireAssign(t *testing.T) {
+	// Act
+	_, err := Run(context.Background(), &fakeConsumer{}, Options{Topics: []string{testTopic}, Partitions: []int32{0}}, fakeDecoder{}, &recorder{})
+
+	// Assert
+	assert.EqualError(t, err, "partitions can only be selected when assigning them")
+}
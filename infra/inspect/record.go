@@ -0,0 +1,279 @@
+package inspect
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"kafka-go-example/infra/serde"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	hamba "github.com/hamba/avro/v2"
+)
+
+// Output formats of records. Avro-JSON writes Avro values in the Avro JSON
+// encoding: unions as {"type": value}, logical types as their underlying type
+// and bytes as strings of code points.
+const (
+	FormatJSON     = "json"
+	FormatPretty   = "pretty"
+	FormatAvroJSON = "avro-json"
+)
+
+// Record is a consumed message with its key and value decoded. Payloads not framed
+// by the schema registry are strings, or base64 when they are not valid UTF-8.
+type Record struct {
+	Topic       string            `json:"topic"`
+	Partition   int32             `json:"partition"`
+	Offset      int64             `json:"offset"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Key         interface{}       `json:"key"`
+	KeySchemaID int               `json:"key_schema_id,omitempty"`
+	Value       interface{}       `json:"value"`
+	SchemaID    int               `json:"schema_id,omitempty"`
+	Error       string            `json:"error,omitempty"` // Why the key or value could not be decoded
+}
+
+// NewEncoder returns the encoder writing records to out in format.
+func NewEncoder(out io.Writer, format string) (*json.Encoder, error) {
+	encoder := json.NewEncoder(out)
+	switch format {
+	case FormatJSON, FormatAvroJSON:
+	case FormatPretty:
+		encoder.SetIndent("", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported output format %q, expected %s, %s or %s", format, FormatJSON, FormatPretty, FormatAvroJSON)
+	}
+	return encoder, nil
+}
+
+// Decoder decodes messages into records with the writer schema of their payloads.
+type Decoder struct {
+	client       schemaregistry.Client
+	deserializer serde.DeserializerInterface
+	avroJSON     bool
+
+	mu      sync.Mutex
+	schemas map[int]hamba.Schema // parsed Avro schemas by id
+}
+
+// NewDecoder returns a decoder of registry framed payloads of any format, writing
+// Avro values in the Avro JSON encoding when avroJSON is set.
+func NewDecoder(client schemaregistry.Client, avroJSON bool) (*Decoder, error) {
+	deserializer, err := serde.NewSchemaDeserializer(client)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{client: client, deserializer: deserializer, avroJSON: avroJSON, schemas: make(map[int]hamba.Schema)}, nil
+}
+
+// Decode returns the record of msg. Payloads that cannot be decoded are left out
+// and the reason is set in Error.
+func (d *Decoder) Decode(msg *kafka.Message) Record {
+	topic := *msg.TopicPartition.Topic
+	record := Record{
+		Topic:     topic,
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Timestamp: msg.Timestamp,
+	}
+	if len(msg.Headers) > 0 {
+		record.Headers = make(map[string]string, len(msg.Headers))
+		for _, header := range msg.Headers {
+			record.Headers[header.Key] = string(header.Value)
+		}
+	}
+
+	var errs []string
+	var err error
+	if record.Key, record.KeySchemaID, err = d.decode(topic, msg.Key); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to decode key: %v", err))
+	}
+	if record.Value, record.SchemaID, err = d.decode(topic, msg.Value); err != nil {
+		errs = append(errs, fmt.Sprintf("failed to decode value: %v", err))
+	}
+	record.Error = strings.Join(errs, "; ")
+	return record
+}
+
+// decode returns a payload decoded with its writer schema, and the schema id.
+func (d *Decoder) decode(topic string, payload []byte) (interface{}, int, error) {
+	if len(payload) == 0 {
+		return nil, 0, nil
+	}
+	if len(payload) < 5 || payload[0] != 0 {
+		if utf8.Valid(payload) {
+			return string(payload), 0, nil
+		}
+		return payload, 0, nil
+	}
+
+	id := int(binary.BigEndian.Uint32(payload[1:5]))
+	info, err := d.client.GetBySubjectAndID("", id)
+	if err != nil {
+		return nil, id, fmt.Errorf("failed to get schema %d: %w", id, err)
+	}
+	if info.SchemaType != "" && info.SchemaType != "AVRO" {
+		var value interface{}
+		if err := d.deserializer.DeserializeInto(topic, payload, &value); err != nil {
+			return nil, id, err
+		}
+		return value, id, nil
+	}
+
+	schema, err := d.avroSchema(id, info)
+	if err != nil {
+		return nil, id, err
+	}
+	var value interface{}
+	if err := hamba.Unmarshal(schema, payload[5:], &value); err != nil {
+		return nil, id, err
+	}
+	return d.convert(schema, value), id, nil
+}
+
+// avroSchema returns the parsed Avro schema of id. For the Avro JSON encoding
+// logical types are dropped, so values are decoded as their underlying type.
+func (d *Decoder) avroSchema(id int, info schemaregistry.SchemaInfo) (hamba.Schema, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if schema, ok := d.schemas[id]; ok {
+		return schema, nil
+	}
+
+	text := info.Schema
+	if d.avroJSON {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %d: %w", id, err)
+		}
+		stripped, err := json.Marshal(withoutLogicalTypes(parsed))
+		if err != nil {
+			return nil, err
+		}
+		text = string(stripped)
+	}
+	// a cache per schema, as schema versions share their names
+	schema, err := hamba.ParseWithCache(text, "", &hamba.SchemaCache{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %d: %w", id, err)
+	}
+	d.schemas[id] = schema
+	return schema, nil
+}
+
+// convert unwraps the unions of a decoded Avro value, or keeps them in the Avro
+// JSON encoding, where bytes are also written as strings of code points.
+func (d *Decoder) convert(schema hamba.Schema, value interface{}) interface{} {
+	switch s := schema.(type) {
+	case *hamba.RefSchema:
+		return d.convert(s.Schema(), value)
+	case *hamba.RecordSchema:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		converted := make(map[string]interface{}, len(fields))
+		for _, field := range s.Fields() {
+			converted[field.Name()] = d.convert(field.Type(), fields[field.Name()])
+		}
+		return converted
+	case *hamba.UnionSchema:
+		branch, ok := value.(map[string]interface{})
+		if !ok || len(branch) != 1 {
+			return value
+		}
+		for name, inner := range branch {
+			for _, t := range s.Types() {
+				if unionName(t) != name {
+					continue
+				}
+				if d.avroJSON {
+					return map[string]interface{}{name: d.convert(t, inner)}
+				}
+				return d.convert(t, inner)
+			}
+		}
+		return value
+	case *hamba.ArraySchema:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		converted := make([]interface{}, len(items))
+		for i, item := range items {
+			converted[i] = d.convert(s.Items(), item)
+		}
+		return converted
+	case *hamba.MapSchema:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		converted := make(map[string]interface{}, len(values))
+		for key, item := range values {
+			converted[key] = d.convert(s.Values(), item)
+		}
+		return converted
+	}
+
+	// bytes and fixed
+	v := reflect.ValueOf(value)
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8 {
+		data := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(data), v)
+		if !d.avroJSON {
+			return data
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return value
+}
+
+// unionName returns the name hamba gives a union branch when decoding into interface{}.
+func unionName(schema hamba.Schema) string {
+	if ref, ok := schema.(*hamba.RefSchema); ok {
+		schema = ref.Schema()
+	}
+	if named, ok := schema.(hamba.NamedSchema); ok {
+		return named.FullName()
+	}
+	name := string(schema.Type())
+	if logical, ok := schema.(hamba.LogicalTypeSchema); ok && logical.Logical() != nil {
+		name += "." + string(logical.Logical().Type())
+	}
+	return name
+}
+
+// withoutLogicalTypes returns a parsed schema without its logicalType attributes.
+func withoutLogicalTypes(schema interface{}) interface{} {
+	switch s := schema.(type) {
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(s))
+		for key, value := range s {
+			if key != "logicalType" {
+				converted[key] = withoutLogicalTypes(value)
+			}
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(s))
+		for i, value := range s {
+			converted[i] = withoutLogicalTypes(value)
+		}
+		return converted
+	}
+	return schema
+}
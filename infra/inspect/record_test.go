@@ -0,0 +1,181 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/fakeregistry"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	hamba "github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const eventSchema = `{
+  "type": "record",
+  "name": "Event",
+  "namespace": "kafka.example",
+  "fields": [
+    {"name": "id", "type": "long"},
+    {"name": "note", "type": ["null", "string"]},
+    {"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "data", "type": "bytes"}
+  ]
+}`
+
+var eventAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestRegistry returns a client of a fake registry with the event schema
+// registered, and its id.
+func newTestRegistry(t *testing.T) (schemaregistry.Client, int) {
+	registry := fakeregistry.NewRegistry()
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+	id, _, err := registry.Register("events-value", schemaregistry.SchemaInfo{Schema: eventSchema})
+	assert.NoError(t, err)
+	client, err := avro.NewSchemaRegistryClient(config.SchemaRegistryConfig{SchemaRegistryUrl: server.URL})
+	assert.NoError(t, err)
+	return client, id
+}
+
+// eventPayload returns the registry framed Avro encoding of an event.
+func eventPayload(t *testing.T, id int) []byte {
+	schema, err := hamba.ParseWithCache(eventSchema, "", &hamba.SchemaCache{})
+	assert.NoError(t, err)
+	note := "hello"
+	data, err := hamba.Marshal(schema, map[string]interface{}{"id": int64(7), "note": &note, "at": eventAt, "data": []byte{0x01, 0xff}})
+	assert.NoError(t, err)
+	header := []byte{0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+	return append(header, data...)
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	testCases := []struct {
+		name          string
+		avroJSON      bool
+		expectedValue map[string]interface{}
+	}{
+		{
+			name:          "json",
+			expectedValue: map[string]interface{}{"id": int64(7), "note": "hello", "at": eventAt, "data": []byte{0x01, 0xff}},
+		},
+		{
+			name:          "avro-json",
+			avroJSON:      true,
+			expectedValue: map[string]interface{}{"id": int64(7), "note": map[string]interface{}{"string": "hello"}, "at": eventAt.UnixMilli(), "data": "\u0001ÿ"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			client, id := newTestRegistry(t)
+			decoder, err := NewDecoder(client, tc.avroJSON)
+			assert.NoError(t, err)
+			topic := "events"
+			msg := &kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 40},
+				Key:            []byte("7"),
+				Value:          eventPayload(t, id),
+				Headers:        []kafka.Header{{Key: "source", Value: []byte("users")}},
+				Timestamp:      eventAt,
+			}
+
+			// Act
+			record := decoder.Decode(msg)
+
+			// Assert
+			assert.Empty(t, record.Error)
+			assert.Equal(t, "events", record.Topic)
+			assert.Equal(t, int32(2), record.Partition)
+			assert.Equal(t, int64(40), record.Offset)
+			assert.Equal(t, map[string]string{"source": "users"}, record.Headers)
+			assert.Equal(t, "7", record.Key)
+			assert.Zero(t, record.KeySchemaID)
+			assert.Equal(t, id, record.SchemaID)
+			assert.Equal(t, tc.expectedValue, record.Value)
+		})
+	}
+}
+
+func TestDecoder_KeyAndValueErrors(t *testing.T) {
+	// Arrange
+	client, _ := newTestRegistry(t)
+	decoder, err := NewDecoder(client, false)
+	assert.NoError(t, err)
+	topic := "events"
+	unknownSchema := []byte{0, 0, 0, 0x03, 0xe7, 0x02}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Key:            unknownSchema,
+		Value:          unknownSchema,
+	}
+
+	// Act
+	record := decoder.Decode(msg)
+
+	// Assert
+	assert.Contains(t, record.Error, "failed to decode key: failed to get schema 999")
+	assert.Contains(t, record.Error, "; failed to decode value: failed to get schema 999")
+}
+
+func TestDecoder_UnframedPayloads(t *testing.T) {
+	// Arrange
+	client, _ := newTestRegistry(t)
+	decoder, err := NewDecoder(client, false)
+	assert.NoError(t, err)
+	topic := "events"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Key: []byte{0xff, 0xfe}, Value: []byte{0, 0, 0, 0, 99, 1}}
+
+	// Act
+	record := decoder.Decode(msg)
+
+	// Assert
+	assert.Equal(t, []byte{0xff, 0xfe}, record.Key)
+	assert.Nil(t, record.Value)
+	assert.Equal(t, 99, record.SchemaID)
+	assert.Contains(t, record.Error, "failed to decode value: failed to get schema 99")
+}
+
+func TestNewEncoder(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{format: FormatJSON, expected: "{\"offset\":1}\n"},
+		{format: FormatAvroJSON, expected: "{\"offset\":1}\n"},
+		{format: FormatPretty, expected: "{\n  \"offset\": 1\n}\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			// Arrange
+			var out bytes.Buffer
+			encoder, err := NewEncoder(&out, tc.format)
+			assert.NoError(t, err)
+
+			// Act
+			err = encoder.Encode(map[string]int{"offset": 1})
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestNewEncoder_UnsupportedFormat(t *testing.T) {
+	// Act
+	encoder, err := NewEncoder(&bytes.Buffer{}, "yaml")
+
+	// Assert
+	assert.EqualError(t, err, `unsupported output format "yaml", expected json, pretty or avro-json`)
+	assert.Nil(t, encoder)
+}
@@ -0,0 +1,155 @@
+package inspect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// requestTimeoutMs bounds the metadata and offset requests resolving start positions.
+const requestTimeoutMs = 10000
+
+// Start is where each partition is consumed from.
+type Start struct {
+	Offset    kafka.Offset // kafka.OffsetStored, OffsetBeginning, OffsetEnd or an absolute offset
+	Last      int64        // Messages before the end of each partition, when positive
+	Timestamp time.Time    // First message at or after Timestamp, when set
+}
+
+// ParseStart parses a start offset and timestamp, at most one of them set. offset
+// is "stored", "beginning", "end", an absolute offset or -N for the last N
+// messages; timestamp is RFC 3339 or Unix milliseconds. Empty values start at
+// the stored offsets of the group.
+func ParseStart(offset, timestamp string) (Start, error) {
+	if offset != "" && timestamp != "" {
+		return Start{}, fmt.Errorf("start at an offset or a timestamp, not both")
+	}
+	if timestamp != "" {
+		if millis, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+			return Start{Timestamp: time.UnixMilli(millis)}, nil
+		}
+		t, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return Start{}, fmt.Errorf("invalid timestamp %q, expected RFC 3339 or Unix milliseconds", timestamp)
+		}
+		return Start{Timestamp: t}, nil
+	}
+
+	switch offset {
+	case "", "stored":
+		return Start{Offset: kafka.OffsetStored}, nil
+	case "beginning":
+		return Start{Offset: kafka.OffsetBeginning}, nil
+	case "end":
+		return Start{Offset: kafka.OffsetEnd}, nil
+	}
+	n, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil {
+		return Start{}, fmt.Errorf("invalid offset %q, expected stored, beginning, end, an offset or -N for the last N messages", offset)
+	}
+	if n < 0 {
+		return Start{Last: -n}, nil
+	}
+	return Start{Offset: kafka.Offset(n)}, nil
+}
+
+// ClientInterface is the part of *kafka.Consumer resolving partitions and start offsets.
+type ClientInterface interface {
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	OffsetsForTimes(times []kafka.TopicPartition, timeoutMs int) ([]kafka.TopicPartition, error)
+}
+
+// Partitions returns the partitions of topics, only the selected ones unless
+// selected is empty.
+func Partitions(client ClientInterface, topics []string, selected []int32) ([]kafka.TopicPartition, error) {
+	var partitions []kafka.TopicPartition
+	for _, topic := range topics {
+		metadata, err := client.GetMetadata(&topic, false, requestTimeoutMs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata of %s: %w", topic, err)
+		}
+		meta, ok := metadata.Topics[topic]
+		if !ok || meta.Error.Code() == kafka.ErrUnknownTopicOrPart || len(meta.Partitions) == 0 {
+			return nil, fmt.Errorf("topic %s does not exist", topic)
+		}
+		existing := make(map[int32]bool, len(meta.Partitions))
+		for _, p := range meta.Partitions {
+			existing[p.ID] = true
+		}
+
+		ids := selected
+		if len(ids) == 0 {
+			for _, p := range meta.Partitions {
+				ids = append(ids, p.ID)
+			}
+		}
+		for _, id := range ids {
+			if !existing[id] {
+				return nil, fmt.Errorf("topic %s has no partition %d", topic, id)
+			}
+			partitions = append(partitions, kafka.TopicPartition{Topic: &topic, Partition: id, Offset: kafka.OffsetStored})
+		}
+	}
+	return partitions, nil
+}
+
+// Positions returns partitions with their offset set to start.
+func Positions(client ClientInterface, partitions []kafka.TopicPartition, start Start) ([]kafka.TopicPartition, error) {
+	positions := make([]kafka.TopicPartition, len(partitions))
+	copy(positions, partitions)
+
+	switch {
+	case !start.Timestamp.IsZero():
+		for i := range positions {
+			positions[i].Offset = kafka.Offset(start.Timestamp.UnixMilli())
+		}
+		offsets, err := client.OffsetsForTimes(positions, requestTimeoutMs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get offsets at %s: %w", start.Timestamp.Format(time.RFC3339), err)
+		}
+		for i, tp := range offsets {
+			if tp.Error != nil {
+				return nil, fmt.Errorf("failed to get offset of %s [%d] at %s: %w", *tp.Topic, tp.Partition, start.Timestamp.Format(time.RFC3339), tp.Error)
+			}
+			positions[i] = tp
+			if tp.Offset < 0 {
+				// no message at or after the timestamp
+				positions[i].Offset = kafka.OffsetEnd
+			}
+		}
+	case start.Last > 0:
+		for i, tp := range positions {
+			low, high, err := client.QueryWatermarkOffsets(*tp.Topic, tp.Partition, requestTimeoutMs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get watermarks of %s [%d]: %w", *tp.Topic, tp.Partition, err)
+			}
+			positions[i].Offset = kafka.Offset(max(low, high-start.Last))
+		}
+	default:
+		for i := range positions {
+			positions[i].Offset = start.Offset
+		}
+	}
+	return positions, nil
+}
+
+// ParsePartitions parses a comma separated list of partitions.
+func ParsePartitions(value string) ([]int32, error) {
+	var partitions []int32
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 32)
+		if err != nil || id < 0 {
+			return nil, fmt.Errorf("invalid partition %q", part)
+		}
+		partitions = append(partitions, int32(id))
+	}
+	return partitions, nil
+}
@@ -0,0 +1,183 @@
+package inspect
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClient serves the metadata and offsets of topics with three partitions.
+type fakeClient struct {
+	low, high int64
+	times     map[int32]kafka.Offset // offset at the requested time per partition
+	err       error
+}
+
+func (f *fakeClient) GetMetadata(topic *string, _ bool, _ int) (*kafka.Metadata, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if *topic == "missing" {
+		return &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{*topic: {Topic: *topic, Error: kafka.NewError(kafka.ErrUnknownTopicOrPart, "unknown", false)}}}, nil
+	}
+	partitions := []kafka.PartitionMetadata{{ID: 0}, {ID: 1}, {ID: 2}}
+	return &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{*topic: {Topic: *topic, Partitions: partitions}}}, nil
+}
+
+func (f *fakeClient) QueryWatermarkOffsets(_ string, _ int32, _ int) (int64, int64, error) {
+	return f.low, f.high, f.err
+}
+
+func (f *fakeClient) OffsetsForTimes(times []kafka.TopicPartition, _ int) ([]kafka.TopicPartition, error) {
+	offsets := make([]kafka.TopicPartition, len(times))
+	for i, tp := range times {
+		offsets[i] = tp
+		offsets[i].Offset = f.times[tp.Partition]
+	}
+	return offsets, f.err
+}
+
+func TestParseStart(t *testing.T) {
+	testCases := []struct {
+		offset    string
+		timestamp string
+		expected  Start
+	}{
+		{expected: Start{Offset: kafka.OffsetStored}},
+		{offset: "stored", expected: Start{Offset: kafka.OffsetStored}},
+		{offset: "beginning", expected: Start{Offset: kafka.OffsetBeginning}},
+		{offset: "end", expected: Start{Offset: kafka.OffsetEnd}},
+		{offset: "42", expected: Start{Offset: 42}},
+		{offset: "-10", expected: Start{Last: 10}},
+		{timestamp: "2024-05-01T12:00:00Z", expected: Start{Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}},
+		{timestamp: "1714564800000", expected: Start{Timestamp: time.UnixMilli(1714564800000)}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.offset+tc.timestamp, func(t *testing.T) {
+			// Act
+			start, err := ParseStart(tc.offset, tc.timestamp)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, start)
+		})
+	}
+}
+
+func TestParseStart_Invalid(t *testing.T) {
+	testCases := []struct {
+		offset      string
+		timestamp   string
+		expectedErr string
+	}{
+		{offset: "latest", expectedErr: `invalid offset "latest", expected stored, beginning, end, an offset or -N for the last N messages`},
+		{timestamp: "yesterday", expectedErr: `invalid timestamp "yesterday", expected RFC 3339 or Unix milliseconds`},
+		{offset: "end", timestamp: "1714564800000", expectedErr: "start at an offset or a timestamp, not both"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.offset+tc.timestamp, func(t *testing.T) {
+			// Act
+			_, err := ParseStart(tc.offset, tc.timestamp)
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		topics      []string
+		selected    []int32
+		expected    []int32
+		expectedErr string
+	}{
+		{name: "all partitions", topics: []string{"users"}, expected: []int32{0, 1, 2}},
+		{name: "selected partitions", topics: []string{"users"}, selected: []int32{2}, expected: []int32{2}},
+		{name: "unknown partition", topics: []string{"users"}, selected: []int32{3}, expectedErr: "topic users has no partition 3"},
+		{name: "unknown topic", topics: []string{"missing"}, expectedErr: "topic missing does not exist"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			partitions, err := Partitions(&fakeClient{}, tc.topics, tc.selected)
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			var ids []int32
+			for _, tp := range partitions {
+				assert.Equal(t, "users", *tp.Topic)
+				ids = append(ids, tp.Partition)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestPositions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		client   *fakeClient
+		start    Start
+		expected []kafka.Offset
+	}{
+		{name: "offset", client: &fakeClient{}, start: Start{Offset: kafka.OffsetBeginning}, expected: []kafka.Offset{kafka.OffsetBeginning, kafka.OffsetBeginning}},
+		{name: "last messages", client: &fakeClient{low: 95, high: 100}, start: Start{Last: 10}, expected: []kafka.Offset{95, 95}},
+		{name: "last messages of a long partition", client: &fakeClient{low: 0, high: 100}, start: Start{Last: 10}, expected: []kafka.Offset{90, 90}},
+		{
+			name:     "timestamp",
+			client:   &fakeClient{times: map[int32]kafka.Offset{0: 12, 1: -1}},
+			start:    Start{Timestamp: time.UnixMilli(1714564800000)},
+			expected: []kafka.Offset{12, kafka.OffsetEnd},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			topic := "users"
+			partitions := []kafka.TopicPartition{{Topic: &topic, Partition: 0}, {Topic: &topic, Partition: 1}}
+
+			// Act
+			positions, err := Positions(tc.client, partitions, tc.start)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, []kafka.Offset{positions[0].Offset, positions[1].Offset})
+		})
+	}
+}
+
+func TestPositions_Failure(t *testing.T) {
+	// Arrange
+	topic := "users"
+	partitions := []kafka.TopicPartition{{Topic: &topic, Partition: 0}}
+
+	// Act
+	_, err := Positions(&fakeClient{err: errors.New("timed out")}, partitions, Start{Last: 10})
+
+	// Assert
+	assert.EqualError(t, err, "failed to get watermarks of users [0]: timed out")
+}
+
+func TestParsePartitions(t *testing.T) {
+	// Act
+	partitions, err := ParsePartitions("0, 2,")
+	_, invalidErr := ParsePartitions("0,-1")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{0, 2}, partitions)
+	assert.EqualError(t, invalidErr, `invalid partition "-1"`)
+}
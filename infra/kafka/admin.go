@@ -0,0 +1,225 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// metadataTimeoutMs bounds the cluster metadata request made by EnsureTopics.
+const metadataTimeoutMs = 10000
+
+// AdminInterface is the subset of the Kafka admin client used to provision topics.
+type AdminInterface interface {
+	CreateTopics(ctx context.Context, topics []kafka.TopicSpecification, options ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error)
+	DescribeConfigs(ctx context.Context, resources []kafka.ConfigResource, options ...kafka.DescribeConfigsAdminOption) ([]kafka.ConfigResourceResult, error)
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+	Close()
+}
+
+// TopicDrift is a setting of an existing topic that differs from its declaration.
+type TopicDrift struct {
+	Topic    string
+	Setting  string
+	Declared string
+	Actual   string
+}
+
+func (d TopicDrift) String() string {
+	return fmt.Sprintf("topic %s: %s is %s, declared %s", d.Topic, d.Setting, d.Actual, d.Declared)
+}
+
+// TopicReport is the outcome of EnsureTopics.
+type TopicReport struct {
+	Created  []string     // Topics created
+	Existing []string     // Topics created by another client meanwhile, settings not compared
+	Missing  []string     // Topics missing and not created
+	Drift    []TopicDrift // Existing topics whose settings differ, never altered
+}
+
+// NewKafkaAdmin returns an admin client connecting like the producers, see ConnectionConfigMap.
+func NewKafkaAdmin(cfg config.KafkaConfig) (*kafka.AdminClient, error) {
+	configMap, err := ConnectionConfigMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewAdminClient(&configMap)
+}
+
+// TopicSpecs returns the topics declared by tasks publishing to Kafka: the task
+// topic and its static route topics. Topics declared twice must match.
+func TopicSpecs(tasks []config.TaskConfig) ([]kafka.TopicSpecification, error) {
+	specs := make(map[string]kafka.TopicSpecification)
+	for _, task := range tasks {
+		// Only Kafka sinks publish to topics
+		if task.TopicSpec.Partitions <= 0 || task.DryRun || (task.Sink.Type != "" && task.Sink.Type != config.SinkTypeKafka) {
+			continue
+		}
+
+		topics := []string{task.Topic}
+		for _, route := range task.Routes {
+			if !strings.Contains(route.Topic, "{") {
+				topics = append(topics, route.Topic)
+			}
+		}
+
+		for _, topic := range topics {
+			spec := kafka.TopicSpecification{
+				Topic:             topic,
+				NumPartitions:     task.TopicSpec.Partitions,
+				ReplicationFactor: task.TopicSpec.ReplicationFactor,
+				Config:            task.TopicSpec.Configs,
+			}
+			if existing, ok := specs[topic]; ok && !reflect.DeepEqual(existing, spec) {
+				return nil, fmt.Errorf("topic %s is declared with different settings by task %s", topic, task.Name)
+			}
+			specs[topic] = spec
+		}
+	}
+
+	result := make([]kafka.TopicSpecification, 0, len(specs))
+	for _, spec := range specs {
+		result = append(result, spec)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Topic < result[j].Topic })
+	return result, nil
+}
+
+// ProvisionTopics ensures the topics declared by tasks with a new admin client.
+// No client is created when no topic is declared.
+func ProvisionTopics(ctx context.Context, cfg config.KafkaConfig, tasks []config.TaskConfig, create bool) (TopicReport, error) {
+	specs, err := TopicSpecs(tasks)
+	if err != nil || len(specs) == 0 {
+		return TopicReport{}, err
+	}
+
+	admin, err := NewKafkaAdmin(cfg)
+	if err != nil {
+		return TopicReport{}, fmt.Errorf("failed to create admin client: %w", err)
+	}
+	defer admin.Close()
+
+	return EnsureTopics(ctx, admin, specs, create)
+}
+
+// EnsureTopics compares the declared topics with the cluster. Missing topics are
+// created when create is set; settings of existing topics are reported as drift.
+func EnsureTopics(ctx context.Context, admin AdminInterface, specs []kafka.TopicSpecification, create bool) (TopicReport, error) {
+	var report TopicReport
+	if len(specs) == 0 {
+		return report, nil
+	}
+
+	metadata, err := admin.GetMetadata(nil, true, metadataTimeoutMs)
+	if err != nil {
+		return report, fmt.Errorf("failed to get cluster metadata: %w", err)
+	}
+
+	var missing []kafka.TopicSpecification
+	var describe []kafka.ConfigResource
+	declared := make(map[string]kafka.TopicSpecification)
+	for _, spec := range specs {
+		topic, ok := metadata.Topics[spec.Topic]
+		if !ok || topic.Error.Code() == kafka.ErrUnknownTopicOrPart {
+			missing = append(missing, spec)
+			continue
+		}
+
+		report.Drift = append(report.Drift, partitionDrift(spec, topic)...)
+		if len(spec.Config) > 0 {
+			declared[spec.Topic] = spec
+			describe = append(describe, kafka.ConfigResource{Type: kafka.ResourceTopic, Name: spec.Topic})
+		}
+	}
+
+	if len(describe) > 0 {
+		results, err := admin.DescribeConfigs(ctx, describe)
+		if err != nil {
+			return report, fmt.Errorf("failed to describe topic configs: %w", err)
+		}
+		for _, result := range results {
+			if result.Error.Code() != kafka.ErrNoError {
+				return report, fmt.Errorf("failed to describe topic %s: %w", result.Name, result.Error)
+			}
+			report.Drift = append(report.Drift, configDrift(declared[result.Name], result)...)
+		}
+	}
+
+	if !create {
+		for _, spec := range missing {
+			report.Missing = append(report.Missing, spec.Topic)
+		}
+		return report, nil
+	}
+
+	if len(missing) > 0 {
+		results, err := admin.CreateTopics(ctx, missing)
+		if err != nil {
+			return report, fmt.Errorf("failed to create topics: %w", err)
+		}
+		for _, result := range results {
+			switch result.Error.Code() {
+			case kafka.ErrNoError:
+				report.Created = append(report.Created, result.Topic)
+			case kafka.ErrTopicAlreadyExists:
+				report.Existing = append(report.Existing, result.Topic)
+			default:
+				return report, fmt.Errorf("failed to create topic %s: %w", result.Topic, result.Error)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// partitionDrift compares the partition count and, when declared, the replication factor.
+func partitionDrift(spec kafka.TopicSpecification, topic kafka.TopicMetadata) []TopicDrift {
+	var drift []TopicDrift
+	if len(topic.Partitions) != spec.NumPartitions {
+		drift = append(drift, TopicDrift{
+			Topic:    spec.Topic,
+			Setting:  "partitions",
+			Declared: strconv.Itoa(spec.NumPartitions),
+			Actual:   strconv.Itoa(len(topic.Partitions)),
+		})
+	}
+	if spec.ReplicationFactor > 0 && len(topic.Partitions) > 0 && len(topic.Partitions[0].Replicas) != spec.ReplicationFactor {
+		drift = append(drift, TopicDrift{
+			Topic:    spec.Topic,
+			Setting:  "replication_factor",
+			Declared: strconv.Itoa(spec.ReplicationFactor),
+			Actual:   strconv.Itoa(len(topic.Partitions[0].Replicas)),
+		})
+	}
+	return drift
+}
+
+// configDrift compares the declared topic configs with the described ones.
+func configDrift(spec kafka.TopicSpecification, result kafka.ConfigResourceResult) []TopicDrift {
+	names := make([]string, 0, len(spec.Config))
+	for name := range spec.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var drift []TopicDrift
+	for _, name := range names {
+		actual := result.Config[name].Value
+		if actual != spec.Config[name] {
+			drift = append(drift, TopicDrift{
+				Topic:    spec.Topic,
+				Setting:  name,
+				Declared: spec.Config[name],
+				Actual:   actual,
+			})
+		}
+	}
+	return drift
+}
@@ -0,0 +1,175 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAdmin serves metadata and topic configs from memory and records created topics.
+type fakeAdmin struct {
+	topics  map[string]kafka.TopicMetadata
+	configs map[string]map[string]string
+	created []kafka.TopicSpecification
+	results map[string]kafka.Error
+}
+
+func (f *fakeAdmin) CreateTopics(ctx context.Context, topics []kafka.TopicSpecification, options ...kafka.CreateTopicsAdminOption) ([]kafka.TopicResult, error) {
+	f.created = append(f.created, topics...)
+	results := make([]kafka.TopicResult, 0, len(topics))
+	for _, topic := range topics {
+		results = append(results, kafka.TopicResult{Topic: topic.Topic, Error: f.results[topic.Topic]})
+	}
+	return results, nil
+}
+
+func (f *fakeAdmin) DescribeConfigs(ctx context.Context, resources []kafka.ConfigResource, options ...kafka.DescribeConfigsAdminOption) ([]kafka.ConfigResourceResult, error) {
+	results := make([]kafka.ConfigResourceResult, 0, len(resources))
+	for _, resource := range resources {
+		entries := make(map[string]kafka.ConfigEntryResult)
+		for name, value := range f.configs[resource.Name] {
+			entries[name] = kafka.ConfigEntryResult{Name: name, Value: value}
+		}
+		results = append(results, kafka.ConfigResourceResult{Type: resource.Type, Name: resource.Name, Config: entries})
+	}
+	return results, nil
+}
+
+func (f *fakeAdmin) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error) {
+	return &kafka.Metadata{Topics: f.topics}, nil
+}
+
+func (f *fakeAdmin) Close() {}
+
+func topicMetadata(name string, partitions, replicas int) kafka.TopicMetadata {
+	metadata := kafka.TopicMetadata{Topic: name}
+	for i := 0; i < partitions; i++ {
+		metadata.Partitions = append(metadata.Partitions, kafka.PartitionMetadata{ID: int32(i), Replicas: make([]int32, replicas)})
+	}
+	return metadata
+}
+
+func TestTopicSpecs(t *testing.T) {
+	// Arrange
+	spec := config.TopicSpec{Partitions: 3, ReplicationFactor: 1, Configs: map[string]string{"cleanup.policy": "compact"}}
+	tasks := []config.TaskConfig{
+		{Name: "users", Topic: "user-topic", TopicSpec: spec, Routes: []config.RouteConfig{
+			{Field: "status", Value: "active", Topic: "user-active"},
+			{Field: "country.code", Topic: "user-{country.code}"},
+		}},
+		{Name: "undeclared", Topic: "other-topic"},
+		{Name: "webhook", Topic: "hook-topic", TopicSpec: spec, Sink: config.SinkConfig{Type: "webhook"}},
+		{Name: "dry-run", Topic: "dry-topic", TopicSpec: spec, DryRun: true},
+	}
+
+	// Act
+	specs, err := TopicSpecs(tasks)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []kafka.TopicSpecification{
+		{Topic: "user-active", NumPartitions: 3, ReplicationFactor: 1, Config: spec.Configs},
+		{Topic: "user-topic", NumPartitions: 3, ReplicationFactor: 1, Config: spec.Configs},
+	}, specs)
+}
+
+func TestTopicSpecs_Conflict(t *testing.T) {
+	// Arrange
+	tasks := []config.TaskConfig{
+		{Name: "a", Topic: "user-topic", TopicSpec: config.TopicSpec{Partitions: 3}},
+		{Name: "b", Topic: "user-topic", TopicSpec: config.TopicSpec{Partitions: 6}},
+	}
+
+	// Act
+	_, err := TopicSpecs(tasks)
+
+	// Assert
+	assert.EqualError(t, err, "topic user-topic is declared with different settings by task b")
+}
+
+func TestEnsureTopics_CreatesMissingAndReportsDrift(t *testing.T) {
+	// Arrange
+	admin := &fakeAdmin{
+		topics: map[string]kafka.TopicMetadata{
+			"user-topic": topicMetadata("user-topic", 1, 1),
+		},
+		configs: map[string]map[string]string{
+			"user-topic": {"cleanup.policy": "delete", "retention.ms": "604800000"},
+		},
+	}
+	configs := map[string]string{"cleanup.policy": "compact", "retention.ms": "604800000"}
+	specs := []kafka.TopicSpecification{
+		{Topic: "user-active", NumPartitions: 3, ReplicationFactor: 1, Config: configs},
+		{Topic: "user-topic", NumPartitions: 3, ReplicationFactor: 1, Config: configs},
+	}
+
+	// Act
+	report, err := EnsureTopics(context.Background(), admin, specs, true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-active"}, report.Created)
+	assert.Empty(t, report.Missing)
+	assert.Equal(t, []TopicDrift{
+		{Topic: "user-topic", Setting: "partitions", Declared: "3", Actual: "1"},
+		{Topic: "user-topic", Setting: "cleanup.policy", Declared: "compact", Actual: "delete"},
+	}, report.Drift)
+	assert.Equal(t, []kafka.TopicSpecification{specs[0]}, admin.created)
+}
+
+func TestEnsureTopics_CheckOnly(t *testing.T) {
+	// Arrange
+	admin := &fakeAdmin{topics: map[string]kafka.TopicMetadata{}}
+	specs := []kafka.TopicSpecification{{Topic: "user-topic", NumPartitions: 3}}
+
+	// Act
+	report, err := EnsureTopics(context.Background(), admin, specs, false)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-topic"}, report.Missing)
+	assert.Empty(t, admin.created)
+}
+
+func TestEnsureTopics_CreatedMeanwhile(t *testing.T) {
+	// Arrange
+	admin := &fakeAdmin{
+		topics: map[string]kafka.TopicMetadata{},
+		results: map[string]kafka.Error{
+			"user-topic": kafka.NewError(kafka.ErrTopicAlreadyExists, "topic already exists", false),
+		},
+	}
+	specs := []kafka.TopicSpecification{
+		{Topic: "user-active", NumPartitions: 3},
+		{Topic: "user-topic", NumPartitions: 3},
+	}
+
+	// Act
+	report, err := EnsureTopics(context.Background(), admin, specs, true)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-active"}, report.Created)
+	assert.Equal(t, []string{"user-topic"}, report.Existing)
+}
+
+func TestEnsureTopics_CreateError(t *testing.T) {
+	// Arrange
+	admin := &fakeAdmin{
+		topics: map[string]kafka.TopicMetadata{},
+		results: map[string]kafka.Error{
+			"user-topic": kafka.NewError(kafka.ErrInvalidReplicationFactor, "replication factor larger than available brokers", false),
+		},
+	}
+	specs := []kafka.TopicSpecification{{Topic: "user-topic", NumPartitions: 3, ReplicationFactor: 3}}
+
+	// Act
+	_, err := EnsureTopics(context.Background(), admin, specs, true)
+
+	// Assert
+	assert.ErrorContains(t, err, "failed to create topic user-topic")
+}
@@ -1,13 +1,148 @@
 package kafka
 
 import (
+	"fmt"
+	"strings"
+
 	"kafka-go-example/infra/config"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
-func NewKafkaProducer(cfg config.KafkaConfig) (*kafka.Producer, error) {
-	return kafka.NewProducer(&kafka.ConfigMap{
-		"bootstrap.servers": cfg.BootstrapServers,
-	})
+// defaultProducerProperties favour delivery guarantees over throughput.
+var defaultProducerProperties = map[string]string{
+	"enable.idempotence": "true",
+	"acks":               "all",
+}
+
+func NewKafkaProducer(cfg config.KafkaConfig, overrides map[string]string) (*kafka.Producer, error) {
+	configMap, err := ProducerConfigMap(cfg, overrides)
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewProducer(configMap)
+}
+
+// ValidateProducerConfig builds and closes a producer with the task overrides, so
+// properties librdkafka does not know fail at startup rather than when the task
+// first acquires its producer. No message is sent.
+func ValidateProducerConfig(cfg config.KafkaConfig, overrides map[string]string) error {
+	producer, err := NewKafkaProducer(cfg, overrides)
+	if err != nil {
+		return err
+	}
+	producer.Close()
+	return nil
+}
+
+// ProducerConfigMap merges the safe defaults, the global producer configuration
+// and the task overrides, in that order, and validates the result.
+func ProducerConfigMap(cfg config.KafkaConfig, overrides map[string]string) (*kafka.ConfigMap, error) {
+	global, err := cfg.ProducerProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	properties := make(map[string]string)
+	for _, layer := range []map[string]string{defaultProducerProperties, global, overrides} {
+		for k, v := range layer {
+			properties[k] = v
+		}
+	}
+	if err := validateProducerProperties(properties); err != nil {
+		return nil, err
+	}
+
+	configMap := kafka.ConfigMap{"bootstrap.servers": cfg.BootstrapServers}
+	for k, v := range properties {
+		configMap[k] = v
+	}
+	return &configMap, nil
+}
+
+// validateProducerProperties rejects combinations librdkafka would accept but
+// that break the delivery guarantees. Unknown properties are left to librdkafka,
+// which refuses them when the producer is created, see ValidateProducerConfig.
+func validateProducerProperties(properties map[string]string) error {
+	if isTrue(properties["enable.idempotence"]) {
+		for _, key := range []string{"acks", "request.required.acks"} {
+			if acks, ok := properties[key]; ok && acks != "all" && acks != "-1" {
+				return fmt.Errorf("idempotent producer requires %s=all, got %s", key, acks)
+			}
+		}
+	}
+	return nil
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "t", "1":
+		return true
+	}
+	return false
+}
+
+// defaultConsumerProperties start new consumer groups at the earliest offset. Offsets
+// are committed by the consumer once messages are handled, never automatically.
+var defaultConsumerProperties = kafka.ConfigMap{
+	"session.timeout.ms": 6000,
+	"enable.auto.commit": false,
+	"auto.offset.reset":  "earliest",
+}
+
+func NewKafkaConsumer(cfg config.KafkaConfig) (*kafka.Consumer, error) {
+	configMap, err := ConsumerConfigMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return kafka.NewConsumer(configMap)
+}
+
+// ConsumerConfigMap returns the consumer configuration of the consumer group. An
+// instance id makes the consumer a static member: restarting within the session
+// timeout keeps its partitions without a rebalance.
+func ConsumerConfigMap(cfg config.KafkaConfig) (*kafka.ConfigMap, error) {
+	configMap, err := ConnectionConfigMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	configMap["group.id"] = cfg.Group
+	for k, v := range defaultConsumerProperties {
+		configMap[k] = v
+	}
+	if cfg.AssignmentStrategy != "" {
+		configMap["partition.assignment.strategy"] = cfg.AssignmentStrategy
+	}
+	if cfg.InstanceID != "" {
+		configMap["group.instance.id"] = cfg.InstanceID
+	}
+	if cfg.SessionTimeout > 0 {
+		configMap["session.timeout.ms"] = int(cfg.SessionTimeout.Milliseconds())
+	}
+	return &configMap, nil
+}
+
+// connectionPrefixes select the producer properties every client of the cluster
+// needs: the security protocol and its SASL and SSL settings.
+var connectionPrefixes = []string{"security.protocol", "sasl.", "ssl."}
+
+// ConnectionConfigMap returns the bootstrap servers and the connection properties
+// of the global producer configuration, so admin and consumer clients reach the
+// cluster the way the producers do.
+func ConnectionConfigMap(cfg config.KafkaConfig) (kafka.ConfigMap, error) {
+	global, err := cfg.ProducerProperties()
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := kafka.ConfigMap{"bootstrap.servers": cfg.BootstrapServers}
+	for k, v := range global {
+		for _, prefix := range connectionPrefixes {
+			if strings.HasPrefix(k, prefix) {
+				configMap[k] = v
+				break
+			}
+		}
+	}
+	return configMap, nil
 }
@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProducerConfigMap_Defaults(t *testing.T) {
+	// Act
+	configMap, err := ProducerConfigMap(config.KafkaConfig{BootstrapServers: "broker:9092"}, nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &kafka.ConfigMap{
+		"bootstrap.servers":  "broker:9092",
+		"enable.idempotence": "true",
+		"acks":               "all",
+	}, configMap)
+}
+
+func TestProducerConfigMap_Precedence(t *testing.T) {
+	// Arrange
+	cfg := config.KafkaConfig{
+		BootstrapServers: "broker:9092",
+		ProducerConfig:   "linger.ms=5,compression.type=gzip",
+	}
+	overrides := map[string]string{"compression.type": "lz4", "enable.idempotence": "false", "acks": "1"}
+
+	// Act
+	configMap, err := ProducerConfigMap(cfg, overrides)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &kafka.ConfigMap{
+		"bootstrap.servers":  "broker:9092",
+		"enable.idempotence": "false",
+		"acks":               "1",
+		"linger.ms":          "5",
+		"compression.type":   "lz4",
+	}, configMap)
+}
+
+func TestProducerConfigMap_PassesLibrdkafkaProperties(t *testing.T) {
+	// Arrange
+	overrides := map[string]string{
+		"ssl.endpoint.identification.algorithm": "none",
+		"client.rack":                           "eu-west-1a",
+		"sasl.oauthbearer.method":               "oidc",
+	}
+
+	// Act
+	configMap, err := ProducerConfigMap(config.KafkaConfig{}, overrides)
+
+	// Assert
+	assert.NoError(t, err)
+	for k, v := range overrides {
+		assert.Equal(t, v, (*configMap)[k])
+	}
+}
+
+func TestNewKafkaProducer_UnknownProperty(t *testing.T) {
+	// Act
+	producer, err := NewKafkaProducer(config.KafkaConfig{BootstrapServers: "localhost:9092"}, map[string]string{"linger": "5"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, producer)
+	assert.Contains(t, err.Error(), "linger")
+}
+
+func TestValidateProducerConfig(t *testing.T) {
+	testCases := []struct {
+		name      string
+		overrides map[string]string
+		wantErr   string
+	}{
+		{name: "valid", overrides: map[string]string{"linger.ms": "5"}},
+		{name: "unknown property", overrides: map[string]string{"linger": "5"}, wantErr: "linger"},
+		{name: "acks without idempotence", overrides: map[string]string{"acks": "1"}, wantErr: "acks=all"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			err := ValidateProducerConfig(config.KafkaConfig{BootstrapServers: "localhost:9092"}, tc.overrides)
+
+			// Assert
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestProducerConfigMap_IdempotenceRequiresAcksAll(t *testing.T) {
+	// Act
+	_, err := ProducerConfigMap(config.KafkaConfig{}, map[string]string{"acks": "1"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "idempotent producer requires acks=all")
+}
+
+func TestProducerConfigMap_InvalidGlobalConfig(t *testing.T) {
+	// Act
+	_, err := ProducerConfigMap(config.KafkaConfig{ProducerConfig: "linger.ms"}, nil)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestConsumerConfigMap(t *testing.T) {
+	// Act
+	configMap, err := ConsumerConfigMap(config.KafkaConfig{BootstrapServers: "broker:9092", Group: "group"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, &kafka.ConfigMap{
+		"bootstrap.servers":  "broker:9092",
+		"group.id":           "group",
+		"session.timeout.ms": 6000,
+		"enable.auto.commit": false,
+		"auto.offset.reset":  "earliest",
+	}, configMap)
+}
+
+func TestConsumerConfigMap_GroupMembership(t *testing.T) {
+	// Act
+	configMap, err := ConsumerConfigMap(config.KafkaConfig{
+		BootstrapServers:   "broker:9092",
+		Group:              "group",
+		AssignmentStrategy: "cooperative-sticky",
+		InstanceID:         "consumer-1",
+		SessionTimeout:     45 * time.Second,
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "cooperative-sticky", (*configMap)["partition.assignment.strategy"])
+	assert.Equal(t, "consumer-1", (*configMap)["group.instance.id"])
+	assert.Equal(t, 45000, (*configMap)["session.timeout.ms"])
+}
+
+func TestConnectionConfigMap(t *testing.T) {
+	// Arrange
+	cfg := config.KafkaConfig{
+		BootstrapServers: "broker:9093",
+		ProducerConfig:   "security.protocol=SASL_SSL,sasl.mechanisms=PLAIN,sasl.username=app,ssl.ca.location=/etc/ca.pem,linger.ms=5",
+	}
+
+	// Act
+	configMap, err := ConnectionConfigMap(cfg)
+	consumerMap, consumerErr := ConsumerConfigMap(cfg)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, kafka.ConfigMap{
+		"bootstrap.servers": "broker:9093",
+		"security.protocol": "SASL_SSL",
+		"sasl.mechanisms":   "PLAIN",
+		"sasl.username":     "app",
+		"ssl.ca.location":   "/etc/ca.pem",
+	}, configMap)
+	assert.NoError(t, consumerErr)
+	assert.Equal(t, "SASL_SSL", (*consumerMap)["security.protocol"])
+	assert.NotContains(t, *consumerMap, "linger.ms")
+}
+
+func TestConnectionConfigMap_InvalidGlobalConfig(t *testing.T) {
+	// Act
+	_, err := ConnectionConfigMap(config.KafkaConfig{ProducerConfig: "security.protocol"})
+
+	// Assert
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// peekTimeout bounds the wait for the message at a committed offset.
+const peekTimeout = 5 * time.Second
+
+// LagAdminInterface is the subset of the Kafka admin client reading committed offsets.
+type LagAdminInterface interface {
+	ListConsumerGroupOffsets(ctx context.Context, groupsPartitions []kafka.ConsumerGroupTopicPartitions, options ...kafka.ListConsumerGroupOffsetsAdminOption) (kafka.ListConsumerGroupOffsetsResult, error)
+	GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error)
+}
+
+// LagConsumerInterface is the subset of the Kafka consumer reading watermarks and
+// the timestamps of the next messages of a group. It must not commit.
+type LagConsumerInterface interface {
+	QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (low, high int64, err error)
+	Assign(partitions []kafka.TopicPartition) error
+	Poll(timeoutMs int) kafka.Event
+}
+
+// PartitionLag is how far a consumer group is behind on a partition.
+type PartitionLag struct {
+	Group     string
+	Topic     string
+	Partition int32
+	Committed int64         // Committed offset, -1 when the group has none
+	High      int64         // High watermark, the offset of the next message produced
+	Lag       int64         // Messages between the committed offset and the high watermark
+	TimeLag   time.Duration // Age of the next message to consume, zero without lag
+}
+
+func (l PartitionLag) String() string {
+	return fmt.Sprintf("group %s on %s [%d]: committed %d, high %d, lag %d messages, %s", l.Group, l.Topic, l.Partition, l.Committed, l.High, l.Lag, l.TimeLag)
+}
+
+// ConsumerLag returns the lag of groups on every partition of topics, or on the
+// partitions a group committed to when topics is empty. Partitions without a
+// committed offset are behind by every retained message. The time lag is
+// measured at now from the timestamp of the message at the committed offset.
+func ConsumerLag(ctx context.Context, admin LagAdminInterface, consumer LagConsumerInterface, groups, topics []string, now time.Time) ([]PartitionLag, error) {
+	var partitions []kafka.TopicPartition
+	for _, topic := range topics {
+		metadata, err := admin.GetMetadata(&topic, false, metadataTimeoutMs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get metadata of %s: %w", topic, err)
+		}
+		meta, ok := metadata.Topics[topic]
+		if !ok || meta.Error.Code() == kafka.ErrUnknownTopicOrPart || len(meta.Partitions) == 0 {
+			return nil, fmt.Errorf("topic %s does not exist", topic)
+		}
+		for _, p := range meta.Partitions {
+			partitions = append(partitions, kafka.TopicPartition{Topic: &topic, Partition: p.ID})
+		}
+	}
+
+	var lags []PartitionLag
+	for _, group := range groups {
+		// the admin client lists the offsets of one group per request
+		result, err := admin.ListConsumerGroupOffsets(ctx, []kafka.ConsumerGroupTopicPartitions{{Group: group, Partitions: partitions}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list offsets of group %s: %w", group, err)
+		}
+		for _, offsets := range result.ConsumerGroupsTopicPartitions {
+			for _, tp := range offsets.Partitions {
+				if tp.Error != nil {
+					return nil, fmt.Errorf("failed to list offset of group %s on %s [%d]: %w", group, *tp.Topic, tp.Partition, tp.Error)
+				}
+				lag, err := partitionLag(consumer, group, tp, now)
+				if err != nil {
+					return nil, err
+				}
+				lags = append(lags, lag)
+			}
+		}
+	}
+
+	sort.Slice(lags, func(i, j int) bool {
+		if lags[i].Group != lags[j].Group {
+			return lags[i].Group < lags[j].Group
+		}
+		if lags[i].Topic != lags[j].Topic {
+			return lags[i].Topic < lags[j].Topic
+		}
+		return lags[i].Partition < lags[j].Partition
+	})
+	return lags, nil
+}
+
+// partitionLag returns the lag of group on the partition of its committed offset tp.
+func partitionLag(consumer LagConsumerInterface, group string, tp kafka.TopicPartition, now time.Time) (PartitionLag, error) {
+	lag := PartitionLag{Group: group, Topic: *tp.Topic, Partition: tp.Partition, Committed: -1}
+	low, high, err := consumer.QueryWatermarkOffsets(*tp.Topic, tp.Partition, metadataTimeoutMs)
+	if err != nil {
+		return lag, fmt.Errorf("failed to get watermarks of %s [%d]: %w", *tp.Topic, tp.Partition, err)
+	}
+	lag.High = high
+
+	next := low
+	if tp.Offset >= 0 {
+		lag.Committed = int64(tp.Offset)
+		next = max(low, lag.Committed) // committed messages may be deleted by retention
+	}
+	lag.Lag = max(0, high-next)
+	if lag.Lag == 0 {
+		return lag, nil
+	}
+
+	timestamp, err := messageTime(consumer, kafka.TopicPartition{Topic: tp.Topic, Partition: tp.Partition, Offset: kafka.Offset(next)})
+	if err != nil {
+		return lag, err
+	}
+	lag.TimeLag = max(0, now.Sub(timestamp))
+	return lag, nil
+}
+
+// messageTime returns the timestamp of the message at the offset of tp, or of the
+// next one when compaction removed it. Messages fetched before the assignment,
+// at earlier offsets, are skipped.
+func messageTime(consumer LagConsumerInterface, tp kafka.TopicPartition) (time.Time, error) {
+	if err := consumer.Assign([]kafka.TopicPartition{tp}); err != nil {
+		return time.Time{}, fmt.Errorf("failed to assign %s [%d]: %w", *tp.Topic, tp.Partition, err)
+	}
+	deadline := time.Now().Add(peekTimeout)
+	for time.Now().Before(deadline) {
+		switch e := consumer.Poll(100).(type) {
+		case *kafka.Message:
+			if *e.TopicPartition.Topic == *tp.Topic && e.TopicPartition.Partition == tp.Partition && e.TopicPartition.Offset >= tp.Offset {
+				return e.Timestamp, nil
+			}
+		case kafka.Error:
+			if e.IsFatal() {
+				return time.Time{}, fmt.Errorf("failed to read %s [%d]: %w", *tp.Topic, tp.Partition, e)
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("no message read from %s [%d] at offset %d within %s", *tp.Topic, tp.Partition, tp.Offset, peekTimeout)
+}
+
+// WriteLagMetrics writes lags as gauges in the Prometheus text format.
+func WriteLagMetrics(w io.Writer, lags []PartitionLag) error {
+	gauges := []struct {
+		name  string
+		help  string
+		value func(PartitionLag) float64
+	}{
+		{"kafka_consumer_group_committed_offset", "Committed offset of the group, -1 when none.", func(l PartitionLag) float64 { return float64(l.Committed) }},
+		{"kafka_consumer_group_high_watermark", "High watermark of the partition.", func(l PartitionLag) float64 { return float64(l.High) }},
+		{"kafka_consumer_group_lag", "Messages the group is behind the high watermark.", func(l PartitionLag) float64 { return float64(l.Lag) }},
+		{"kafka_consumer_group_lag_seconds", "Age of the next message the group consumes.", func(l PartitionLag) float64 { return l.TimeLag.Seconds() }},
+	}
+	for _, gauge := range gauges {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", gauge.name, gauge.help, gauge.name); err != nil {
+			return err
+		}
+		for _, lag := range lags {
+			if _, err := fmt.Fprintf(w, "%s{group=%q,topic=%q,partition=\"%d\"} %g\n", gauge.name, lag.Group, lag.Topic, lag.Partition, gauge.value(lag)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
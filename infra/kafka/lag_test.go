@@ -0,0 +1,164 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLagAdmin serves topics with two partitions and the committed offsets of groups.
+type fakeLagAdmin struct {
+	committed map[string][]kafka.TopicPartition // committed offsets by group
+	requested []kafka.ConsumerGroupTopicPartitions
+}
+
+func (f *fakeLagAdmin) ListConsumerGroupOffsets(ctx context.Context, groupsPartitions []kafka.ConsumerGroupTopicPartitions, options ...kafka.ListConsumerGroupOffsetsAdminOption) (kafka.ListConsumerGroupOffsetsResult, error) {
+	f.requested = append(f.requested, groupsPartitions...)
+	group := groupsPartitions[0].Group
+	return kafka.ListConsumerGroupOffsetsResult{ConsumerGroupsTopicPartitions: []kafka.ConsumerGroupTopicPartitions{{Group: group, Partitions: f.committed[group]}}}, nil
+}
+
+func (f *fakeLagAdmin) GetMetadata(topic *string, allTopics bool, timeoutMs int) (*kafka.Metadata, error) {
+	return &kafka.Metadata{Topics: map[string]kafka.TopicMetadata{*topic: topicMetadata(*topic, 2, 1)}}, nil
+}
+
+// fakeLagConsumer serves watermarks and the message at the assigned offset,
+// timestamped a minute before the end of the partition per message.
+type fakeLagConsumer struct {
+	low, high int64
+	end       time.Time
+	assigned  []kafka.TopicPartition
+	err       error
+}
+
+func (f *fakeLagConsumer) QueryWatermarkOffsets(topic string, partition int32, timeoutMs int) (int64, int64, error) {
+	return f.low, f.high, f.err
+}
+
+func (f *fakeLagConsumer) Assign(partitions []kafka.TopicPartition) error {
+	f.assigned = append(f.assigned, partitions...)
+	return nil
+}
+
+func (f *fakeLagConsumer) Poll(timeoutMs int) kafka.Event {
+	tp := f.assigned[len(f.assigned)-1]
+	return &kafka.Message{TopicPartition: tp, Timestamp: f.end.Add(-time.Duration(f.high-int64(tp.Offset)) * time.Minute)}
+}
+
+func TestConsumerLag(t *testing.T) {
+	// Arrange
+	topic := "user-topic"
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	admin := &fakeLagAdmin{committed: map[string][]kafka.TopicPartition{
+		"users": {
+			{Topic: &topic, Partition: 1, Offset: 100},
+			{Topic: &topic, Partition: 0, Offset: 97},
+		},
+		"new": {
+			{Topic: &topic, Partition: 0, Offset: kafka.OffsetInvalid},
+		},
+	}}
+	consumer := &fakeLagConsumer{low: 90, high: 100, end: now}
+
+	// Act
+	lags, err := ConsumerLag(context.Background(), admin, consumer, []string{"users", "new"}, []string{topic}, now)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []PartitionLag{
+		{Group: "new", Topic: topic, Partition: 0, Committed: -1, High: 100, Lag: 10, TimeLag: 10 * time.Minute},
+		{Group: "users", Topic: topic, Partition: 0, Committed: 97, High: 100, Lag: 3, TimeLag: 3 * time.Minute},
+		{Group: "users", Topic: topic, Partition: 1, Committed: 100, High: 100, Lag: 0},
+	}, lags)
+	assert.Len(t, admin.requested, 2)
+	assert.Len(t, admin.requested[0].Partitions, 2)
+	assert.Equal(t, []kafka.Offset{97, 90}, []kafka.Offset{consumer.assigned[0].Offset, consumer.assigned[1].Offset})
+}
+
+func TestConsumerLag_CommittedPartitionsOfGroup(t *testing.T) {
+	// Arrange
+	topic := "user-topic"
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	admin := &fakeLagAdmin{committed: map[string][]kafka.TopicPartition{"users": {{Topic: &topic, Partition: 1, Offset: 100}}}}
+
+	// Act
+	lags, err := ConsumerLag(context.Background(), admin, &fakeLagConsumer{high: 100}, []string{"users"}, nil, now)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []PartitionLag{{Group: "users", Topic: topic, Partition: 1, Committed: 100, High: 100}}, lags)
+	assert.Nil(t, admin.requested[0].Partitions)
+}
+
+func TestConsumerLag_WatermarkFailure(t *testing.T) {
+	// Arrange
+	topic := "user-topic"
+	admin := &fakeLagAdmin{committed: map[string][]kafka.TopicPartition{"users": {{Topic: &topic, Partition: 0, Offset: 1}}}}
+
+	// Act
+	lags, err := ConsumerLag(context.Background(), admin, &fakeLagConsumer{err: errors.New("timed out")}, []string{"users"}, nil, time.Now())
+
+	// Assert
+	assert.EqualError(t, err, "failed to get watermarks of user-topic [0]: timed out")
+	assert.Nil(t, lags)
+}
+
+// queuedLagConsumer polls its events in order.
+type queuedLagConsumer struct {
+	fakeLagConsumer
+	events []kafka.Event
+}
+
+func (q *queuedLagConsumer) Poll(timeoutMs int) kafka.Event {
+	if len(q.events) == 0 {
+		return nil
+	}
+	event := q.events[0]
+	q.events = q.events[1:]
+	return event
+}
+
+func TestMessageTime_SkipsEarlierMessages(t *testing.T) {
+	// Arrange
+	topic, other := "user-topic", "order-topic"
+	at := func(topic string, partition int32, offset kafka.Offset, minute int) *kafka.Message {
+		return &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset},
+			Timestamp:      time.Date(2024, 5, 1, 12, minute, 0, 0, time.UTC),
+		}
+	}
+	consumer := &queuedLagConsumer{events: []kafka.Event{
+		at(topic, 0, 3, 1), // fetched for an earlier assignment
+		at(other, 0, 7, 2),
+		at(topic, 1, 7, 3),
+		at(topic, 0, 8, 4), // compaction removed offset 7
+	}}
+
+	// Act
+	timestamp, err := messageTime(consumer, kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: 7})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 4, 0, 0, time.UTC), timestamp)
+}
+
+func TestWriteLagMetrics(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	lags := []PartitionLag{{Group: "users", Topic: "user-topic", Partition: 0, Committed: 97, High: 100, Lag: 3, TimeLag: 90 * time.Second}}
+
+	// Act
+	err := WriteLagMetrics(&out, lags)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "# TYPE kafka_consumer_group_lag gauge\n")
+	assert.Contains(t, out.String(), `kafka_consumer_group_committed_offset{group="users",topic="user-topic",partition="0"} 97`+"\n")
+	assert.Contains(t, out.String(), `kafka_consumer_group_lag{group="users",topic="user-topic",partition="0"} 3`+"\n")
+	assert.Contains(t, out.String(), `kafka_consumer_group_lag_seconds{group="users",topic="user-topic",partition="0"} 90`+"\n")
+}
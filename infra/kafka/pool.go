@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"kafka-go-example/infra/config"
+)
+
+// ProducerPool shares one Producer between all tasks with the same producer
+// configuration. Producers are closed once every task has released them.
+type ProducerPool struct {
+	mu          sync.Mutex
+	newProducer func(overrides map[string]string) (KafkaProducerInterface, error)
+	producers   map[string]*pooledProducer
+}
+
+type pooledProducer struct {
+	producer *Producer
+	refs     int
+}
+
+// SharedProducer is a task's handle on a pooled Producer. Closing it releases
+// the handle; the Producer itself closes with its last handle.
+type SharedProducer struct {
+	*Producer
+	release func()
+	once    sync.Once
+}
+
+// Close releases the handle.
+func (s *SharedProducer) Close() {
+	s.once.Do(s.release)
+}
+
+func NewProducerPool(cfg config.KafkaConfig) *ProducerPool {
+	return &ProducerPool{
+		newProducer: func(overrides map[string]string) (KafkaProducerInterface, error) {
+			return NewKafkaProducer(cfg, overrides)
+		},
+		producers: make(map[string]*pooledProducer),
+	}
+}
+
+// Acquire returns a handle on the producer for the given task overrides,
+// creating it on first use.
+func (p *ProducerPool) Acquire(overrides map[string]string) (*SharedProducer, error) {
+	key := poolKey(overrides)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.producers[key]
+	if !ok {
+		kp, err := p.newProducer(overrides)
+		if err != nil {
+			return nil, err
+		}
+		entry = &pooledProducer{producer: NewProducer(kp)}
+		p.producers[key] = entry
+	}
+	entry.refs++
+
+	return &SharedProducer{
+		Producer: entry.producer,
+		release:  func() { p.release(key) },
+	}, nil
+}
+
+func (p *ProducerPool) release(key string) {
+	p.mu.Lock()
+	entry, ok := p.producers[key]
+	if ok {
+		entry.refs--
+		if entry.refs > 0 {
+			ok = false
+		} else {
+			delete(p.producers, key)
+		}
+	}
+	p.mu.Unlock()
+
+	if ok {
+		entry.producer.Close()
+	}
+}
+
+// Drain waits for the outstanding messages of every pooled producer until ctx is done.
+func (p *ProducerPool) Drain(ctx context.Context) error {
+	p.mu.Lock()
+	producers := make([]*Producer, 0, len(p.producers))
+	for _, entry := range p.producers {
+		producers = append(producers, entry.producer)
+	}
+	p.mu.Unlock()
+
+	var errs []error
+	for _, producer := range producers {
+		if err := producer.Drain(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every producer still in the pool, regardless of open handles.
+func (p *ProducerPool) Close() {
+	p.mu.Lock()
+	producers := p.producers
+	p.producers = make(map[string]*pooledProducer)
+	p.mu.Unlock()
+
+	for _, entry := range producers {
+		entry.producer.Close()
+	}
+}
+
+// poolKey identifies a producer configuration independently of map ordering.
+func poolKey(overrides map[string]string) string {
+	pairs := make([]string, 0, len(overrides))
+	for k, v := range overrides {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPool(created *[]*MockProducer) *ProducerPool {
+	return &ProducerPool{
+		newProducer: func(overrides map[string]string) (KafkaProducerInterface, error) {
+			if overrides["fail"] != "" {
+				return nil, errors.New("create error")
+			}
+			mp := NewMockProducer()
+			*created = append(*created, mp)
+			return mp, nil
+		},
+		producers: make(map[string]*pooledProducer),
+	}
+}
+
+func TestProducerPool_SharesProducerPerConfiguration(t *testing.T) {
+	// Arrange
+	var created []*MockProducer
+	pool := newTestPool(&created)
+
+	// Act
+	a, err := pool.Acquire(nil)
+	assert.NoError(t, err)
+	b, err := pool.Acquire(map[string]string{})
+	assert.NoError(t, err)
+	c, err := pool.Acquire(map[string]string{"linger.ms": "5"})
+	assert.NoError(t, err)
+
+	// Assert
+	assert.Same(t, a.Producer, b.Producer)
+	assert.NotSame(t, a.Producer, c.Producer)
+	assert.Len(t, created, 2)
+}
+
+func TestProducerPool_ClosesWithLastHandle(t *testing.T) {
+	// Arrange
+	var created []*MockProducer
+	pool := newTestPool(&created)
+	a, _ := pool.Acquire(nil)
+	b, _ := pool.Acquire(nil)
+
+	// Act & Assert
+	a.Close()
+	a.Close() // releasing twice does not drop b's reference
+	assert.Equal(t, 0, created[0].closed)
+
+	b.Close()
+	assert.Equal(t, 1, created[0].closed)
+	assert.Empty(t, pool.producers)
+}
+
+func TestProducerPool_Close(t *testing.T) {
+	// Arrange
+	var created []*MockProducer
+	pool := newTestPool(&created)
+	pool.Acquire(nil)
+	pool.Acquire(map[string]string{"linger.ms": "5"})
+
+	// Act
+	pool.Close()
+
+	// Assert
+	for _, mp := range created {
+		assert.Equal(t, 1, mp.closed)
+	}
+}
+
+func TestProducerPool_AcquireError(t *testing.T) {
+	// Arrange
+	var created []*MockProducer
+	pool := newTestPool(&created)
+
+	// Act
+	producer, err := pool.Acquire(map[string]string{"fail": "true"})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, producer)
+	assert.Empty(t, pool.producers)
+}
+
+func TestProducerPool_Drain(t *testing.T) {
+	// Arrange
+	var created []*MockProducer
+	pool := newTestPool(&created)
+	a, _ := pool.Acquire(nil)
+	defer a.Close()
+	b, _ := pool.Acquire(map[string]string{"linger.ms": "5"})
+	defer b.Close()
+
+	// Act & Assert
+	assert.NoError(t, pool.Drain(context.Background()))
+
+	created[1].outstanding = 3
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := pool.Drain(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "3 messages not delivered")
+}
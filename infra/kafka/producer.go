@@ -1,48 +1,348 @@
 package kafka
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 )
 
+const (
+	// deliveryChanSize bounds the delivery reports waiting to be dispatched.
+	deliveryChanSize = 1000
+	// closeFlushTimeoutMs is how long Close waits for outstanding deliveries. It is
+	// kept short so Close fits in a shutdown hook: callers wait for deliveries with
+	// Drain, whose context bounds the wait, before closing.
+	closeFlushTimeoutMs = 500
+	// drainPollMs is how long each Flush call in Drain waits before checking the context.
+	drainPollMs = 100
+	// defaultProduceTimeout bounds ProduceMessage when the context has no deadline.
+	defaultProduceTimeout = 30 * time.Second
+	// queueFullBackoff and maxQueueFullBackoff bound the wait between retries of a full local queue.
+	queueFullBackoff    = 10 * time.Millisecond
+	maxQueueFullBackoff = time.Second
+)
+
+// ErrProducerClosed is returned for messages that were not delivered before the producer closed.
+var ErrProducerClosed = errors.New("producer closed")
+
+// DeliveryError is returned by ProduceMessage when a message was not delivered.
+// Retriable failures are transient (timeouts, full queues, unavailable brokers)
+// and the message may succeed if produced again; other failures are permanent.
+type DeliveryError struct {
+	Err       error
+	retriable bool
+}
+
+func (e *DeliveryError) Error() string {
+	kind := "fatal"
+	if e.retriable {
+		kind = "retriable"
+	}
+	return fmt.Sprintf("%s delivery failure: %v", kind, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Retriable reports whether producing the message again may succeed.
+func (e *DeliveryError) Retriable() bool {
+	return e.retriable
+}
+
+// IsRetriable reports whether err is a retriable delivery failure.
+func IsRetriable(err error) bool {
+	var deliveryErr *DeliveryError
+	return errors.As(err, &deliveryErr) && deliveryErr.Retriable()
+}
+
+// newDeliveryError classifies err as retriable or fatal.
+func newDeliveryError(err error) *DeliveryError {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return &DeliveryError{Err: err, retriable: true}
+	}
+
+	var kafkaErr kafka.Error
+	if errors.As(err, &kafkaErr) {
+		if kafkaErr.IsFatal() {
+			return &DeliveryError{Err: err}
+		}
+		switch kafkaErr.Code() {
+		case kafka.ErrQueueFull, kafka.ErrMsgTimedOut, kafka.ErrTimedOut, kafka.ErrTimedOutQueue,
+			kafka.ErrTransport, kafka.ErrAllBrokersDown, kafka.ErrNotEnoughReplicas,
+			kafka.ErrNotEnoughReplicasAfterAppend, kafka.ErrLeaderNotAvailable,
+			kafka.ErrNotLeaderForPartition, kafka.ErrRequestTimedOut:
+			return &DeliveryError{Err: err, retriable: true}
+		}
+		return &DeliveryError{Err: err, retriable: kafkaErr.IsRetriable() || kafkaErr.IsTimeout()}
+	}
+
+	return &DeliveryError{Err: err}
+}
+
 // KafkaProducerInterface abstracts the Kafka producer for testing purposes.
 type KafkaProducerInterface interface {
 	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+	Events() chan kafka.Event
+	Flush(timeoutMs int) int
 	Close()
 }
 
+// Producer is safe for concurrent use. Every message carries an opaque id so
+// its delivery report is dispatched to the caller that produced it.
 type Producer struct {
 	producer     KafkaProducerInterface // Using the interface instead of concrete type
 	deliveryChan chan kafka.Event
+	timeout      time.Duration // applied when the produce context has no deadline
+
+	mu      sync.Mutex
+	pending map[uint64]chan error
+	nextID  uint64
+	closed  bool
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
 }
 
 func NewProducer(kp KafkaProducerInterface) *Producer {
-	return &Producer{
+	p := &Producer{
 		producer:     kp,
-		deliveryChan: make(chan kafka.Event, 1),
+		deliveryChan: make(chan kafka.Event, deliveryChanSize),
+		timeout:      defaultProduceTimeout,
+		pending:      make(map[uint64]chan error),
+		done:         make(chan struct{}),
 	}
+
+	p.wg.Add(1)
+	go p.handleEvents()
+
+	return p
 }
 
-// ProduceMessage encapsulates publishing a message and handling delivery events.
-func (p *Producer) ProduceMessage(topic string, payload []byte, key []byte) error {
-	err := p.producer.Produce(&kafka.Message{
+// ProduceMessage publishes a message and waits for its delivery report until ctx
+// is done. A full local queue is retried with backoff. Failures are returned as
+// *DeliveryError. A message abandoned because ctx is done may still be delivered.
+func (p *Producer) ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error {
+	if _, ok := ctx.Deadline(); !ok && p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	id, result, err := p.register()
+	if err != nil {
+		return newDeliveryError(err)
+	}
+
+	msg := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 		Key:            key,
 		Value:          payload,
-	}, p.deliveryChan)
-	if err != nil {
-		return err
+		Headers:        toKafkaHeaders(headers),
+		Opaque:         id,
+	}
+	if err := p.produce(ctx, msg); err != nil {
+		p.unregister(id)
+		return newDeliveryError(err)
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return newDeliveryError(err)
+		}
+		return nil
+	case <-ctx.Done():
+		p.unregister(id)
+		return newDeliveryError(ctx.Err())
+	}
+}
+
+// produce enqueues msg, retrying with backoff while the local queue is full.
+func (p *Producer) produce(ctx context.Context, msg *kafka.Message) error {
+	backoff := queueFullBackoff
+	for {
+		err := p.producer.Produce(msg, p.deliveryChan)
+		var kafkaErr kafka.Error
+		if !errors.As(err, &kafkaErr) || kafkaErr.Code() != kafka.ErrQueueFull {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxQueueFullBackoff)
 	}
+}
 
-	e := <-p.deliveryChan
-	m := e.(*kafka.Message)
-	if m.TopicPartition.Error != nil {
-		return m.TopicPartition.Error
+// Drain waits until every outstanding message has been delivered or ctx is done.
+func (p *Producer) Drain(ctx context.Context) error {
+	for {
+		remaining := p.producer.Flush(drainPollMs)
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d messages not delivered: %w", remaining, ctx.Err())
+		default:
+		}
 	}
-	return nil
 }
 
-// Close cleans up the delivery channel.
+// Close briefly flushes outstanding messages, closes the Kafka producer and fails
+// any message still waiting for a delivery report. Call Drain first to wait for
+// every delivery.
 func (p *Producer) Close() {
-	close(p.deliveryChan)
-	p.producer.Close()
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		if remaining := p.producer.Flush(closeFlushTimeoutMs); remaining > 0 {
+			log.Printf("Closing producer with %d undelivered messages", remaining)
+		}
+		p.producer.Close()
+
+		close(p.done)
+		p.wg.Wait()
+		close(p.deliveryChan)
+
+		p.failPending(ErrProducerClosed)
+	})
+}
+
+// register allocates an opaque id and the channel its delivery result is sent to.
+func (p *Producer) register() (uint64, chan error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0, nil, ErrProducerClosed
+	}
+
+	p.nextID++
+	result := make(chan error, 1)
+	p.pending[p.nextID] = result
+	return p.nextID, result, nil
+}
+
+func (p *Producer) unregister(id uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+// resolve sends the delivery result for the message with the given opaque id.
+func (p *Producer) resolve(id uint64, err error) {
+	p.mu.Lock()
+	result, ok := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	if ok {
+		result <- err
+	}
+}
+
+func (p *Producer) failPending(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, result := range p.pending {
+		result <- err
+		delete(p.pending, id)
+	}
+}
+
+// handleEvents dispatches delivery reports and handles client level events
+// until the producer is closed.
+func (p *Producer) handleEvents() {
+	defer p.wg.Done()
+
+	events := p.producer.Events()
+	for {
+		select {
+		case <-p.done:
+			p.drainDeliveries()
+			return
+		case e := <-p.deliveryChan:
+			p.handleDelivery(e)
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			p.handleEvent(e)
+		}
+	}
+}
+
+// drainDeliveries dispatches the reports received before the producer closed.
+func (p *Producer) drainDeliveries() {
+	for {
+		select {
+		case e := <-p.deliveryChan:
+			p.handleDelivery(e)
+		default:
+			return
+		}
+	}
+}
+
+func (p *Producer) handleDelivery(e kafka.Event) {
+	m, ok := e.(*kafka.Message)
+	if !ok {
+		p.handleEvent(e)
+		return
+	}
+
+	id, ok := m.Opaque.(uint64)
+	if !ok {
+		log.Printf("Ignored delivery report without opaque id for topic: %v", m.TopicPartition)
+		return
+	}
+	p.resolve(id, m.TopicPartition.Error)
+}
+
+func (p *Producer) handleEvent(e kafka.Event) {
+	switch evt := e.(type) {
+	case kafka.Error:
+		log.Printf("Kafka producer error: %v", evt)
+		if evt.IsFatal() {
+			p.failPending(evt)
+		}
+	case *kafka.Message:
+		p.handleDelivery(evt)
+	default:
+		log.Printf("Ignored producer event: %v", e)
+	}
+}
+
+// toKafkaHeaders converts headers to Kafka headers sorted by key.
+func toKafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]kafka.Header, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, kafka.Header{Key: k, Value: []byte(headers[k])})
+	}
+	return result
 }
@@ -1,8 +1,12 @@
 package kafka
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/stretchr/testify/assert"
@@ -11,42 +15,87 @@ import (
 
 type MockProducer struct {
 	mock.Mock
+	events      chan kafka.Event
+	closed      int
+	outstanding int // messages Flush reports as not yet delivered
+}
+
+func NewMockProducer() *MockProducer {
+	return &MockProducer{events: make(chan kafka.Event, 10)}
 }
 
 func (m *MockProducer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
 	args := m.Called(msg, deliveryChan)
-	if deliveryChan != nil {
+	if args.Error(0) == nil && deliveryChan != nil {
 		deliveryChan <- &kafka.Message{
 			TopicPartition: kafka.TopicPartition{
 				Topic:     msg.TopicPartition.Topic,
 				Partition: kafka.PartitionAny,
 				Error:     args.Error(1),
 			},
+			Opaque: msg.Opaque,
 		}
 	}
 	return args.Error(0)
 }
 
+func (m *MockProducer) Events() chan kafka.Event {
+	return m.events
+}
+
+func (m *MockProducer) Flush(timeoutMs int) int {
+	return m.outstanding
+}
+
 func (m *MockProducer) Close() {
-	m.Called()
+	m.closed++
+	close(m.events)
+}
+
+// reorderingProducer holds delivery reports and sends them in reverse order,
+// as librdkafka may when messages go to different partitions.
+type reorderingProducer struct {
+	mu       sync.Mutex
+	held     []*kafka.Message
+	batch    int
+	events   chan kafka.Event
+	failures map[string]error
+}
+
+func (r *reorderingProducer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := *msg
+	report.TopicPartition.Error = r.failures[string(msg.Value)]
+	r.held = append(r.held, &report)
+	if len(r.held) == r.batch {
+		for i := len(r.held) - 1; i >= 0; i-- {
+			deliveryChan <- r.held[i]
+		}
+		r.held = nil
+	}
+	return nil
 }
 
+func (r *reorderingProducer) Events() chan kafka.Event { return r.events }
+func (r *reorderingProducer) Flush(timeoutMs int) int  { return 0 }
+func (r *reorderingProducer) Close()                   { close(r.events) }
+
 func TestProduceMessage_Success(t *testing.T) {
 	// Arrange
-	mockProducer := new(MockProducer)
-	topic := "test-topic"
-	producer := &Producer{
-		producer:     mockProducer,
-		deliveryChan: make(chan kafka.Event, 1),
-	}
+	mockProducer := NewMockProducer()
+	producer := NewProducer(mockProducer)
+	defer producer.Close()
 
+	topic := "test-topic"
 	payload := []byte("test-payload")
 	key := []byte("test-key")
 
 	mockProducer.On("Produce", mock.Anything, producer.deliveryChan).Return(nil, nil)
 
 	// Act
-	err := producer.ProduceMessage(topic, payload, key)
+	err := producer.ProduceMessage(context.Background(), topic, payload, key, nil)
 
 	// Assert
 	assert.NoError(t, err)
@@ -55,64 +104,253 @@ func TestProduceMessage_Success(t *testing.T) {
 
 func TestProduceMessage_ProduceError(t *testing.T) {
 	// Arrange
-	mockProducer := new(MockProducer)
-	topic := "test-topic"
-	producer := &Producer{
-		producer:     mockProducer,
-		deliveryChan: make(chan kafka.Event, 1),
-	}
-
-	payload := []byte("test-payload")
-	key := []byte("test-key")
+	mockProducer := NewMockProducer()
+	producer := NewProducer(mockProducer)
+	defer producer.Close()
 
 	mockProducer.On("Produce", mock.Anything, producer.deliveryChan).Return(errors.New("produce error"), nil)
 
 	// Act
-	err := producer.ProduceMessage(topic, payload, key)
+	err := producer.ProduceMessage(context.Background(), "test-topic", []byte("test-payload"), []byte("test-key"), nil)
 
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, "produce error", err.Error())
+	assert.Equal(t, "fatal delivery failure: produce error", err.Error())
+	assert.False(t, IsRetriable(err))
+	assert.Empty(t, producer.pending)
 	mockProducer.AssertExpectations(t)
 }
 
 func TestProduceMessage_DeliveryError(t *testing.T) {
 	// Arrange
-	mockProducer := new(MockProducer)
-	topic := "test-topic"
-	producer := &Producer{
-		producer:     mockProducer,
-		deliveryChan: make(chan kafka.Event, 1),
-	}
-
-	payload := []byte("test-payload")
-	key := []byte("test-key")
+	mockProducer := NewMockProducer()
+	producer := NewProducer(mockProducer)
+	defer producer.Close()
 
 	mockProducer.On("Produce", mock.Anything, producer.deliveryChan).Return(nil, errors.New("delivery error"))
 
 	// Act
-	err := producer.ProduceMessage(topic, payload, key)
+	err := producer.ProduceMessage(context.Background(), "test-topic", []byte("test-payload"), []byte("test-key"), nil)
 
 	// Assert
 	assert.Error(t, err)
-	assert.Equal(t, "delivery error", err.Error())
+	assert.Equal(t, "delivery error", errors.Unwrap(err).Error())
 	mockProducer.AssertExpectations(t)
 }
 
-func TestClose(t *testing.T) {
+func TestProduceMessage_Headers(t *testing.T) {
+	// Arrange
+	mockProducer := NewMockProducer()
+	producer := NewProducer(mockProducer)
+	defer producer.Close()
+
+	headers := map[string]string{"replay": "true", "source": "test"}
+	expected := []kafka.Header{
+		{Key: "replay", Value: []byte("true")},
+		{Key: "source", Value: []byte("test")},
+	}
+
+	mockProducer.On("Produce", mock.MatchedBy(func(msg *kafka.Message) bool {
+		return assert.ObjectsAreEqual(expected, msg.Headers)
+	}), producer.deliveryChan).Return(nil, nil)
+
+	// Act
+	err := producer.ProduceMessage(context.Background(), "test-topic", []byte("test-payload"), nil, headers)
+
+	// Assert
+	assert.NoError(t, err)
+	mockProducer.AssertExpectations(t)
+}
+
+func TestProduceMessage_ConcurrentDeliveryCorrelation(t *testing.T) {
 	// Arrange
-	mockProducer := new(MockProducer)
-	mockProducer.On("Close").Return()
+	const count = 10
+	kp := &reorderingProducer{
+		batch:    count,
+		events:   make(chan kafka.Event),
+		failures: map[string]error{"message-3": errors.New("delivery error")},
+	}
+	producer := NewProducer(kp)
+	defer producer.Close()
 
-	producer := &Producer{
-		producer:     mockProducer,
-		deliveryChan: make(chan kafka.Event, 1),
+	// Act
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = producer.ProduceMessage(context.Background(), "test-topic", []byte(fmt.Sprintf("message-%d", i)), nil, nil)
+		}(i)
 	}
+	wg.Wait()
+
+	// Assert
+	for i, err := range errs {
+		if i == 3 {
+			assert.EqualError(t, errors.Unwrap(err), "delivery error")
+		} else {
+			assert.NoError(t, err, "message-%d", i)
+		}
+	}
+}
+
+func TestProduceMessage_FatalErrorFailsPending(t *testing.T) {
+	// Arrange
+	kp := &reorderingProducer{batch: 2, events: make(chan kafka.Event)}
+	producer := NewProducer(kp)
+	defer producer.Close()
+
+	// Act
+	result := make(chan error)
+	go func() {
+		result <- producer.ProduceMessage(context.Background(), "test-topic", []byte("held"), nil, nil)
+	}()
+	assert.Eventually(t, func() bool {
+		producer.mu.Lock()
+		defer producer.mu.Unlock()
+		return len(producer.pending) == 1
+	}, time.Second, time.Millisecond)
+	kp.events <- kafka.NewError(kafka.ErrFatal, "fatal error", true)
+
+	// Assert
+	select {
+	case err := <-result:
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "fatal error")
+	case <-time.After(time.Second):
+		t.Fatal("pending message was not failed by the fatal error")
+	}
+}
+
+func TestProduceMessage_IgnoresUnexpectedEvents(t *testing.T) {
+	// Arrange
+	mockProducer := NewMockProducer()
+	producer := NewProducer(mockProducer)
+	defer producer.Close()
+
+	mockProducer.On("Produce", mock.Anything, producer.deliveryChan).Return(nil, nil)
+
+	// Act: neither event may panic the event loop
+	producer.deliveryChan <- kafka.NewError(kafka.ErrTransport, "transport error", false)
+	mockProducer.events <- kafka.OAuthBearerTokenRefresh{}
+	err := producer.ProduceMessage(context.Background(), "test-topic", []byte("test-payload"), nil, nil)
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestClose(t *testing.T) {
+	// Arrange
+	mockProducer := NewMockProducer()
+
+	producer := NewProducer(mockProducer)
 
 	// Act
 	producer.Close()
+	producer.Close() // closing twice is safe
 
 	// Assert
 	assert.Panics(t, func() { close(producer.deliveryChan) }) // Ensure the channel is closed.
-	mockProducer.AssertExpectations(t)
+	assert.ErrorIs(t, producer.ProduceMessage(context.Background(), "test-topic", nil, nil, nil), ErrProducerClosed)
+	assert.Equal(t, 1, mockProducer.closed)
+}
+
+// blockingProducer accepts messages but never reports their delivery,
+// after rejecting the first queueFull attempts with a full queue.
+type blockingProducer struct {
+	mu        sync.Mutex
+	attempts  int
+	queueFull int
+	events    chan kafka.Event
+}
+
+func (b *blockingProducer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.attempts++
+	if b.attempts <= b.queueFull {
+		return kafka.NewError(kafka.ErrQueueFull, "queue full", false)
+	}
+	return nil
+}
+
+func (b *blockingProducer) Events() chan kafka.Event { return b.events }
+func (b *blockingProducer) Flush(timeoutMs int) int  { return 0 }
+func (b *blockingProducer) Close()                   { close(b.events) }
+
+func TestProduceMessage_RetriesQueueFull(t *testing.T) {
+	// Arrange
+	kp := &blockingProducer{queueFull: 2, events: make(chan kafka.Event)}
+	producer := NewProducer(kp)
+	defer producer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := producer.ProduceMessage(ctx, "test-topic", []byte("test-payload"), nil, nil)
+
+	// Assert: enqueued on the third attempt, then timed out waiting for delivery
+	assert.Equal(t, 3, kp.attempts)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, IsRetriable(err))
+	assert.Empty(t, producer.pending)
+}
+
+func TestProduceMessage_QueueFullUntilCancelled(t *testing.T) {
+	// Arrange
+	kp := &blockingProducer{queueFull: 1000, events: make(chan kafka.Event)}
+	producer := NewProducer(kp)
+	defer producer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Act
+	err := producer.ProduceMessage(ctx, "test-topic", []byte("test-payload"), nil, nil)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, IsRetriable(err))
+	assert.Empty(t, producer.pending)
+}
+
+func TestProduceMessage_DefaultTimeout(t *testing.T) {
+	// Arrange
+	kp := &blockingProducer{events: make(chan kafka.Event)}
+	producer := NewProducer(kp)
+	producer.timeout = 20 * time.Millisecond
+	defer producer.Close()
+
+	// Act
+	err := producer.ProduceMessage(context.Background(), "test-topic", []byte("test-payload"), nil, nil)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewDeliveryError(t *testing.T) {
+	testCases := []struct {
+		name      string
+		err       error
+		retriable bool
+	}{
+		{name: "message timed out", err: kafka.NewError(kafka.ErrMsgTimedOut, "timed out", false), retriable: true},
+		{name: "all brokers down", err: kafka.NewError(kafka.ErrAllBrokersDown, "down", false), retriable: true},
+		{name: "message too large", err: kafka.NewError(kafka.ErrMsgSizeTooLarge, "too large", false), retriable: false},
+		{name: "fatal", err: kafka.NewError(kafka.ErrFatal, "fatal", true), retriable: false},
+		{name: "deadline", err: context.DeadlineExceeded, retriable: true},
+		{name: "closed", err: ErrProducerClosed, retriable: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newDeliveryError(tc.err)
+			assert.Equal(t, tc.retriable, err.Retriable())
+			assert.Equal(t, tc.retriable, IsRetriable(err))
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
 }
@@ -0,0 +1,136 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// abortGrace is how long Shutdown waits for workers to return once their work is aborted.
+const abortGrace = 5 * time.Second
+
+// ErrDrainTimeout is returned by Shutdown when running work did not finish in time.
+var ErrDrainTimeout = errors.New("timed out draining running work")
+
+// WorkerFunc runs until stop is done. Work already in progress may continue
+// after stop is done, until it completes or work is done.
+type WorkerFunc func(stop, work context.Context)
+
+// HookFunc releases a resource during shutdown. ctx expires with the hook timeout,
+// shared by all the hooks.
+type HookFunc func(ctx context.Context) error
+
+type hook struct {
+	name string
+	fn   HookFunc
+}
+
+// Manager runs workers and shuts them down in order: it stops the workers,
+// waits for their running work to complete, then runs the shutdown hooks in
+// the order they were registered.
+type Manager struct {
+	timeout     time.Duration
+	hookTimeout time.Duration
+
+	stopCtx context.Context
+	stop    context.CancelFunc
+	workCtx context.Context
+	abort   context.CancelFunc
+
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+	hooks []hook
+}
+
+// NewManager returns a manager allowing timeout for running work to drain, then
+// hookTimeout for the shutdown hooks, so hooks still run when draining timed out.
+func NewManager(timeout, hookTimeout time.Duration) *Manager {
+	m := &Manager{timeout: timeout, hookTimeout: hookTimeout}
+	m.stopCtx, m.stop = context.WithCancel(context.Background())
+	m.workCtx, m.abort = context.WithCancel(context.Background())
+	return m
+}
+
+// Go runs fn in a goroutine tracked by the manager.
+func (m *Manager) Go(fn WorkerFunc) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		fn(m.stopCtx, m.workCtx)
+	}()
+}
+
+// OnShutdown registers a hook to run once the workers have returned.
+func (m *Manager) OnShutdown(name string, fn HookFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook{name: name, fn: fn})
+}
+
+// WaitForSignal blocks until the process receives SIGINT or SIGTERM.
+func (m *Manager) WaitForSignal() {
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signalChan)
+
+	sig := <-signalChan
+	log.Printf("Received signal %v", sig)
+}
+
+// Shutdown stops the workers, waits for them to drain and runs the shutdown hooks.
+// It returns ErrDrainTimeout if the workers did not drain in time, joined with
+// any hook errors.
+func (m *Manager) Shutdown() error {
+	log.Println("Shutting down gracefully...")
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), m.timeout)
+	defer cancelDrain()
+
+	m.stop()
+
+	drained := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(drained)
+	}()
+
+	var errs []error
+	select {
+	case <-drained:
+		log.Println("Running work drained")
+	case <-drainCtx.Done():
+		log.Printf("Timed out after %s waiting for running work, aborting it", m.timeout)
+		errs = append(errs, ErrDrainTimeout)
+		m.abort()
+		select {
+		case <-drained:
+		case <-time.After(abortGrace):
+			log.Println("Workers did not return after abort")
+		}
+	}
+	m.abort()
+
+	m.mu.Lock()
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.hookTimeout)
+	defer cancel()
+
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			log.Printf("Shutdown step <%s> failed: %v", h.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+			continue
+		}
+		log.Printf("Shutdown step <%s> completed", h.name)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,90 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_DrainsWorkBeforeHooks(t *testing.T) {
+	// Arrange
+	manager := NewManager(time.Second, time.Second)
+	var steps []string
+
+	manager.Go(func(stop, work context.Context) {
+		<-stop.Done()
+		time.Sleep(20 * time.Millisecond) // finish the running work
+		assert.NoError(t, work.Err())
+		steps = append(steps, "worker")
+	})
+	manager.OnShutdown("flush", func(ctx context.Context) error {
+		steps = append(steps, "flush")
+		return nil
+	})
+	manager.OnShutdown("close", func(ctx context.Context) error {
+		steps = append(steps, "close")
+		return nil
+	})
+
+	// Act
+	err := manager.Shutdown()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker", "flush", "close"}, steps)
+}
+
+func TestManager_DrainTimeoutAbortsWork(t *testing.T) {
+	// Arrange
+	manager := NewManager(20*time.Millisecond, time.Second)
+	aborted := make(chan struct{})
+
+	manager.Go(func(stop, work context.Context) {
+		<-work.Done()
+		close(aborted)
+	})
+	hookRan := false
+	var hookCtxErr error
+	manager.OnShutdown("close", func(ctx context.Context) error {
+		hookRan = true
+		hookCtxErr = ctx.Err()
+		return nil
+	})
+
+	// Act
+	err := manager.Shutdown()
+
+	// Assert
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.True(t, hookRan)
+	assert.NoError(t, hookCtxErr) // hooks get their own timeout once draining timed out
+	select {
+	case <-aborted:
+	default:
+		t.Fatal("work context was not cancelled")
+	}
+}
+
+func TestManager_HookErrors(t *testing.T) {
+	// Arrange
+	manager := NewManager(time.Second, time.Second)
+	flushErr := errors.New("flush error")
+	manager.OnShutdown("flush", func(ctx context.Context) error { return flushErr })
+	closed := false
+	manager.OnShutdown("close", func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+
+	// Act
+	err := manager.Shutdown()
+
+	// Assert
+	assert.ErrorIs(t, err, flushErr)
+	assert.NotErrorIs(t, err, ErrDrainTimeout)
+	assert.Contains(t, err.Error(), "flush")
+	assert.True(t, closed) // later hooks still run
+}
@@ -0,0 +1,62 @@
+package serde
+
+import (
+	"reflect"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/jsonschema"
+)
+
+// JSONSchemaSerializer serializes models as JSON documents validated against
+// the latest JSON Schema registered for the subject. Fields are named by their avro tags.
+type JSONSchemaSerializer struct {
+	serializer *jsonschema.Serializer
+}
+
+// NewJSONSchemaSerializer returns a JSON Schema serializer. Schemas are not derived
+// from Go models, so the latest registered version of the subject is always used.
+func NewJSONSchemaSerializer(client schemaregistry.Client) (*JSONSchemaSerializer, error) {
+	srCfg := jsonschema.NewSerializerConfig()
+	srCfg.AutoRegisterSchemas = false
+	srCfg.UseLatestVersion = true
+	srCfg.EnableValidation = true
+	serializer, err := jsonschema.NewSerializer(client, confluent.ValueSerde, srCfg)
+	if err != nil {
+		return nil, err
+	}
+	serializer.SubjectNameStrategy = subjectStrategy
+	return &JSONSchemaSerializer{serializer: serializer}, nil
+}
+
+// Serialize serializes data with the latest schema of subject.
+func (s *JSONSchemaSerializer) Serialize(subject string, data interface{}) ([]byte, error) {
+	value, err := toValue(reflect.ValueOf(data))
+	if err != nil {
+		return nil, err
+	}
+	return s.serializer.Serialize(subject, value)
+}
+
+// JSONSchemaDeserializer decodes JSON Schema payloads into models or maps.
+type JSONSchemaDeserializer struct {
+	deserializer *jsonschema.Deserializer
+}
+
+func NewJSONSchemaDeserializer(client schemaregistry.Client) (*JSONSchemaDeserializer, error) {
+	deserializer, err := jsonschema.NewDeserializer(client, confluent.ValueSerde, jsonschema.NewDeserializerConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &JSONSchemaDeserializer{deserializer: deserializer}, nil
+}
+
+// DeserializeInto decodes payload into msg, a model, a *map[string]interface{}
+// or an *interface{}.
+func (d *JSONSchemaDeserializer) DeserializeInto(topic string, payload []byte, msg interface{}) error {
+	var value interface{}
+	if err := d.deserializer.DeserializeInto(topic, payload, &value); err != nil {
+		return err
+	}
+	return decodeValue(value, msg)
+}
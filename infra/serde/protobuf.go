@@ -0,0 +1,284 @@
+package serde
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/protobuf"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// protoFile is the name the registered schema is compiled under.
+const protoFile = "schema.proto"
+
+// protoSchemas compiles the latest Protobuf schema of each subject once.
+type protoSchemas struct {
+	client schemaregistry.Client
+	mu     sync.Mutex
+	files  map[string]protoreflect.FileDescriptor
+}
+
+func newProtoSchemas(client schemaregistry.Client) *protoSchemas {
+	return &protoSchemas{client: client, files: make(map[string]protoreflect.FileDescriptor)}
+}
+
+// latest returns the compiled latest schema of subject.
+func (p *protoSchemas) latest(subject string) (protoreflect.FileDescriptor, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if file, ok := p.files[subject]; ok {
+		return file, nil
+	}
+
+	metadata, err := p.client.GetLatestSchemaMetadata(subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema of subject %s: %w", subject, err)
+	}
+	file, err := compileProto(p.client, metadata.SchemaInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema of subject %s version %d: %w", subject, metadata.Version, err)
+	}
+	p.files[subject] = file
+	return file, nil
+}
+
+// message returns the message of the latest schema of subject that data is
+// serialized with: the message named by the subject under the record strategies,
+// else the top level message named after the model type, else the only top level message.
+func (p *protoSchemas) message(subject string, data interface{}) (protoreflect.MessageDescriptor, error) {
+	file, err := p.latest(subject)
+	if err != nil {
+		return nil, err
+	}
+	messages := file.Messages()
+	if messages.Len() == 0 {
+		return nil, fmt.Errorf("schema of subject %s declares no message", subject)
+	}
+
+	if desc := messageNamed(messages, subject); desc != nil {
+		return desc, nil
+	}
+	model := reflect.TypeOf(data)
+	for model != nil && model.Kind() == reflect.Pointer {
+		model = model.Elem()
+	}
+	if model != nil && model.Name() != "" {
+		for i := 0; i < messages.Len(); i++ {
+			if strings.EqualFold(string(messages.Get(i).Name()), model.Name()) {
+				return messages.Get(i), nil
+			}
+		}
+	}
+	if messages.Len() == 1 {
+		return messages.Get(0), nil
+	}
+	return nil, fmt.Errorf("schema of subject %s declares %d messages, none named after the subject or %v", subject, messages.Len(), model)
+}
+
+// messageNamed returns the message, possibly nested, whose full name is subject,
+// or ends subject after a dash as under topic_record_name, nil when there is none.
+func messageNamed(messages protoreflect.MessageDescriptors, subject string) protoreflect.MessageDescriptor {
+	for i := 0; i < messages.Len(); i++ {
+		desc := messages.Get(i)
+		name := string(desc.FullName())
+		if subject == name || strings.HasSuffix(subject, "-"+name) {
+			return desc
+		}
+		if nested := messageNamed(desc.Messages(), subject); nested != nil {
+			return nested
+		}
+	}
+	return nil
+}
+
+// compileProto compiles a registered Protobuf schema with its references.
+// The well-known google/protobuf imports are always available.
+func compileProto(client schemaregistry.Client, info schemaregistry.SchemaInfo) (protoreflect.FileDescriptor, error) {
+	deps := make(map[string]string)
+	if err := confluent.ResolveReferences(client, info, deps); err != nil {
+		return nil, err
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: func(path string) (io.ReadCloser, error) {
+				if path == protoFile {
+					return io.NopCloser(strings.NewReader(info.Schema)), nil
+				}
+				if schema, ok := deps[path]; ok {
+					return io.NopCloser(strings.NewReader(schema)), nil
+				}
+				return nil, os.ErrNotExist
+			},
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), protoFile)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// ProtobufSerializer serializes models with a message of the latest Protobuf
+// schema registered for the subject, see protoSchemas.message. Model fields are
+// matched to message fields by their avro names; a model field missing from the
+// message is an error.
+type ProtobufSerializer struct {
+	serializer *protobuf.Serializer
+	schemas    *protoSchemas
+}
+
+// NewProtobufSerializer returns a Protobuf serializer. Schemas cannot be derived
+// from Go models, so the latest registered version of the subject is always used.
+func NewProtobufSerializer(client schemaregistry.Client) (*ProtobufSerializer, error) {
+	srCfg := protobuf.NewSerializerConfig()
+	srCfg.AutoRegisterSchemas = false
+	srCfg.UseLatestVersion = true
+	serializer, err := protobuf.NewSerializer(client, confluent.ValueSerde, srCfg)
+	if err != nil {
+		return nil, err
+	}
+	serializer.SubjectNameStrategy = subjectStrategy
+	return &ProtobufSerializer{serializer: serializer, schemas: newProtoSchemas(client)}, nil
+}
+
+// Serialize serializes data with the latest schema of subject.
+func (s *ProtobufSerializer) Serialize(subject string, data interface{}) ([]byte, error) {
+	desc, err := s.schemas.message(subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := ToMap(data)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	msg := dynamicpb.NewMessage(desc)
+	if err := protojson.Unmarshal(encoded, msg); err != nil {
+		return nil, fmt.Errorf("failed to convert to %s: %w", desc.FullName(), err)
+	}
+	return s.serializer.Serialize(subject, msg)
+}
+
+// ProtobufDeserializer decodes Protobuf payloads into models or maps with the
+// schema they were written with, looked up by the schema id of the payload.
+type ProtobufDeserializer struct {
+	deserializer *protobuf.Deserializer
+	mu           sync.Mutex
+	files        map[string]protoreflect.FileDescriptor // compiled schemas by schema text
+}
+
+func NewProtobufDeserializer(client schemaregistry.Client) (*ProtobufDeserializer, error) {
+	deserializer, err := protobuf.NewDeserializer(client, confluent.ValueSerde, protobuf.NewDeserializerConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &ProtobufDeserializer{deserializer: deserializer, files: make(map[string]protoreflect.FileDescriptor)}, nil
+}
+
+// DeserializeInto decodes payload into msg, a model, a *map[string]interface{}
+// or an *interface{}.
+func (d *ProtobufDeserializer) DeserializeInto(topic string, payload []byte, msg interface{}) error {
+	if len(payload) < 6 {
+		return fmt.Errorf("payload of %d bytes is too short", len(payload))
+	}
+	info, err := d.deserializer.GetSchema(topic, payload)
+	if err != nil {
+		return err
+	}
+	file, err := d.file(info)
+	if err != nil {
+		return err
+	}
+	read, indexes, err := readMessageIndexes(payload[5:])
+	if err != nil {
+		return err
+	}
+	desc, err := messageAt(file.Messages(), indexes)
+	if err != nil {
+		return err
+	}
+
+	message := dynamicpb.NewMessage(desc)
+	if err := proto.Unmarshal(payload[5+read:], message); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", desc.FullName(), err)
+	}
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return decodeJSON(data, msg)
+}
+
+// file returns the compiled writer schema.
+func (d *ProtobufDeserializer) file(info schemaregistry.SchemaInfo) (protoreflect.FileDescriptor, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if file, ok := d.files[info.Schema]; ok {
+		return file, nil
+	}
+	file, err := compileProto(d.deserializer.Client, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile writer schema: %w", err)
+	}
+	d.files[info.Schema] = file
+	return file, nil
+}
+
+// readMessageIndexes reads the zigzag encoded path of the message type that
+// follows the schema id. An empty path is the first message of the schema.
+func readMessageIndexes(payload []byte) (int, []int, error) {
+	count, read := binary.Varint(payload)
+	if read <= 0 || count < 0 {
+		return 0, nil, errors.New("invalid message indexes")
+	}
+	if count == 0 {
+		return read, []int{0}, nil
+	}
+	// every index takes at least one byte
+	if count > int64(len(payload)-read) {
+		return 0, nil, fmt.Errorf("invalid message indexes: %d indexes in %d bytes", count, len(payload)-read)
+	}
+
+	indexes := make([]int, count)
+	for i := range indexes {
+		index, n := binary.Varint(payload[read:])
+		if n <= 0 {
+			return 0, nil, errors.New("invalid message indexes")
+		}
+		read += n
+		indexes[i] = int(index)
+	}
+	return read, indexes, nil
+}
+
+// messageAt returns the message at a path of indexes into messages and their nested messages.
+func messageAt(messages protoreflect.MessageDescriptors, indexes []int) (protoreflect.MessageDescriptor, error) {
+	index := indexes[0]
+	if index < 0 || index >= messages.Len() {
+		return nil, fmt.Errorf("message index %d out of range", index)
+	}
+	desc := messages.Get(index)
+	if len(indexes) == 1 {
+		return desc, nil
+	}
+	return messageAt(desc.Messages(), indexes[1:])
+}
@@ -0,0 +1,60 @@
+package serde
+
+import (
+	"fmt"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avrov2"
+)
+
+// SchemaDeserializer decodes payloads of any format, choosing the deserializer by
+// the type of the schema each payload was written with.
+type SchemaDeserializer struct {
+	avro          *avrov2.Deserializer
+	deserializers map[string]DeserializerInterface // by schema type
+}
+
+func NewSchemaDeserializer(client schemaregistry.Client) (*SchemaDeserializer, error) {
+	avroDeserializer, err := avrov2.NewDeserializer(client, confluent.ValueSerde, avrov2.NewDeserializerConfig())
+	if err != nil {
+		return nil, err
+	}
+	protobufDeserializer, err := NewProtobufDeserializer(client)
+	if err != nil {
+		return nil, err
+	}
+	jsonDeserializer, err := NewJSONSchemaDeserializer(client)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaDeserializer{
+		avro: avroDeserializer,
+		deserializers: map[string]DeserializerInterface{
+			"AVRO":     avroDeserializer,
+			"PROTOBUF": protobufDeserializer,
+			"JSON":     jsonDeserializer,
+		},
+	}, nil
+}
+
+// DeserializeInto decodes payload into msg, a model, a *map[string]interface{}
+// or an *interface{}.
+func (d *SchemaDeserializer) DeserializeInto(topic string, payload []byte, msg interface{}) error {
+	if len(payload) < 5 {
+		return fmt.Errorf("payload of %d bytes is too short", len(payload))
+	}
+	info, err := d.avro.GetSchema(topic, payload)
+	if err != nil {
+		return fmt.Errorf("failed to get writer schema: %w", err)
+	}
+	schemaType := info.SchemaType
+	if schemaType == "" {
+		schemaType = "AVRO"
+	}
+	deserializer, ok := d.deserializers[schemaType]
+	if !ok {
+		return fmt.Errorf("unsupported schema type %q", schemaType)
+	}
+	return deserializer.DeserializeInto(topic, payload, msg)
+}
@@ -0,0 +1,78 @@
+package serde
+
+import (
+	"fmt"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	confluent "github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avrov2"
+)
+
+// Formats of the task format key. An empty format is Avro.
+const (
+	FormatAvro       = avro.Format
+	FormatProtobuf   = "protobuf"
+	FormatJSONSchema = "jsonschema"
+)
+
+// SerializerInterface serializes a model or key with the schema of a subject.
+type SerializerInterface interface {
+	Serialize(subject string, data interface{}) ([]byte, error)
+}
+
+// DeserializerInterface decodes a payload into a model, a *map[string]interface{}
+// or an *interface{}.
+type DeserializerInterface interface {
+	DeserializeInto(topic string, payload []byte, msg interface{}) error
+}
+
+// NewSerializer returns the serializer of format. Avro follows the auto-register and
+// latest-version settings of cfg; Protobuf and JSON Schema always use the latest
+// registered version, as their schemas cannot be derived from Go models.
+func NewSerializer(client schemaregistry.Client, format string, cfg config.SchemaRegistryConfig) (SerializerInterface, error) {
+	switch format {
+	case "", FormatAvro:
+		srCfg := avrov2.NewSerializerConfig()
+		srCfg.AutoRegisterSchemas = cfg.AutoRegisterSchemas
+		srCfg.UseLatestVersion = cfg.UseLatestVersion
+		serializer, err := avrov2.NewSerializer(client, confluent.ValueSerde, srCfg)
+		if err != nil {
+			return nil, err
+		}
+		serializer.SubjectNameStrategy = subjectStrategy
+		return serializer, nil
+	case FormatProtobuf:
+		return NewProtobufSerializer(client)
+	case FormatJSONSchema:
+		return NewJSONSchemaSerializer(client)
+	default:
+		return nil, unsupportedFormat(format)
+	}
+}
+
+// NewDeserializer returns the deserializer of format.
+func NewDeserializer(client schemaregistry.Client, format string) (DeserializerInterface, error) {
+	switch format {
+	case "", FormatAvro:
+		return avrov2.NewDeserializer(client, confluent.ValueSerde, avrov2.NewDeserializerConfig())
+	case FormatProtobuf:
+		return NewProtobufDeserializer(client)
+	case FormatJSONSchema:
+		return NewJSONSchemaDeserializer(client)
+	default:
+		return nil, unsupportedFormat(format)
+	}
+}
+
+// subjectStrategy lets serializers receive the subject, already named by the
+// task subject strategy, in place of the topic.
+func subjectStrategy(subject string, _ confluent.Type, _ schemaregistry.SchemaInfo) (string, error) {
+	return subject, nil
+}
+
+func unsupportedFormat(format string) error {
+	return fmt.Errorf("unsupported format %q, expected %s, %s or %s", format, FormatAvro, FormatProtobuf, FormatJSONSchema)
+}
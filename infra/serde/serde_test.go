@@ -0,0 +1,356 @@
+package serde
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/models"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSchema = "user-schema"
+
+var testUser = models.User{
+	ID:        42,
+	Status:    "active",
+	Name:      "Ada",
+	Country:   models.Country{Code: "GB", Name: "United Kingdom"},
+	CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	UpdatedAt: time.Date(2024, 5, 2, 11, 30, 0, 0, time.UTC),
+}
+
+// newTestClient returns a mock registry with the user schema file registered as schemaType.
+func newTestClient(t *testing.T, file, schemaType string) schemaregistry.Client {
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+	assert.NoError(t, err)
+	data, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	_, err = client.Register(testSchema+"-value", schemaregistry.SchemaInfo{Schema: string(data), SchemaType: schemaType}, false)
+	assert.NoError(t, err)
+	return client
+}
+
+func TestSerializers_RoundTrip(t *testing.T) {
+	testCases := []struct {
+		format     string
+		file       string
+		schemaType string
+	}{
+		{format: FormatAvro, file: "../../docker/kafka/user-schema-value.avsc", schemaType: "AVRO"},
+		{format: FormatProtobuf, file: "../../docker/kafka/user-schema-value.proto", schemaType: "PROTOBUF"},
+		{format: FormatJSONSchema, file: "../../docker/kafka/user-schema-value.json", schemaType: "JSON"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			// Arrange
+			client := newTestClient(t, tc.file, tc.schemaType)
+			serializer, err := NewSerializer(client, tc.format, config.SchemaRegistryConfig{UseLatestVersion: true})
+			assert.NoError(t, err)
+			deserializer, err := NewDeserializer(client, tc.format)
+			assert.NoError(t, err)
+
+			// Act
+			payload, err := serializer.Serialize(testSchema+"-value", &testUser)
+			assert.NoError(t, err)
+			var user models.User
+			userErr := deserializer.DeserializeInto(testSchema, payload, &user)
+			var values map[string]interface{}
+			valuesErr := deserializer.DeserializeInto(testSchema, payload, &values)
+
+			// Assert
+			assert.NoError(t, userErr)
+			assert.Equal(t, testUser, user)
+			assert.NoError(t, valuesErr)
+			assert.Equal(t, "Ada", values["name"])
+			assert.Equal(t, map[string]interface{}{"code": "GB", "name": "United Kingdom"}, values["country"])
+		})
+	}
+}
+
+func TestProtobufSerializer_FieldMissingFromMessage(t *testing.T) {
+	// Arrange
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+	assert.NoError(t, err)
+	_, err = client.Register(testSchema+"-value", schemaregistry.SchemaInfo{
+		Schema:     `syntax = "proto3"; package test; message Users { int64 user_id = 1; string name = 2; }`,
+		SchemaType: "PROTOBUF",
+	}, false)
+	assert.NoError(t, err)
+	serializer, err := NewProtobufSerializer(client)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = serializer.Serialize(testSchema+"-value", &testUser)
+
+	// Assert
+	assert.ErrorContains(t, err, "failed to convert to test.Users")
+	assert.ErrorContains(t, err, "unknown field")
+}
+
+func TestProtobufSerializer_ResolvesMessage(t *testing.T) {
+	const schema = `syntax = "proto3"; package test;
+message Event { string id = 1; }
+message User { int64 user_id = 1; string name = 2; }
+message Wrapper { message Inner { string name = 1; } }`
+	type name struct {
+		Name string `avro:"name"`
+	}
+	type user struct {
+		ID   int64  `avro:"user_id"`
+		Name string `avro:"name"`
+	}
+	testCases := []struct {
+		name            string
+		subject         string
+		data            interface{}
+		expectedIndexes []int // path of the message in the schema
+		expectedErr     string
+	}{
+		{name: "record name", subject: "test.Wrapper.Inner", data: name{Name: "Ada"}, expectedIndexes: []int{2, 0}},
+		{name: "topic record name", subject: "user-topic-test.User", data: &user{ID: 42}, expectedIndexes: []int{1}},
+		{name: "model type", subject: "user-schema-value", data: &user{ID: 42}, expectedIndexes: []int{1}},
+		{name: "ambiguous", subject: "user-schema-value", data: name{Name: "Ada"}, expectedErr: "schema of subject user-schema-value declares 3 messages, none named after the subject or serde.name"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+			assert.NoError(t, err)
+			_, err = client.Register(tc.subject, schemaregistry.SchemaInfo{Schema: schema, SchemaType: "PROTOBUF"}, false)
+			assert.NoError(t, err)
+			serializer, err := NewProtobufSerializer(client)
+			assert.NoError(t, err)
+
+			// Act
+			payload, err := serializer.Serialize(tc.subject, tc.data)
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			_, indexes, err := readMessageIndexes(payload[5:])
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedIndexes, indexes)
+		})
+	}
+}
+
+func TestProtobufSerializer_UnknownSubject(t *testing.T) {
+	// Arrange
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+	assert.NoError(t, err)
+	serializer, err := NewProtobufSerializer(client)
+	assert.NoError(t, err)
+
+	// Act
+	_, err = serializer.Serialize(testSchema+"-value", &testUser)
+
+	// Assert
+	assert.ErrorContains(t, err, "failed to get schema of subject user-schema-value")
+}
+
+func TestSerializers_Keys(t *testing.T) {
+	testCases := []struct {
+		format     string
+		schema     string
+		schemaType string
+	}{
+		{format: FormatAvro, schema: `"long"`, schemaType: "AVRO"},
+		{format: FormatJSONSchema, schema: `{"type": "integer"}`, schemaType: "JSON"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			// Arrange
+			client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+			assert.NoError(t, err)
+			id, err := client.Register("example.UserKey", schemaregistry.SchemaInfo{Schema: tc.schema, SchemaType: tc.schemaType}, false)
+			assert.NoError(t, err)
+			serializer, err := NewSerializer(client, tc.format, config.SchemaRegistryConfig{UseLatestVersion: true})
+			assert.NoError(t, err)
+
+			// Act
+			key, err := serializer.Serialize("example.UserKey", &testUser.ID)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, []byte{0, 0, 0, 0, byte(id)}, key[:5])
+		})
+	}
+}
+
+func TestNewSerializer_UnsupportedFormat(t *testing.T) {
+	// Act
+	_, serializerErr := NewSerializer(nil, "thrift", config.SchemaRegistryConfig{})
+	_, deserializerErr := NewDeserializer(nil, "thrift")
+
+	// Assert
+	assert.EqualError(t, serializerErr, `unsupported format "thrift", expected avro, protobuf or jsonschema`)
+	assert.EqualError(t, deserializerErr, `unsupported format "thrift", expected avro, protobuf or jsonschema`)
+}
+
+func TestToMap(t *testing.T) {
+	// Arrange
+	note := "fragile"
+	model := struct {
+		ID       int64          `avro:"id"`
+		Note     *string        `avro:"note"`
+		Missing  *string        `avro:"missing"`
+		At       time.Time      `avro:"at"`
+		Tags     []string       `avro:"tags"`
+		Labels   map[string]int `avro:"labels"`
+		Internal string         `avro:"-"`
+		Untagged bool
+		private  string
+	}{ID: 7, Note: &note, At: testUser.CreatedAt, Tags: []string{"a"}, Labels: map[string]int{"x": 1}, Internal: "no", Untagged: true}
+
+	// Act
+	values, err := ToMap(&model)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":       int64(7),
+		"note":     "fragile",
+		"missing":  nil,
+		"at":       "2024-05-01T10:00:00Z",
+		"tags":     []interface{}{"a"},
+		"labels":   map[string]interface{}{"x": 1},
+		"Untagged": true,
+	}, values)
+}
+
+func TestToMap_NotAStruct(t *testing.T) {
+	// Act
+	_, err := ToMap("user")
+
+	// Assert
+	assert.EqualError(t, err, "expected a struct, got string")
+}
+
+func TestFromMap(t *testing.T) {
+	// Arrange
+	values := map[string]interface{}{
+		"user_id":    "42",
+		"name":       "Ada",
+		"status":     "active",
+		"created_at": "2024-05-01T10:00:00Z",
+		"updated_at": "2024-05-02T11:30:00Z",
+		"country":    map[string]interface{}{"code": "GB", "name": "United Kingdom"},
+	}
+	var user models.User
+
+	// Act
+	err := FromMap(values, &user)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, testUser, user)
+}
+
+func TestLookup(t *testing.T) {
+	// Arrange
+	values := map[string]interface{}{
+		"user_id": int64(42),
+		"country": map[string]interface{}{"code": "GB"},
+	}
+
+	// Act
+	code, found := Lookup(values, "country.code")
+	_, missing := Lookup(values, "country.name")
+	_, notRecord := Lookup(values, "user_id.value")
+
+	// Assert
+	assert.True(t, found)
+	assert.Equal(t, "GB", code)
+	assert.False(t, missing)
+	assert.False(t, notRecord)
+}
+
+func TestSchemaDeserializer_ChoosesFormatBySchema(t *testing.T) {
+	testCases := []struct {
+		format     string
+		file       string
+		schemaType string
+	}{
+		{format: FormatAvro, file: "../../docker/kafka/user-schema-value.avsc", schemaType: "AVRO"},
+		{format: FormatProtobuf, file: "../../docker/kafka/user-schema-value.proto", schemaType: "PROTOBUF"},
+		{format: FormatJSONSchema, file: "../../docker/kafka/user-schema-value.json", schemaType: "JSON"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			// Arrange
+			client := newTestClient(t, tc.file, tc.schemaType)
+			serializer, err := NewSerializer(client, tc.format, config.SchemaRegistryConfig{UseLatestVersion: true})
+			assert.NoError(t, err)
+			deserializer, err := NewSchemaDeserializer(client)
+			assert.NoError(t, err)
+			payload, err := serializer.Serialize(testSchema+"-value", &testUser)
+			assert.NoError(t, err)
+
+			// Act
+			var user models.User
+			err = deserializer.DeserializeInto(testSchema, payload, &user)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, testUser, user)
+		})
+	}
+}
+
+func TestSchemaDeserializer_ShortPayload(t *testing.T) {
+	// Arrange
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+	assert.NoError(t, err)
+	deserializer, err := NewSchemaDeserializer(client)
+	assert.NoError(t, err)
+
+	// Act
+	var user models.User
+	err = deserializer.DeserializeInto(testSchema, []byte{0, 1}, &user)
+
+	// Assert
+	assert.EqualError(t, err, "payload of 2 bytes is too short")
+}
+
+func TestReadMessageIndexes(t *testing.T) {
+	testCases := []struct {
+		name            string
+		payload         []byte
+		expectedRead    int
+		expectedIndexes []int
+		expectedErr     string
+	}{
+		{name: "first message", payload: []byte{0, 8}, expectedRead: 1, expectedIndexes: []int{0}},
+		{name: "nested message", payload: []byte{4, 4, 0, 8}, expectedRead: 3, expectedIndexes: []int{2, 0}},
+		{name: "negative count", payload: []byte{1}, expectedErr: "invalid message indexes"},
+		{name: "count beyond payload", payload: []byte{0xfe, 0xff, 0xff, 0xff, 0x0f, 2}, expectedErr: "invalid message indexes: 2147483647 indexes in 1 bytes"},
+		{name: "truncated index", payload: []byte{4, 2}, expectedErr: "invalid message indexes: 2 indexes in 1 bytes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			read, indexes, err := readMessageIndexes(tc.payload)
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRead, read)
+			assert.Equal(t, tc.expectedIndexes, indexes)
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package serde
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// fieldTag names the struct tag giving the field names of a model, shared by all formats.
+const fieldTag = "avro"
+
+// ToMap converts a model to a map keyed by its avro field names, so every format
+// publishes the same field names. Values implementing encoding.TextMarshaler, such
+// as time.Time, become strings as the Avro serializer writes them.
+func ToMap(model interface{}) (map[string]interface{}, error) {
+	value, err := toValue(reflect.ValueOf(model))
+	if err != nil {
+		return nil, err
+	}
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a struct, got %T", model)
+	}
+	return values, nil
+}
+
+func toValue(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toValue(v.Elem())
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		values := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get(fieldTag), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			value, err := toValue(v.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			values[name] = value
+		}
+		return values, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+		fallthrough
+	case reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := toValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			value, err := toValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			values[fmt.Sprint(iter.Key().Interface())] = value
+		}
+		return values, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+// Lookup returns the value of a dotted field path in decoded values.
+func Lookup(values map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = values
+	for _, name := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = fields[name]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// decodeJSON decodes a JSON document into target: a map, an interface{} or a
+// model whose fields are named by their avro tags.
+func decodeJSON(data []byte, target interface{}) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return decodeValue(value, target)
+}
+
+// decodeValue stores a decoded JSON value into target, see decodeJSON.
+func decodeValue(value interface{}, target interface{}) error {
+	if t, ok := target.(*interface{}); ok {
+		*t = value
+		return nil
+	}
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("cannot decode %T into %T", value, target)
+	}
+	if t, ok := target.(*map[string]interface{}); ok {
+		*t = values
+		return nil
+	}
+	return FromMap(values, target)
+}
+
+// FromMap decodes a map keyed by avro field names into the model target points to.
+// Strings are decoded into encoding.TextUnmarshaler fields such as time.Time, and
+// numbers written as strings, as Protobuf JSON writes 64-bit integers, are converted.
+func FromMap(values map[string]interface{}, target interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:          fieldTag,
+		WeaklyTypedInput: true,
+		DecodeHook:       mapstructure.TextUnmarshallerHookFunc(),
+		Result:           target,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(values)
+}
@@ -0,0 +1,72 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+
+	"kafka-go-example/infra/avro"
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/kafka"
+	"kafka-go-example/infra/serde"
+)
+
+// ProducerInterface is implemented by every sink a task can publish to.
+type ProducerInterface interface {
+	ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error
+	Close()
+}
+
+// NewTaskProducer returns the producer a task publishes to: a dry-run sink in
+// dry-run mode, otherwise the sink selected by the task configuration. Kafka
+// producers are shared through pool; closing the result releases the task's handle.
+func NewTaskProducer(cfg config.TaskConfig, pool *kafka.ProducerPool, schemaCfg config.SchemaRegistryConfig) (ProducerInterface, error) {
+	if cfg.DryRun {
+		decoder, err := newDecoder(cfg.DryRunDecode, cfg.Format, schemaCfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewDryRunSink(cfg, decoder)
+	}
+
+	switch cfg.Sink.Type {
+	case "", config.SinkTypeKafka:
+		return pool.Acquire(cfg.Producer)
+	case config.SinkTypeFile, config.SinkTypeStdout, config.SinkTypeWebhook:
+		decoder, err := newDecoder(cfg.Sink.Decode, cfg.Format, schemaCfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSink(cfg.Sink, decoder)
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s", cfg.Sink.Type)
+	}
+}
+
+// NewSink returns the non-Kafka sink selected by cfg.
+func NewSink(cfg config.SinkConfig, decoder DecoderInterface) (ProducerInterface, error) {
+	switch cfg.Type {
+	case config.SinkTypeFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("file sink requires a path")
+		}
+		return NewFileSink(cfg.Path, cfg.MaxBytes, cfg.MaxFiles, decoder)
+	case config.SinkTypeStdout:
+		return NewStdoutSink(decoder), nil
+	case config.SinkTypeWebhook:
+		return NewWebhookSink(cfg, decoder)
+	default:
+		return nil, fmt.Errorf("unsupported sink type: %s", cfg.Type)
+	}
+}
+
+// newDecoder returns the schema registry deserializer of format when decoding is enabled.
+func newDecoder(enabled bool, format string, schemaCfg config.SchemaRegistryConfig) (DecoderInterface, error) {
+	if !enabled {
+		return nil, nil
+	}
+	client, err := avro.NewSchemaRegistryClient(schemaCfg)
+	if err != nil {
+		return nil, err
+	}
+	return serde.NewDeserializer(client, format)
+}
@@ -0,0 +1,70 @@
+package sink
+
+import (
+	"path/filepath"
+	"testing"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/kafka"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTaskProducer_File(t *testing.T) {
+	// Arrange
+	cfg := config.TaskConfig{
+		Name: "test",
+		Sink: config.SinkConfig{Type: config.SinkTypeFile, Path: filepath.Join(t.TempDir(), "out.jsonl")},
+	}
+
+	// Act
+	producer, err := NewTaskProducer(cfg, kafka.NewProducerPool(config.KafkaConfig{}), config.SchemaRegistryConfig{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.IsType(t, &WriterSink{}, producer)
+	producer.Close()
+}
+
+func TestNewTaskProducer_DryRunOverridesSink(t *testing.T) {
+	// Arrange
+	cfg := config.TaskConfig{
+		Name:   "test",
+		DryRun: true,
+		Sink:   config.SinkConfig{Type: config.SinkTypeWebhook, URL: "http://localhost"},
+	}
+
+	// Act
+	producer, err := NewTaskProducer(cfg, kafka.NewProducerPool(config.KafkaConfig{}), config.SchemaRegistryConfig{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.IsType(t, &WriterSink{}, producer)
+}
+
+func TestNewTaskProducer_UnsupportedType(t *testing.T) {
+	// Act
+	producer, err := NewTaskProducer(config.TaskConfig{Sink: config.SinkConfig{Type: "ftp"}}, kafka.NewProducerPool(config.KafkaConfig{}), config.SchemaRegistryConfig{})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, producer)
+	assert.Contains(t, err.Error(), "unsupported sink type")
+}
+
+func TestNewSink(t *testing.T) {
+	// Stdout
+	producer, err := NewSink(config.SinkConfig{Type: config.SinkTypeStdout}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &WriterSink{}, producer)
+
+	// Webhook
+	producer, err = NewSink(config.SinkConfig{Type: config.SinkTypeWebhook, URL: "http://localhost"}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &WebhookSink{}, producer)
+	producer.Close()
+
+	// File without a path
+	_, err = NewSink(config.SinkConfig{Type: config.SinkTypeFile}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,127 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+const (
+	defaultMaxBytes = 100 * 1024 * 1024
+	defaultMaxFiles = 5
+)
+
+// rotatingFile is an append-only file that is rotated once it reaches maxBytes.
+// Rotated files are renamed to path.1, path.2, ... keeping at most maxFiles of them.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func openRotatingFile(path string, maxBytes int64, maxFiles int) (*rotatingFile, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultMaxFiles
+	}
+
+	r := &rotatingFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Write appends p, rotating the file first if p would not fit.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open sink file %s: %w", r.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat sink file %s: %w", r.path, err)
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files and starts a new file. path is reopened even
+// when rotating fails, so later writes append to it and retry the rotation.
+func (r *rotatingFile) rotate() error {
+	var errs []error
+	if err := r.file.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close sink file %s: %w", r.path, err))
+	} else {
+		errs = append(errs, r.shift()...)
+	}
+	if err := r.open(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// shift renames path.1 to path.2 and so on, dropping the oldest file, then path to path.1.
+func (r *rotatingFile) shift() []error {
+	var errs []error
+	oldest := fmt.Sprintf("%s.%d", r.path, r.maxFiles)
+	if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, fmt.Errorf("failed to remove rotated sink file %s: %w", oldest, err))
+	}
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		from, to := fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1)
+		if err := os.Rename(from, to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, fmt.Errorf("failed to shift rotated sink file %s: %w", from, err))
+		}
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		errs = append(errs, fmt.Errorf("failed to rotate sink file %s: %w", r.path, err))
+	}
+	return errs
+}
+
+// NewFileSink returns a sink appending newline-delimited JSON records to path,
+// rotating the file once it reaches maxBytes.
+func NewFileSink(path string, maxBytes int64, maxFiles int, decoder DecoderInterface) (*WriterSink, error) {
+	f, err := openRotatingFile(path, maxBytes, maxFiles)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(f, decoder), nil
+}
+
+// NewStdoutSink returns a sink writing newline-delimited JSON records to stdout.
+func NewStdoutSink(decoder DecoderInterface) *WriterSink {
+	return NewWriterSink(os.Stdout, decoder)
+}
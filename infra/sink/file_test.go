@@ -0,0 +1,84 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSink_Rotation(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	s, err := NewFileSink(path, 60, 2, nil)
+	assert.NoError(t, err)
+
+	// Act: every record is ~40 bytes, so each write after the first rotates
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil))
+	}
+	s.Close()
+
+	// Assert
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		content, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(content), "\n"), name)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileSink_RotationFailureKeepsWriting(t *testing.T) {
+	// Arrange: a non-empty directory in place of the rotated file
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	assert.NoError(t, os.MkdirAll(filepath.Join(path+".1", "keep"), 0755))
+	s, err := NewFileSink(path, 60, 1, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil))
+
+	// Act
+	failedErr := s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil)
+	assert.NoError(t, os.RemoveAll(path+".1"))
+	retriedErr := s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil)
+	s.Close()
+
+	// Assert
+	assert.ErrorContains(t, failedErr, "failed to rotate sink file")
+	assert.NoError(t, retriedErr)
+	for _, name := range []string{path, path + ".1"} {
+		content, err := os.ReadFile(name)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(content), "\n"), name)
+	}
+}
+
+func TestFileSink_AppendsToExistingFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	assert.NoError(t, os.WriteFile(path, []byte("{}\n"), 0644))
+
+	// Act
+	s, err := NewFileSink(path, 0, 0, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil))
+	s.Close()
+
+	// Assert
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "{}\n"))
+	assert.Equal(t, 2, strings.Count(string(content), "\n"))
+}
+
+func TestFileSink_OpenError(t *testing.T) {
+	// Act
+	s, err := NewFileSink(filepath.Join(t.TempDir(), "missing", "users.jsonl"), 0, 0, nil)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, s)
+}
@@ -0,0 +1,244 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"kafka-go-example/infra/config"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	defaultMaxRetries    = 3
+	defaultTimeout       = 10 * time.Second
+	retryBackoff         = 500 * time.Millisecond
+	maxBufferedBatches   = 10 // batches buffered while the endpoint fails
+)
+
+// WebhookSink posts batches of records as a JSON array to an HTTP endpoint.
+// A batch is sent when it is full, when the flush interval elapses, on Flush and on Close.
+type WebhookSink struct {
+	mu            sync.Mutex // guards batch and err, never held while sending
+	sendMu        sync.Mutex // sends one batch at a time, in order
+	client        *http.Client
+	url           string
+	headers       map[string]string
+	batchSize     int
+	maxBuffered   int
+	maxRetries    int
+	backoff       time.Duration
+	decoder       DecoderInterface
+	batch         []Record
+	sending       int   // records of the batch being sent
+	err           error // first failure of a background send, returned by the next Flush
+	done          chan struct{}
+	closeOnce     sync.Once
+	wg            sync.WaitGroup
+	flushInterval time.Duration
+}
+
+// NewWebhookSink returns a webhook sink for cfg. Header values are expanded with
+// environment variables so credentials can stay out of the YAML file.
+func NewWebhookSink(cfg config.SinkConfig, decoder DecoderInterface) (*WebhookSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook sink requires a url")
+	}
+
+	batchSize := valueOr(cfg.BatchSize, defaultBatchSize)
+	s := &WebhookSink{
+		client:        &http.Client{Timeout: valueOr(cfg.Timeout, defaultTimeout)},
+		url:           cfg.URL,
+		headers:       make(map[string]string, len(cfg.Headers)),
+		batchSize:     batchSize,
+		maxBuffered:   batchSize * maxBufferedBatches,
+		maxRetries:    valueOr(cfg.MaxRetries, defaultMaxRetries),
+		backoff:       retryBackoff,
+		decoder:       decoder,
+		done:          make(chan struct{}),
+		flushInterval: valueOr(cfg.FlushInterval, defaultFlushInterval),
+	}
+	for k, v := range cfg.Headers {
+		s.headers[k] = os.ExpandEnv(v)
+	}
+
+	s.wg.Add(1)
+	go s.flushLoop()
+
+	return s, nil
+}
+
+// ProduceMessage buffers the message, sending the batch once it is full. A nil
+// error means the message is buffered; failures to send it are returned by the
+// next Flush. The message is refused while the buffer holds maxBufferedBatches
+// batches the endpoint did not accept yet.
+func (s *WebhookSink) ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error {
+	record, err := newRecord(s.decoder, topic, payload, key, headers)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if len(s.batch)+s.sending >= s.maxBuffered {
+		s.mu.Unlock()
+		return fmt.Errorf("webhook sink buffer is full with %d unsent records", s.maxBuffered)
+	}
+	s.batch = append(s.batch, record)
+	full := len(s.batch) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		s.record(s.send(ctx))
+	}
+	return nil
+}
+
+// Flush sends the buffered messages and returns the first failure since the last Flush.
+func (s *WebhookSink) Flush(ctx context.Context) error {
+	err := s.send(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		err = s.err
+	}
+	s.err = nil
+	return err
+}
+
+// Close stops the flush loop and sends the remaining messages. Later calls do nothing.
+func (s *WebhookSink) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+
+		if err := s.Flush(context.Background()); err != nil {
+			log.Printf("Failed to flush webhook sink on close: %v", err)
+		}
+	})
+}
+
+func (s *WebhookSink) flushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.record(s.send(context.Background()))
+		}
+	}
+}
+
+// record keeps the first failure of a background send for the next Flush.
+func (s *WebhookSink) record(err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("Failed to flush webhook sink: %v", err)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// send takes the buffered batch and posts it. A batch the endpoint rejects, with
+// a client error other than 429, is dropped; a batch that still fails after the
+// retries is put back in front of the buffer for the next send.
+func (s *WebhookSink) send(ctx context.Context) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.mu.Lock()
+	batch := s.batch
+	s.batch = nil
+	s.sending = len(batch)
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	defer func() {
+		s.mu.Lock()
+		s.sending = 0
+		s.mu.Unlock()
+	}()
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook batch, dropped %d records: %w", len(batch), err)
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retriable, err := s.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retriable {
+			return fmt.Errorf("webhook rejected the batch, dropped %d records: %w", len(batch), err)
+		}
+		if attempt >= s.maxRetries {
+			s.requeue(batch)
+			return fmt.Errorf("failed to send webhook batch after %d attempts: %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.requeue(batch)
+			return fmt.Errorf("failed to send webhook batch: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// requeue puts an unsent batch back in front of the records buffered since.
+func (s *WebhookSink) requeue(batch []Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch = append(batch, s.batch...)
+}
+
+// post sends body once and reports whether a failure is worth retrying.
+func (s *WebhookSink) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retriable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retriable, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}
+
+func valueOr[T int | time.Duration](value, fallback T) T {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
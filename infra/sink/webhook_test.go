@@ -0,0 +1,231 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// webhookServer records the batches it receives and fails the first failures requests.
+type webhookServer struct {
+	mu       sync.Mutex
+	batches  [][]Record
+	headers  []http.Header
+	failures int
+	status   int
+}
+
+func (w *webhookServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.headers = append(w.headers, r.Header.Clone())
+	if w.failures > 0 {
+		w.failures--
+		rw.WriteHeader(w.status)
+		return
+	}
+
+	var batch []Record
+	json.NewDecoder(r.Body).Decode(&batch)
+	w.batches = append(w.batches, batch)
+}
+
+// sendingRecords returns the records of the batch being sent.
+func (s *WebhookSink) sendingRecords() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sending
+}
+
+func (w *webhookServer) received() [][]Record {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.batches
+}
+
+func TestWebhookSink_BatchesAndHeaders(t *testing.T) {
+	// Arrange
+	os.Setenv("WEBHOOK_TEST_TOKEN", "secret")
+	defer os.Unsetenv("WEBHOOK_TEST_TOKEN")
+
+	handler := &webhookServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{
+		URL:           server.URL,
+		Headers:       map[string]string{"authorization": "Bearer ${WEBHOOK_TEST_TOKEN}"},
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	}, nil)
+	assert.NoError(t, err)
+
+	// Act
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+	assert.Empty(t, handler.received())
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("2"), nil, nil))
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("3"), nil, nil))
+	s.Close()
+
+	// Assert
+	batches := handler.received()
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], 2)
+	assert.Len(t, batches[1], 1)
+	assert.Equal(t, "Bearer secret", handler.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/json", handler.headers[0].Get("Content-Type"))
+}
+
+func TestWebhookSink_FlushInterval(t *testing.T) {
+	// Arrange
+	handler := &webhookServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{URL: server.URL, FlushInterval: 20 * time.Millisecond}, nil)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	// Act
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+
+	// Assert
+	assert.Eventually(t, func() bool { return len(handler.received()) == 1 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWebhookSink_RetriesServerErrors(t *testing.T) {
+	// Arrange
+	handler := &webhookServer{failures: 2, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{URL: server.URL, MaxRetries: 2, FlushInterval: time.Hour}, nil)
+	assert.NoError(t, err)
+	s.backoff = time.Millisecond
+
+	// Act
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+	err = s.Flush(context.Background())
+	s.Close()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, handler.received(), 1)
+	assert.Len(t, handler.headers, 3)
+}
+
+func TestWebhookSink_ClientErrorDropsTheBatch(t *testing.T) {
+	// Arrange
+	handler := &webhookServer{failures: 1, status: http.StatusUnauthorized}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{URL: server.URL, MaxRetries: 3, FlushInterval: time.Hour}, nil)
+	assert.NoError(t, err)
+	s.backoff = time.Millisecond
+
+	// Act
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+	err = s.Flush(context.Background())
+	nextErr := s.Flush(context.Background())
+	s.Close()
+
+	// Assert
+	assert.EqualError(t, err, "webhook rejected the batch, dropped 1 records: webhook responded with status 401")
+	assert.NoError(t, nextErr)
+	assert.Len(t, handler.headers, 1)
+	assert.Empty(t, handler.received())
+}
+
+func TestWebhookSink_FailedBatchesAreKeptUpToTheLimit(t *testing.T) {
+	// Arrange
+	handler := &webhookServer{failures: 100, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{URL: server.URL, BatchSize: 1, MaxRetries: 1, FlushInterval: time.Hour}, nil)
+	assert.NoError(t, err)
+	s.backoff = time.Millisecond
+
+	// Act
+	var errs []error
+	for i := 0; i < maxBufferedBatches+1; i++ {
+		errs = append(errs, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+	}
+	flushErr := s.Flush(context.Background())
+
+	// Assert
+	for _, err := range errs[:maxBufferedBatches] {
+		assert.NoError(t, err)
+	}
+	assert.EqualError(t, errs[maxBufferedBatches], "webhook sink buffer is full with 10 unsent records")
+	assert.EqualError(t, flushErr, "failed to send webhook batch after 2 attempts: webhook responded with status 503")
+	assert.Len(t, s.batch, maxBufferedBatches)
+}
+
+func TestWebhookSink_ProducesWhileRetrying(t *testing.T) {
+	// Arrange
+	handler := &webhookServer{failures: 1, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{URL: server.URL, BatchSize: 10, FlushInterval: time.Hour}, nil)
+	assert.NoError(t, err)
+	s.backoff = 200 * time.Millisecond
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+	flushed := make(chan error)
+	go func() { flushed <- s.Flush(context.Background()) }()
+	assert.Eventually(t, func() bool { return len(handler.received()) == 0 && s.sendingRecords() == 1 }, time.Second, time.Millisecond)
+
+	// Act
+	start := time.Now()
+	err = s.ProduceMessage(context.Background(), "test-topic", []byte("2"), nil, nil)
+	elapsed := time.Since(start)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Less(t, elapsed, 100*time.Millisecond)
+	assert.NoError(t, <-flushed)
+	s.Close()
+	batches := handler.received()
+	assert.Len(t, batches, 2)
+	assert.Len(t, batches[0], 1)
+	assert.Len(t, batches[1], 1)
+}
+
+func TestWebhookSink_CloseTwice(t *testing.T) {
+	// Arrange
+	handler := &webhookServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	s, err := NewWebhookSink(config.SinkConfig{URL: server.URL, FlushInterval: time.Hour}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("1"), nil, nil))
+
+	// Act & Assert
+	assert.NotPanics(t, func() {
+		s.Close()
+		s.Close()
+	})
+	assert.Len(t, handler.received(), 1)
+}
+
+func TestNewWebhookSink_MissingURL(t *testing.T) {
+	// Act
+	s, err := NewWebhookSink(config.SinkConfig{}, nil)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, s)
+}
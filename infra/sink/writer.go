@@ -0,0 +1,127 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"kafka-go-example/infra/config"
+)
+
+// DecoderInterface decodes a serialized payload back into a generic value.
+type DecoderInterface interface {
+	DeserializeInto(topic string, payload []byte, msg interface{}) error
+}
+
+// Record is the JSON representation of a message written by a sink.
+type Record struct {
+	Topic   string                 `json:"topic"`
+	Key     string                 `json:"key,omitempty"`
+	Headers map[string]string      `json:"headers,omitempty"`
+	Payload []byte                 `json:"payload,omitempty"`
+	Value   map[string]interface{} `json:"value,omitempty"`
+}
+
+// WriterSink writes messages as newline-delimited JSON records instead of producing them.
+type WriterSink struct {
+	mu      sync.Mutex
+	w       io.Writer
+	closer  io.Closer
+	decoder DecoderInterface
+}
+
+// NewWriterSink returns a sink writing to w. When decoder is set, payloads are
+// decoded and written as JSON values instead of raw bytes.
+func NewWriterSink(w io.Writer, decoder DecoderInterface) *WriterSink {
+	s := &WriterSink{w: w, decoder: decoder}
+	if c, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+		s.closer = c
+	}
+	return s
+}
+
+// NewDryRunSink returns the sink a task writes to in dry-run mode.
+func NewDryRunSink(cfg config.TaskConfig, decoder DecoderInterface) (*WriterSink, error) {
+	if !cfg.DryRunDecode {
+		decoder = nil
+	}
+	if cfg.DryRunOutput == "" {
+		return NewWriterSink(os.Stdout, decoder), nil
+	}
+
+	f, err := os.OpenFile(cfg.DryRunOutput, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dry-run output %s: %w", cfg.DryRunOutput, err)
+	}
+	return NewWriterSink(f, decoder), nil
+}
+
+// ProduceMessage writes the message as a single JSON line.
+func (s *WriterSink) ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	record, err := newRecord(s.decoder, topic, payload, key, headers)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// newRecord builds the record for a message, decoding its payload when decoder is set.
+func newRecord(decoder DecoderInterface, topic string, payload []byte, key []byte, headers map[string]string) (Record, error) {
+	record := Record{
+		Topic:   topic,
+		Key:     string(key),
+		Headers: headers,
+	}
+
+	if decoder == nil {
+		record.Payload = payload
+		return record, nil
+	}
+
+	if err := decoder.DeserializeInto(topic, payload, &record.Value); err != nil {
+		return Record{}, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	if len(key) > 0 {
+		var value interface{}
+		if err := decoder.DeserializeInto(topic, key, &value); err != nil {
+			return Record{}, fmt.Errorf("failed to decode key: %w", err)
+		}
+		record.Key = keyString(value)
+	}
+	return record, nil
+}
+
+// keyString returns a decoded key as text, encoding keys that are not strings as JSON.
+func keyString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// Close closes the underlying writer unless it is stdout or stderr.
+func (s *WriterSink) Close() {
+	if s.closer != nil {
+		s.closer.Close()
+	}
+}
@@ -0,0 +1,130 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDecoder struct {
+	mock.Mock
+}
+
+func (m *MockDecoder) DeserializeInto(topic string, payload []byte, msg interface{}) error {
+	args := m.Called(topic, payload, msg)
+	if target, ok := msg.(*interface{}); ok {
+		*target = args.Get(0)
+	} else if value, ok := args.Get(0).(map[string]interface{}); ok {
+		*(msg.(*map[string]interface{})) = value
+	}
+	return args.Error(1)
+}
+
+func TestWriterSink_ProduceMessage(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	s := NewWriterSink(&buf, nil)
+
+	// Act
+	err := s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), []byte("key"), map[string]string{"replay": "true"})
+
+	// Assert
+	assert.NoError(t, err)
+	var record Record
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "test-topic", record.Topic)
+	assert.Equal(t, "key", record.Key)
+	assert.Equal(t, map[string]string{"replay": "true"}, record.Headers)
+	assert.Equal(t, []byte("payload"), record.Payload)
+	assert.Nil(t, record.Value)
+}
+
+func TestWriterSink_ProduceMessageDecoded(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	decoder := new(MockDecoder)
+	decoder.On("DeserializeInto", "test-topic", []byte("payload"), mock.Anything).
+		Return(map[string]interface{}{"name": "John"}, nil)
+	s := NewWriterSink(&buf, decoder)
+
+	// Act
+	err := s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"topic":"test-topic","value":{"name":"John"}}`, buf.String())
+	decoder.AssertExpectations(t)
+}
+
+func TestWriterSink_ProduceMessageDecodedKey(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	decoder := new(MockDecoder)
+	decoder.On("DeserializeInto", "test-topic", []byte("payload"), mock.Anything).
+		Return(map[string]interface{}{"name": "John"}, nil)
+	decoder.On("DeserializeInto", "test-topic", []byte("key"), mock.Anything).Return(int64(42), nil)
+	s := NewWriterSink(&buf, decoder)
+
+	// Act
+	err := s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), []byte("key"), nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"topic":"test-topic","key":"42","value":{"name":"John"}}`, buf.String())
+	decoder.AssertExpectations(t)
+}
+
+func TestWriterSink_DecodeError(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	decoder := new(MockDecoder)
+	decoder.On("DeserializeInto", "test-topic", []byte("payload"), mock.Anything).
+		Return(nil, errors.New("decode error"))
+	s := NewWriterSink(&buf, decoder)
+
+	// Act
+	err := s.ProduceMessage(context.Background(), "test-topic", []byte("payload"), nil, nil)
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "decode error")
+	assert.Empty(t, buf.String())
+}
+
+func TestNewDryRunSink_File(t *testing.T) {
+	// Arrange
+	output := filepath.Join(t.TempDir(), "dry-run.jsonl")
+	cfg := config.TaskConfig{DryRun: true, DryRunOutput: output}
+
+	// Act
+	s, err := NewDryRunSink(cfg, new(MockDecoder))
+	assert.NoError(t, err)
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("a"), nil, nil))
+	assert.NoError(t, s.ProduceMessage(context.Background(), "test-topic", []byte("b"), nil, nil))
+	s.Close()
+
+	// Assert
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Len(t, bytes.Split(bytes.TrimSpace(content), []byte("\n")), 2)
+	assert.Nil(t, s.decoder) // decoding is off unless dry_run_decode is set
+}
+
+func TestNewDryRunSink_Stdout(t *testing.T) {
+	// Act
+	s, err := NewDryRunSink(config.TaskConfig{DryRun: true}, nil)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, os.Stdout, s.w)
+	assert.Nil(t, s.closer)
+}
@@ -4,13 +4,14 @@ import (
 	"context"
 	"log"
 	"os"
-	"os/signal"
-	"syscall"
 
 	"kafka-go-example/infra/avro"
 	"kafka-go-example/infra/config"
 	"kafka-go-example/infra/database"
 	"kafka-go-example/infra/kafka"
+	"kafka-go-example/infra/lifecycle"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/infra/sink"
 	"kafka-go-example/tasks"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -21,44 +22,72 @@ func main() {
 	dbCfg := config.LoadDatabaseConfig()
 	kafkaCfg := config.LoadKafkaConfig()
 	schemaCfg := config.LoadSchemaRegistryConfig()
+	dryRunCfg := config.LoadDryRunConfig()
+	shutdownCfg := config.LoadShutdownConfig()
 	taskConfigs, err := config.LoadTaskConfigs("config")
 	if err != nil {
 		log.Fatalf("Failed to load task configurations: %v", err)
 	}
 
-	// Initialize database
-	db, err := database.NewDatabase(dbCfg)
+	// Validate producer configuration, including unknown librdkafka properties,
+	// before starting any task
+	for i, cfg := range taskConfigs {
+		taskConfigs[i] = config.ApplyDryRun(cfg, dryRunCfg)
+		if err := kafka.ValidateProducerConfig(kafkaCfg, cfg.Producer); err != nil {
+			log.Fatalf("Invalid producer configuration for task %s: %v", cfg.Name, err)
+		}
+	}
+
+	// Verify task models against the registered schemas before reading any row
+	schemaClient, err := avro.NewSchemaRegistryClient(schemaCfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to create schema registry client: %v", err)
+	}
+	if !avro.NewSchemaManager(schemaClient).VerifyTasks(taskConfigs, tasks.TaskModel, schemaCfg.AutoRegisterSchemas) {
+		log.Fatalf("Task models do not match their registered schemas")
 	}
-	defer db.Close()
 
-	// Initialize serializer
-	avroSer, err := avro.NewAvroSerializer(schemaCfg)
+	// Create the declared topics that are missing, existing topics are never altered
+	report, err := kafka.ProvisionTopics(context.Background(), kafkaCfg, taskConfigs, true)
 	if err != nil {
-		log.Fatalf("Failed to create avro serializer: %v", err)
+		log.Fatalf("Failed to provision topics: %v", err)
+	}
+	for _, topic := range report.Created {
+		log.Printf("Created topic %s", topic)
+	}
+	for _, topic := range report.Existing {
+		log.Printf("Topic %s created by another client, settings not checked", topic)
+	}
+	for _, drift := range report.Drift {
+		log.Printf("Topic drift, not altered: %s", drift)
 	}
-	serializer, err := avro.NewSerializer(avroSer)
+
+	// Initialize database
+	db, err := database.NewDatabase(dbCfg)
 	if err != nil {
-		log.Fatalf("Failed to create serializer: %v", err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Create a context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
+	// Initialize the producers shared by all tasks
+	pool := kafka.NewProducerPool(kafkaCfg)
+
+	// Shutdown stops the runners, waits for running executions, then
+	// flushes and closes the producers before closing the database
+	manager := lifecycle.NewManager(shutdownCfg.Timeout, shutdownCfg.HookTimeout)
+	var producers []sink.ProducerInterface
 
 	// Initialize and run tasks
 	for _, cfg := range taskConfigs {
-		kafkaProducer, err := kafka.NewKafkaProducer(kafkaCfg)
+		serializer, err := serde.NewSerializer(schemaClient, cfg.Format, schemaCfg)
 		if err != nil {
-			log.Printf("Failed to create Kafka producer for task %s: %v", cfg.Name, err)
+			log.Fatalf("Failed to create serializer for task %s: %v", cfg.Name, err)
 		}
-		producer := kafka.NewProducer(kafkaProducer)
-		defer producer.Close()
 
+		producer, err := sink.NewTaskProducer(cfg, pool, schemaCfg)
 		if err != nil {
-			continue
+			log.Fatalf("Failed to create producer for task %s: %v", cfg.Name, err)
 		}
-		defer kafkaProducer.Close()
+		producers = append(producers, producer)
 
 		t, err := tasks.CreateTask(db, cfg, serializer, producer)
 		if err != nil {
@@ -67,17 +96,25 @@ func main() {
 		}
 
 		runner := tasks.NewTaskRunner(t, cfg.Interval)
-		go runner.Run(ctx)
+		manager.Go(runner.RunWithDrain)
 	}
 
+	manager.OnShutdown("flush producers", pool.Drain)
+	manager.OnShutdown("close producers", func(ctx context.Context) error {
+		for _, producer := range producers {
+			producer.Close()
+		}
+		pool.Close()
+		return nil
+	})
+	manager.OnShutdown("close database", func(ctx context.Context) error {
+		return db.Close()
+	})
+
 	// Graceful shutdown
-	// Wait for termination signal
-	// Cancel the context to stop tasks
-	// @fixme: add a timeout to the context
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
-
-	<-signalChan
-	log.Println("Shutting down gracefully...")
-	cancel()
+	manager.WaitForSignal()
+	if err := manager.Shutdown(); err != nil {
+		log.Printf("Shutdown incomplete: %v", err)
+		os.Exit(1)
+	}
 }
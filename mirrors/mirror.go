@@ -0,0 +1,117 @@
+package mirrors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/consumer"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/tasks"
+)
+
+// RepositoryInterface writes the rows of the mirrored table.
+type RepositoryInterface interface {
+	Upsert(ctx context.Context, row map[string]interface{}) error
+	Delete(ctx context.Context, key map[string]interface{}) error
+}
+
+// Mirror writes the records of a topic, decoded into T, to the rows of a table.
+// Records are upserted and tombstones delete the row of their key. The offset
+// of a record is committed once its write is committed.
+type Mirror[T any] struct {
+	cfg          config.MirrorConfig
+	repo         RepositoryInterface
+	deserializer serde.DeserializerInterface
+}
+
+// NewMirror returns the mirror of cfg. Tombstone keys are decoded with deserializer.
+func NewMirror[T any](cfg config.MirrorConfig, repo RepositoryInterface, deserializer serde.DeserializerInterface) (*Mirror[T], error) {
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("mirror %s has no topic", cfg.Name)
+	}
+	if len(cfg.Columns) == 0 {
+		return nil, fmt.Errorf("mirror %s has no columns", cfg.Name)
+	}
+	var item T
+	for _, column := range columns(cfg) {
+		v, err := tasks.FieldRef(&item, cfg.Columns[column])
+		if err != nil {
+			return nil, fmt.Errorf("mirror %s column %s: %w", cfg.Name, column, err)
+		}
+		if v.IsValid() && v.Kind() == reflect.Struct && v.Type() != reflect.TypeOf(time.Time{}) {
+			return nil, fmt.Errorf("mirror %s column %s: field %q is a struct, map one of its fields", cfg.Name, column, cfg.Columns[column])
+		}
+	}
+	return &Mirror[T]{cfg: cfg, repo: repo, deserializer: deserializer}, nil
+}
+
+// Handle upserts the row of a record, or deletes the row of a tombstone key.
+// Records that cannot be mapped to a row are not retried.
+func (m *Mirror[T]) Handle(ctx context.Context, msg consumer.Message[T]) error {
+	if msg.Tombstone {
+		key, err := m.key(msg.Topic, msg.Key)
+		if err != nil {
+			return consumer.Permanent(err)
+		}
+		return m.repo.Delete(ctx, key)
+	}
+
+	row := make(map[string]interface{}, len(m.cfg.Columns))
+	for column, path := range m.cfg.Columns {
+		v, err := tasks.FieldRef(&msg.Value, path)
+		if err != nil {
+			return consumer.Permanent(err)
+		}
+		if v.IsValid() {
+			row[column] = v.Interface()
+		} else {
+			row[column] = nil
+		}
+	}
+	return m.repo.Upsert(ctx, row)
+}
+
+// key returns the key columns of a tombstone from its key. A record key holds
+// the fields mapped to the key columns, any other key is the only key column.
+func (m *Mirror[T]) key(topic string, payload []byte) (map[string]interface{}, error) {
+	if len(payload) == 0 {
+		return nil, errors.New("tombstone has no key")
+	}
+	var decoded interface{}
+	if err := m.deserializer.DeserializeInto(topic, payload, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to deserialize tombstone key: %w", err)
+	}
+
+	key := make(map[string]interface{}, len(m.cfg.KeyColumns))
+	record, ok := decoded.(map[string]interface{})
+	if !ok {
+		if len(m.cfg.KeyColumns) != 1 {
+			return nil, fmt.Errorf("tombstone key %v has one value, expected %d key columns", decoded, len(m.cfg.KeyColumns))
+		}
+		key[m.cfg.KeyColumns[0]] = decoded
+		return key, nil
+	}
+	for _, column := range m.cfg.KeyColumns {
+		value, ok := serde.Lookup(record, m.cfg.Columns[column])
+		if !ok {
+			return nil, fmt.Errorf("tombstone key has no field %q for key column %s", m.cfg.Columns[column], column)
+		}
+		key[column] = value
+	}
+	return key, nil
+}
+
+// columns returns the mapped columns of cfg in name order.
+func columns(cfg config.MirrorConfig) []string {
+	names := make([]string, 0, len(cfg.Columns))
+	for column := range cfg.Columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+	return names
+}
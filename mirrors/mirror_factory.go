@@ -0,0 +1,77 @@
+package mirrors
+
+import (
+	"fmt"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/consumer"
+	"kafka-go-example/infra/serde"
+	"kafka-go-example/models"
+	"kafka-go-example/repositories"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Stores of config.DedupConfig.
+const (
+	dedupStoreMemory  = "memory"
+	dedupStoreMySQL   = "mysql"
+	defaultDedupTable = "consumer_dedup"
+	defaultRetention  = 7 * 24 * time.Hour
+)
+
+// RegisterMirror registers the mirror of cfg on c, decoding records into the model cfg names.
+func RegisterMirror(c *consumer.Consumer, db *sqlx.DB, cfg config.MirrorConfig, deserializer serde.DeserializerInterface) error {
+	switch cfg.Model {
+	case "user":
+		return register[models.User](c, db, cfg, deserializer)
+	// Add cases for other models here, matching tasks.CreateTask
+	default:
+		return fmt.Errorf("unsupported mirror model: %s", cfg.Model)
+	}
+}
+
+func register[T any](c *consumer.Consumer, db *sqlx.DB, cfg config.MirrorConfig, deserializer serde.DeserializerInterface) error {
+	repo, err := repositories.NewMirrorRepository(db, cfg.Table, columns(cfg), cfg.KeyColumns)
+	if err != nil {
+		return fmt.Errorf("mirror %s: %w", cfg.Name, err)
+	}
+	mirror, err := NewMirror[T](cfg, repo, deserializer)
+	if err != nil {
+		return err
+	}
+	handle := mirror.Handle
+	if cfg.Dedup.Field != "" || cfg.Dedup.Header != "" {
+		store, err := dedupStore(db, cfg.Dedup)
+		if err != nil {
+			return fmt.Errorf("mirror %s: %w", cfg.Name, err)
+		}
+		handle, err = consumer.Dedup(store, consumer.DedupOptions{Field: cfg.Dedup.Field, Header: cfg.Dedup.Header, Keys: deserializer}, handle)
+		if err != nil {
+			return fmt.Errorf("mirror %s: %w", cfg.Name, err)
+		}
+	}
+	consumer.Register(c, cfg.Topic, handle)
+	return nil
+}
+
+// dedupStore returns the store of the handled versions of cfg.
+func dedupStore(db *sqlx.DB, cfg config.DedupConfig) (consumer.DedupStoreInterface, error) {
+	switch cfg.Store {
+	case "", dedupStoreMemory:
+		return consumer.NewMemoryDedupStore(cfg.Size), nil
+	case dedupStoreMySQL:
+		table := cfg.Table
+		if table == "" {
+			table = defaultDedupTable
+		}
+		retention := cfg.Retention
+		if retention <= 0 {
+			retention = defaultRetention
+		}
+		return repositories.NewDedupRepository(db, table, retention)
+	default:
+		return nil, fmt.Errorf("unsupported dedup store %q, expected %s or %s", cfg.Store, dedupStoreMemory, dedupStoreMySQL)
+	}
+}
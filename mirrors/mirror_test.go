@@ -0,0 +1,225 @@
+package mirrors
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/consumer"
+	"kafka-go-example/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Upsert(ctx context.Context, row map[string]interface{}) error {
+	args := m.Called(ctx, row)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, key map[string]interface{}) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+type MockDeserializer struct {
+	mock.Mock
+}
+
+func (m *MockDeserializer) DeserializeInto(topic string, payload []byte, msg interface{}) error {
+	args := m.Called(topic, payload, msg)
+	*msg.(*interface{}) = args.Get(0)
+	return args.Error(1)
+}
+
+func mirrorConfig() config.MirrorConfig {
+	return config.MirrorConfig{
+		Name:       "user-mirror",
+		Topic:      "user-topic",
+		Model:      "user",
+		Table:      "users_mirror",
+		KeyColumns: []string{"id"},
+		Columns: map[string]string{
+			"id":           "user_id",
+			"name":         "name",
+			"country_code": "country.code",
+			"updated_at":   "updated_at",
+		},
+	}
+}
+
+func TestMirror_UpsertsRecords(t *testing.T) {
+	// Arrange
+	repo := &MockRepository{}
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	repo.On("Upsert", mock.Anything, map[string]interface{}{
+		"id":           int64(7),
+		"name":         "Ada",
+		"country_code": "NL",
+		"updated_at":   updatedAt,
+	}).Return(nil)
+	mirror, err := NewMirror[models.User](mirrorConfig(), repo, &MockDeserializer{})
+	assert.NoError(t, err)
+
+	// Act
+	err = mirror.Handle(context.Background(), consumer.Message[models.User]{
+		Topic: "user-topic",
+		Value: models.User{ID: 7, Name: "Ada", Country: models.Country{Code: "NL"}, UpdatedAt: updatedAt},
+	})
+
+	// Assert
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestMirror_DeletesTombstones(t *testing.T) {
+	testCases := []struct {
+		name        string
+		keyColumns  []string
+		decodedKey  interface{}
+		expectedKey map[string]interface{}
+	}{
+		{name: "scalar key", keyColumns: []string{"id"}, decodedKey: int64(7), expectedKey: map[string]interface{}{"id": int64(7)}},
+		{
+			name:        "record key",
+			keyColumns:  []string{"id", "country_code"},
+			decodedKey:  map[string]interface{}{"user_id": int64(7), "country": map[string]interface{}{"code": "NL"}},
+			expectedKey: map[string]interface{}{"id": int64(7), "country_code": "NL"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			cfg := mirrorConfig()
+			cfg.KeyColumns = tc.keyColumns
+			repo := &MockRepository{}
+			repo.On("Delete", mock.Anything, tc.expectedKey).Return(nil)
+			deserializer := &MockDeserializer{}
+			deserializer.On("DeserializeInto", "user-topic", []byte("key"), mock.Anything).Return(tc.decodedKey, nil)
+			mirror, err := NewMirror[models.User](cfg, repo, deserializer)
+			assert.NoError(t, err)
+
+			// Act
+			err = mirror.Handle(context.Background(), consumer.Message[models.User]{Topic: "user-topic", Key: []byte("key"), Tombstone: true})
+
+			// Assert
+			assert.NoError(t, err)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMirror_UnmappableTombstonesAreNotRetried(t *testing.T) {
+	testCases := []struct {
+		name        string
+		key         []byte
+		decodedKey  interface{}
+		decodeErr   error
+		expectedErr string
+	}{
+		{name: "no key", expectedErr: "tombstone has no key"},
+		{name: "undecodable key", key: []byte("key"), decodeErr: errors.New("unknown magic byte"), expectedErr: "failed to deserialize tombstone key: unknown magic byte"},
+		{name: "missing field", key: []byte("key"), decodedKey: map[string]interface{}{"id": int64(7)}, expectedErr: `tombstone key has no field "user_id" for key column id`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			deserializer := &MockDeserializer{}
+			deserializer.On("DeserializeInto", "user-topic", tc.key, mock.Anything).Return(tc.decodedKey, tc.decodeErr)
+			mirror, err := NewMirror[models.User](mirrorConfig(), &MockRepository{}, deserializer)
+			assert.NoError(t, err)
+
+			// Act
+			err = mirror.Handle(context.Background(), consumer.Message[models.User]{Topic: "user-topic", Key: tc.key, Tombstone: true})
+
+			// Assert
+			var permanentErr *consumer.PermanentError
+			assert.ErrorAs(t, err, &permanentErr)
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestNewMirror_InvalidColumns(t *testing.T) {
+	testCases := []struct {
+		name        string
+		columns     map[string]string
+		expectedErr string
+	}{
+		{name: "no columns", expectedErr: "mirror user-mirror has no columns"},
+		{name: "unknown field", columns: map[string]string{"id": "uid"}, expectedErr: `mirror user-mirror column id: cannot resolve field "uid": models.User has no field "uid"`},
+		{name: "struct field", columns: map[string]string{"country": "country"}, expectedErr: `mirror user-mirror column country: field "country" is a struct, map one of its fields`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			cfg := mirrorConfig()
+			cfg.Columns = tc.columns
+
+			// Act
+			mirror, err := NewMirror[models.User](cfg, &MockRepository{}, &MockDeserializer{})
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Nil(t, mirror)
+		})
+	}
+}
+
+func TestRegisterMirror(t *testing.T) {
+	// Arrange
+	c := consumer.NewConsumer(nil, &MockDeserializer{}, nil, config.ConsumerConfig{})
+	unsupported := mirrorConfig()
+	unsupported.Model = "order"
+
+	// Act
+	err := RegisterMirror(c, nil, mirrorConfig(), &MockDeserializer{})
+	unsupportedErr := RegisterMirror(c, nil, unsupported, &MockDeserializer{})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user-topic"}, c.Topics())
+	assert.EqualError(t, unsupportedErr, "unsupported mirror model: order")
+}
+
+func TestRegisterMirror_Dedup(t *testing.T) {
+	testCases := []struct {
+		name        string
+		dedup       config.DedupConfig
+		expectedErr string
+	}{
+		{name: "memory store", dedup: config.DedupConfig{Field: "updated_at"}},
+		{name: "mysql store", dedup: config.DedupConfig{Header: "event-id", Store: "mysql"}},
+		{name: "unsupported store", dedup: config.DedupConfig{Field: "updated_at", Store: "redis"}, expectedErr: `mirror user-mirror: unsupported dedup store "redis", expected memory or mysql`},
+		{name: "unknown field", dedup: config.DedupConfig{Field: "version"}, expectedErr: `mirror user-mirror: cannot deduplicate by "version": models.User has no such field`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			c := consumer.NewConsumer(nil, &MockDeserializer{}, nil, config.ConsumerConfig{})
+			cfg := mirrorConfig()
+			cfg.Dedup = tc.dedup
+
+			// Act
+			err := RegisterMirror(c, nil, cfg, &MockDeserializer{})
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"user-topic"}, c.Topics())
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 
 type User struct {
 	ID        int64     `db:"id" avro:"user_id"`
-	Status    string    `db:"status" avro:"status"`
+	Status    string    `db:"status" avro:"status" avro_default:"active"`
 	Name      string    `db:"name" avro:"name"`
 	Country   Country   `db:"country" avro:"country"`
 	CreatedAt time.Time `db:"created_at" avro:"created_at"`
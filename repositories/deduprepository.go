@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// maxDedupKeyLength is the length of the dedup_key column. Longer keys are stored
+// as their SHA-256 digest.
+const maxDedupKeyLength = 255
+
+// pruneInterval is the least time between two deletions of expired versions.
+const pruneInterval = time.Hour
+
+// DedupRepository records the last version a consumer handled per deduplication
+// key, in a table with the columns of docker/mysql/init/consumer_dedup.sql.
+// Versions handled longer than the retention ago are deleted as new ones are set.
+type DedupRepository struct {
+	db        *sqlx.DB
+	query     string
+	upsert    string
+	prune     string
+	retention time.Duration
+
+	mu     sync.Mutex
+	pruned time.Time // last deletion of expired versions
+}
+
+// NewDedupRepository returns a repository of the versions recorded in table,
+// keeping them for retention, forever when 0.
+func NewDedupRepository(db *sqlx.DB, table string, retention time.Duration) (*DedupRepository, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	return &DedupRepository{
+		db:        db,
+		query:     fmt.Sprintf("SELECT version FROM %s WHERE dedup_key = ?", quote(table)),
+		upsert:    fmt.Sprintf("INSERT INTO %s (dedup_key, version, handled_at) VALUES (?, ?, CURRENT_TIMESTAMP) ON DUPLICATE KEY UPDATE version = VALUES(version), handled_at = CURRENT_TIMESTAMP", quote(table)),
+		prune:     fmt.Sprintf("DELETE FROM %s WHERE handled_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND", quote(table)),
+		retention: retention,
+	}, nil
+}
+
+// Version returns the last version recorded for key, false when there is none.
+func (r *DedupRepository) Version(ctx context.Context, key string) (string, bool, error) {
+	var version string
+	if err := r.db.GetContext(ctx, &version, r.query, dedupKey(key)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get version of %q: %w", key, err)
+	}
+	return version, true, nil
+}
+
+// SetVersion records version as the last handled for key, then deletes the
+// expired versions when they were not deleted within the last hour.
+func (r *DedupRepository) SetVersion(ctx context.Context, key, version string) error {
+	if _, err := r.db.ExecContext(ctx, r.upsert, dedupKey(key), version); err != nil {
+		return fmt.Errorf("failed to set version of %q: %w", key, err)
+	}
+	if !r.pruneDue(time.Now()) {
+		return nil
+	}
+	_, err := r.Prune(ctx)
+	return err
+}
+
+// Prune deletes the versions handled longer than the retention ago and returns
+// how many were deleted.
+func (r *DedupRepository) Prune(ctx context.Context) (int64, error) {
+	if r.retention <= 0 {
+		return 0, nil
+	}
+	result, err := r.db.ExecContext(ctx, r.prune, int64(r.retention.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired versions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// pruneDue reports whether expired versions are due for deletion at now, and
+// if so records now as the last deletion.
+func (r *DedupRepository) pruneDue(now time.Time) bool {
+	if r.retention <= 0 {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.pruned) < pruneInterval {
+		return false
+	}
+	r.pruned = now
+	return true
+}
+
+func dedupKey(key string) []byte {
+	if len(key) > maxDedupKeyLength {
+		digest := sha256.Sum256([]byte(key))
+		return digest[:]
+	}
+	return []byte(key)
+}
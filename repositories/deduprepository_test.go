@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupRepository_Version(t *testing.T) {
+	testCases := []struct {
+		name            string
+		rows            *sqlmock.Rows
+		queryErr        error
+		expectedVersion string
+		expectedFound   bool
+		expectedErr     string
+	}{
+		{name: "recorded", rows: sqlmock.NewRows([]string{"version"}).AddRow("2024-05-01T12:00:00Z"), expectedVersion: "2024-05-01T12:00:00Z", expectedFound: true},
+		{name: "not recorded", queryErr: sql.ErrNoRows},
+		{name: "failure", queryErr: errors.New("connection lost"), expectedErr: `failed to get version of "user-topic/7": connection lost`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			db, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer db.Close()
+			repo, err := NewDedupRepository(sqlx.NewDb(db, "sqlmock"), "consumer_dedup", 0)
+			assert.NoError(t, err)
+			query := mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM `consumer_dedup` WHERE dedup_key = ?")).WithArgs([]byte("user-topic/7"))
+			if tc.rows != nil {
+				query.WillReturnRows(tc.rows)
+			} else {
+				query.WillReturnError(tc.queryErr)
+			}
+
+			// Act
+			version, found, err := repo.Version(context.Background(), "user-topic/7")
+
+			// Assert
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedVersion, version)
+			assert.Equal(t, tc.expectedFound, found)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDedupRepository_SetVersion(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	repo, err := NewDedupRepository(sqlx.NewDb(db, "sqlmock"), "consumer_dedup", 0)
+	assert.NoError(t, err)
+	longKey := "user-topic/" + strings.Repeat("k", 300)
+	digest := sha256.Sum256([]byte(longKey))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `consumer_dedup` (dedup_key, version, handled_at) VALUES (?, ?, CURRENT_TIMESTAMP) ON DUPLICATE KEY UPDATE version = VALUES(version), handled_at = CURRENT_TIMESTAMP")).
+		WithArgs(digest[:], "3").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	err = repo.SetVersion(context.Background(), longKey, "3")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDedupRepository_SetVersionPrunesExpiredVersions(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	repo, err := NewDedupRepository(sqlx.NewDb(db, "sqlmock"), "consumer_dedup", 48*time.Hour)
+	assert.NoError(t, err)
+	upsert := regexp.QuoteMeta("INSERT INTO `consumer_dedup`")
+	mock.ExpectExec(upsert).WithArgs([]byte("user-topic/1/a"), "a").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `consumer_dedup` WHERE handled_at < CURRENT_TIMESTAMP - INTERVAL ? SECOND")).
+		WithArgs(int64(48 * 60 * 60)).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectExec(upsert).WithArgs([]byte("user-topic/1/b"), "b").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Act
+	firstErr := repo.SetVersion(context.Background(), "user-topic/1/a", "a")
+	secondErr := repo.SetVersion(context.Background(), "user-topic/1/b", "b")
+
+	// Assert
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr) // pruned at most once per interval
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDedupRepository_PruneFailure(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+	repo, err := NewDedupRepository(sqlx.NewDb(db, "sqlmock"), "consumer_dedup", time.Hour)
+	assert.NoError(t, err)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `consumer_dedup`")).WillReturnError(errors.New("lock wait timeout"))
+
+	// Act
+	deleted, err := repo.Prune(context.Background())
+
+	// Assert
+	assert.EqualError(t, err, "failed to delete expired versions: lock wait timeout")
+	assert.Zero(t, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewDedupRepository_InvalidTable(t *testing.T) {
+	// Act
+	repo, err := NewDedupRepository(nil, "dedup; DROP TABLE users", 0)
+
+	// Assert
+	assert.EqualError(t, err, `invalid table name "dedup; DROP TABLE users"`)
+	assert.Nil(t, repo)
+}
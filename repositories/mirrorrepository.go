@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// identifier matches the table and column names a mirror may write.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// MirrorRepository writes the rows of a table mirrored from a topic. Each write
+// runs in its own transaction, which is committed when the write returns.
+type MirrorRepository struct {
+	db         *sqlx.DB
+	columns    []string
+	keyColumns []string
+	upsert     string
+	delete     string
+}
+
+// NewMirrorRepository returns a repository upserting columns of table by
+// keyColumns, which must be a primary or unique key of table.
+func NewMirrorRepository(db *sqlx.DB, table string, columns, keyColumns []string) (*MirrorRepository, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	if len(keyColumns) == 0 {
+		return nil, fmt.Errorf("table %s has no key columns", table)
+	}
+	for _, column := range columns {
+		if !identifier.MatchString(column) {
+			return nil, fmt.Errorf("invalid column name %q", column)
+		}
+	}
+	for _, column := range keyColumns {
+		if !slices.Contains(columns, column) {
+			return nil, fmt.Errorf("key column %s of table %s is not mapped", column, table)
+		}
+	}
+	columns = slices.Clone(columns)
+	sort.Strings(columns)
+
+	var names, placeholders, updates, conditions []string
+	for _, column := range columns {
+		names = append(names, quote(column))
+		placeholders = append(placeholders, "?")
+		if !slices.Contains(keyColumns, column) {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", quote(column), quote(column)))
+		}
+	}
+	if len(updates) == 0 {
+		// every column is part of the key, there is nothing to update
+		updates = append(updates, fmt.Sprintf("%s = %s", quote(keyColumns[0]), quote(keyColumns[0])))
+	}
+	for _, column := range keyColumns {
+		conditions = append(conditions, quote(column)+" = ?")
+	}
+
+	return &MirrorRepository{
+		db:         db,
+		columns:    columns,
+		keyColumns: keyColumns,
+		upsert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+			quote(table), strings.Join(names, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", ")),
+		delete: fmt.Sprintf("DELETE FROM %s WHERE %s", quote(table), strings.Join(conditions, " AND ")),
+	}, nil
+}
+
+// Upsert inserts the row, values by column, or updates the row with the same key.
+func (r *MirrorRepository) Upsert(ctx context.Context, row map[string]interface{}) error {
+	args := make([]interface{}, len(r.columns))
+	for i, column := range r.columns {
+		args[i] = row[column]
+	}
+	if err := r.exec(ctx, r.upsert, args); err != nil {
+		return fmt.Errorf("failed to upsert row: %w", err)
+	}
+	return nil
+}
+
+// Delete deletes the row whose key columns have the values of key.
+func (r *MirrorRepository) Delete(ctx context.Context, key map[string]interface{}) error {
+	args := make([]interface{}, len(r.keyColumns))
+	for i, column := range r.keyColumns {
+		value, ok := key[column]
+		if !ok {
+			return fmt.Errorf("failed to delete row: missing key column %s", column)
+		}
+		args[i] = value
+	}
+	if err := r.exec(ctx, r.delete, args); err != nil {
+		return fmt.Errorf("failed to delete row: %w", err)
+	}
+	return nil
+}
+
+// exec runs query in a transaction.
+func (r *MirrorRepository) exec(ctx context.Context, query string, args []interface{}) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func quote(name string) string {
+	return "`" + name + "`"
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirrorRepository_Upsert(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewMirrorRepository(sqlx.NewDb(db, "sqlmock"), "users_mirror", []string{"name", "id", "country_code"}, []string{"id"})
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users_mirror` (`country_code`, `id`, `name`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `country_code` = VALUES(`country_code`), `name` = VALUES(`name`)")).
+		WithArgs("NL", int64(1), "Ada").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// Act
+	err = repo.Upsert(context.Background(), map[string]interface{}{"id": int64(1), "name": "Ada", "country_code": "NL"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMirrorRepository_Delete(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewMirrorRepository(sqlx.NewDb(db, "sqlmock"), "memberships", []string{"user_id", "group_id"}, []string{"user_id", "group_id"})
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `memberships` WHERE `user_id` = ? AND `group_id` = ?")).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Act
+	err = repo.Delete(context.Background(), map[string]interface{}{"user_id": int64(1), "group_id": int64(2)})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMirrorRepository_RollsBackFailedWrites(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo, err := NewMirrorRepository(sqlx.NewDb(db, "sqlmock"), "users_mirror", []string{"id"}, []string{"id"})
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `users_mirror` (`id`) VALUES (?) ON DUPLICATE KEY UPDATE `id` = `id`")).
+		WithArgs(int64(1)).
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	// Act
+	err = repo.Upsert(context.Background(), map[string]interface{}{"id": int64(1)})
+
+	// Assert
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewMirrorRepository_Invalid(t *testing.T) {
+	testCases := []struct {
+		name        string
+		table       string
+		columns     []string
+		keyColumns  []string
+		expectedErr string
+	}{
+		{name: "table", table: "users; DROP TABLE users", columns: []string{"id"}, keyColumns: []string{"id"}, expectedErr: `invalid table name "users; DROP TABLE users"`},
+		{name: "column", table: "users", columns: []string{"id", "na-me"}, keyColumns: []string{"id"}, expectedErr: `invalid column name "na-me"`},
+		{name: "no key", table: "users", columns: []string{"id"}, expectedErr: "table users has no key columns"},
+		{name: "unmapped key", table: "users", columns: []string{"name"}, keyColumns: []string{"id"}, expectedErr: "key column id of table users is not mapped"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			repo, err := NewMirrorRepository(nil, tc.table, tc.columns, tc.keyColumns)
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Nil(t, repo)
+		})
+	}
+}
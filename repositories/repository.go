@@ -1,11 +1,19 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// Columns the task query must expose for replays to filter on.
+const (
+	IDColumn      = "id"
+	UpdatedColumn = "updated_at"
+)
+
 type Repository[T any] struct {
 	db    *sqlx.DB
 	query string
@@ -15,7 +23,38 @@ func NewRepository[T any](db *sqlx.DB, query string) *Repository[T] {
 	return &Repository[T]{db: db, query: query}
 }
 
-func (r *Repository[T]) Stream(since time.Time) (<-chan T, <-chan error) {
+// Stream streams the rows updated after since until ctx is done.
+func (r *Repository[T]) Stream(ctx context.Context, since time.Time) (<-chan T, <-chan error) {
+	return r.stream(ctx, r.query, since)
+}
+
+// StreamRange streams the rows updated after from and up to and including to.
+// The task query is wrapped so it still receives from as its only argument.
+func (r *Repository[T]) StreamRange(ctx context.Context, from, to time.Time) (<-chan T, <-chan error) {
+	query := fmt.Sprintf("SELECT * FROM (%s) AS q WHERE q.%s <= ?", r.query, UpdatedColumn)
+	return r.stream(ctx, query, from, to)
+}
+
+// MinUpdatedAt is passed to the task query to select rows of any update time. It
+// is the earliest MySQL DATETIME, so it stays valid under strict SQL modes.
+var MinUpdatedAt = time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// StreamIDs streams the rows with the given ids updated after MinUpdatedAt. The
+// task query still filters on its update time, so rows whose update time is NULL
+// are not streamed.
+func (r *Repository[T]) StreamIDs(ctx context.Context, ids []int64) (<-chan T, <-chan error) {
+	if len(ids) == 0 {
+		return r.fail(fmt.Errorf("no ids to stream"))
+	}
+
+	query, args, err := sqlx.In(fmt.Sprintf("SELECT * FROM (%s) AS q WHERE q.%s IN (?)", r.query, IDColumn), MinUpdatedAt, ids)
+	if err != nil {
+		return r.fail(err)
+	}
+	return r.stream(ctx, query, args...)
+}
+
+func (r *Repository[T]) stream(ctx context.Context, query string, args ...interface{}) (<-chan T, <-chan error) {
 	out := make(chan T)
 	errs := make(chan error, 1)
 
@@ -23,7 +62,7 @@ func (r *Repository[T]) Stream(since time.Time) (<-chan T, <-chan error) {
 		defer close(out)
 		defer close(errs)
 
-		rows, err := r.db.Queryx(r.query, since)
+		rows, err := r.db.QueryxContext(ctx, query, args...)
 		if err != nil {
 			errs <- err
 			return
@@ -36,7 +75,12 @@ func (r *Repository[T]) Stream(since time.Time) (<-chan T, <-chan error) {
 				errs <- err
 				return
 			}
-			out <- item
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
 		}
 
 		if err := rows.Err(); err != nil {
@@ -46,3 +90,13 @@ func (r *Repository[T]) Stream(since time.Time) (<-chan T, <-chan error) {
 
 	return out, errs
 }
+
+// fail returns closed channels carrying only err.
+func (r *Repository[T]) fail(err error) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errs := make(chan error, 1)
+	errs <- err
+	close(out)
+	close(errs)
+	return out, errs
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+	"regexp"
 	"testing"
 	"time"
 
@@ -31,7 +33,7 @@ func TestStream_Success(t *testing.T) {
 
 	// Act
 	since := time.Now().Add(-24 * time.Hour)
-	out, errs := repo.Stream(since)
+	out, errs := repo.Stream(context.Background(), since)
 	var results []TestModel
 	for item := range out {
 		results = append(results, item)
@@ -59,7 +61,7 @@ func TestStream_QueryError(t *testing.T) {
 
 	// Act
 	since := time.Now().Add(-24 * time.Hour)
-	out, errs := repo.Stream(since)
+	out, errs := repo.Stream(context.Background(), since)
 
 	// Assert
 	_, ok := <-out
@@ -85,7 +87,7 @@ func TestStream_ScanError(t *testing.T) {
 
 	// Act
 	since := time.Now().Add(-24 * time.Hour)
-	out, errs := repo.Stream(since)
+	out, errs := repo.Stream(context.Background(), since)
 
 	// Assert
 	_, ok := <-out
@@ -93,3 +95,110 @@ func TestStream_ScanError(t *testing.T) {
 	assert.Error(t, <-errs)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestStreamRange_Success(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	query := "SELECT id, name FROM test_table WHERE updated_at > ?"
+	repo := NewRepository[TestModel](sqlxDB, query)
+	rows := sqlmock.NewRows([]string{"id", "name"}).
+		AddRow(1, "Alice")
+
+	from := time.Now().Add(-48 * time.Hour)
+	to := time.Now().Add(-24 * time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM ("+query+") AS q WHERE q.updated_at <= ?")).
+		WithArgs(from, to).
+		WillReturnRows(rows)
+
+	// Act
+	out, errs := repo.StreamRange(context.Background(), from, to)
+	var results []TestModel
+	for item := range out {
+		results = append(results, item)
+	}
+
+	// Assert
+	assert.NoError(t, <-errs)
+	assert.Equal(t, []TestModel{{ID: 1, Name: "Alice"}}, results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamIDs_Success(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	query := "SELECT id, name FROM test_table WHERE updated_at > ?"
+	repo := NewRepository[TestModel](sqlxDB, query)
+	rows := sqlmock.NewRows([]string{"id", "name"}).
+		AddRow(1, "Alice").
+		AddRow(3, "Carol")
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM ("+query+") AS q WHERE q.id IN (?, ?)")).
+		WithArgs(MinUpdatedAt, int64(1), int64(3)).
+		WillReturnRows(rows)
+
+	// Act
+	out, errs := repo.StreamIDs(context.Background(), []int64{1, 3})
+	var results []TestModel
+	for item := range out {
+		results = append(results, item)
+	}
+
+	// Assert
+	assert.NoError(t, <-errs)
+	assert.Len(t, results, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamIDs_Empty(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository[TestModel](sqlx.NewDb(db, "sqlmock"), "SELECT 1")
+
+	// Act
+	out, errs := repo.StreamIDs(context.Background(), nil)
+
+	// Assert
+	_, ok := <-out
+	assert.False(t, ok)
+	assert.Error(t, <-errs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStream_Cancelled(t *testing.T) {
+	// Arrange
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+	query := "SELECT id, name FROM test_table WHERE updated_at > ?"
+	repo := NewRepository[TestModel](sqlxDB, query)
+	rows := sqlmock.NewRows([]string{"id", "name"}).
+		AddRow(1, "Alice").
+		AddRow(2, "Bob")
+
+	mock.ExpectQuery(query).WithArgs(sqlmock.AnyArg()).WillReturnRows(rows)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act: stop reading after the first row
+	out, errs := repo.Stream(ctx, time.Now())
+	<-out
+	cancel()
+
+	// Assert: the stream stops instead of blocking on the unread row
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	_, ok := <-out
+	assert.False(t, ok)
+}
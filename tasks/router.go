@@ -0,0 +1,168 @@
+package tasks
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"kafka-go-example/infra/config"
+)
+
+// topicPlaceholder matches field references in a route topic, e.g. "{country.code}".
+var topicPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// validTopic matches the topic names Kafka accepts.
+var validTopic = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,249}$`)
+
+// validateRoutes checks every route has a field and a topic, and that the field
+// and the topic placeholders resolve on model.
+func validateRoutes(routes []config.RouteConfig, model reflect.Type) error {
+	for i, route := range routes {
+		if route.Field == "" {
+			return fmt.Errorf("route %d: field is required", i)
+		}
+		if route.Topic == "" {
+			return fmt.Errorf("route %d: topic is required", i)
+		}
+		if _, err := resolveFieldType(model, route.Field); err != nil {
+			return fmt.Errorf("route %d: %w", i, err)
+		}
+		for _, match := range topicPlaceholder.FindAllStringSubmatch(route.Topic, -1) {
+			if _, err := resolveFieldType(model, match[1]); err != nil {
+				return fmt.Errorf("route %d: %w", i, err)
+			}
+		}
+		// placeholders expand to at least one character, checked when publishing
+		if literal := topicPlaceholder.ReplaceAllString(route.Topic, "x"); !validTopic.MatchString(literal) {
+			return fmt.Errorf("route %d: invalid topic %q, topics are 1 to 249 of [a-zA-Z0-9._-]", i, route.Topic)
+		}
+	}
+	return nil
+}
+
+// resolveRoute returns the topic and schema for item from the first matching route,
+// falling back to the task topic and schema when no route matches.
+func resolveRoute(cfg config.TaskConfig, item interface{}) (string, string, error) {
+	for _, route := range cfg.Routes {
+		value, err := fieldValue(item, route.Field)
+		if err != nil {
+			return "", "", err
+		}
+		if route.Value != "" && route.Value != value {
+			continue
+		}
+
+		topic, err := expandTopic(route.Topic, item)
+		if err != nil {
+			return "", "", err
+		}
+
+		schema := cfg.Schema
+		if route.Schema != "" {
+			schema = route.Schema
+		}
+		return topic, schema, nil
+	}
+
+	return cfg.Topic, cfg.Schema, nil
+}
+
+// expandTopic replaces field references in topic with the item's values. Empty
+// values and topics Kafka does not accept are errors.
+func expandTopic(topic string, item interface{}) (string, error) {
+	var err error
+	expanded := topicPlaceholder.ReplaceAllStringFunc(topic, func(match string) string {
+		path := match[1 : len(match)-1]
+		value, fieldErr := fieldValue(item, path)
+		if fieldErr == nil && value == "" {
+			fieldErr = fmt.Errorf("topic %s: field %q is empty", topic, path)
+		}
+		if fieldErr != nil && err == nil {
+			err = fieldErr
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	if !validTopic.MatchString(expanded) {
+		return "", fmt.Errorf("topic %s: invalid topic %q, topics are 1 to 249 of [a-zA-Z0-9._-]", topic, expanded)
+	}
+	return expanded, nil
+}
+
+// fieldValue returns the string value of a dotted field path. Each segment is
+// matched against the avro tag, then the db tag, then the field name.
+func fieldValue(item interface{}, path string) (string, error) {
+	v, err := FieldRef(item, path)
+	if err != nil || !v.IsValid() {
+		return "", err
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339), nil
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// FieldRef returns the value of a dotted field path, or the zero Value when a
+// pointer on the path is nil. Segments are matched as in fieldValue.
+func FieldRef(item interface{}, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(item)
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("cannot resolve field %q: %s is not a struct", path, v.Type())
+		}
+
+		field, ok := findField(v.Type(), name)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("cannot resolve field %q: %s has no field %q", path, v.Type(), name)
+		}
+		v = v.FieldByIndex(field.Index)
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, nil
+		}
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+// resolveFieldType returns the type of a dotted field path on the type t, segments
+// matched as in FieldRef.
+func resolveFieldType(t reflect.Type, path string) (reflect.Type, error) {
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("cannot resolve field %q: %s is not a struct", path, t)
+		}
+		field, ok := findField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("cannot resolve field %q: %s has no field %q", path, t, name)
+		}
+		t = field.Type
+	}
+	return t, nil
+}
+
+func findField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for _, tag := range []string{"avro", "db"} {
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); strings.Split(f.Tag.Get(tag), ",")[0] == name {
+				return f, true
+			}
+		}
+	}
+	return t.FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, name) })
+}
@@ -0,0 +1,176 @@
+package tasks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"kafka-go-example/infra/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type routedCountry struct {
+	Code string `avro:"code"`
+}
+
+type routedModel struct {
+	ID        int64         `db:"id" avro:"user_id"`
+	Status    string        `db:"status" avro:"status"`
+	Country   routedCountry `avro:"country"`
+	Manager   *routedModel  `avro:"manager"`
+	UpdatedAt time.Time     `db:"updated_at"`
+}
+
+func TestResolveRoute(t *testing.T) {
+	cfg := config.TaskConfig{
+		Topic:  "user-topic",
+		Schema: "user-schema",
+		Routes: []config.RouteConfig{
+			{Field: "status", Value: "active", Topic: "user-active"},
+			{Field: "country.code", Topic: "user-{country.code}", Schema: "user-country-schema"},
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		item   routedModel
+		topic  string
+		schema string
+	}{
+		{
+			name:   "matching value",
+			item:   routedModel{Status: "active", Country: routedCountry{Code: "USA"}},
+			topic:  "user-active",
+			schema: "user-schema",
+		},
+		{
+			name:   "template route",
+			item:   routedModel{Status: "inactive", Country: routedCountry{Code: "GBR"}},
+			topic:  "user-GBR",
+			schema: "user-country-schema",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			topic, schema, err := resolveRoute(cfg, &tc.item)
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, tc.topic, topic)
+			assert.Equal(t, tc.schema, schema)
+		})
+	}
+}
+
+func TestResolveRoute_Default(t *testing.T) {
+	// Arrange
+	cfg := config.TaskConfig{
+		Topic:  "user-inactive",
+		Schema: "user-schema",
+		Routes: []config.RouteConfig{{Field: "status", Value: "active", Topic: "user-active"}},
+	}
+
+	// Act
+	topic, schema, err := resolveRoute(cfg, &routedModel{Status: "inactive"})
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, "user-inactive", topic)
+	assert.Equal(t, "user-schema", schema)
+}
+
+func TestResolveRoute_UnknownField(t *testing.T) {
+	// Arrange
+	cfg := config.TaskConfig{
+		Topic:  "user-topic",
+		Routes: []config.RouteConfig{{Field: "country.name", Topic: "user-{country.name}"}},
+	}
+
+	// Act
+	_, _, err := resolveRoute(cfg, &routedModel{})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `has no field "name"`)
+}
+
+func TestFieldValue(t *testing.T) {
+	updatedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	item := routedModel{ID: 42, Status: "active", UpdatedAt: updatedAt}
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "user_id", expected: "42"},      // avro tag
+		{path: "id", expected: "42"},           // db tag
+		{path: "Status", expected: "active"},   // field name
+		{path: "manager.status", expected: ""}, // nil pointer
+		{path: "updated_at", expected: "2025-01-02T03:04:05Z"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			value, err := fieldValue(&item, tc.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, value)
+		})
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	model := reflect.TypeOf(routedModel{})
+	testCases := []struct {
+		name        string
+		route       config.RouteConfig
+		expectedErr string
+	}{
+		{name: "static topic", route: config.RouteConfig{Field: "status", Topic: "user-active"}},
+		{name: "template topic", route: config.RouteConfig{Field: "manager.country.code", Topic: "user-{country.code}"}},
+		{name: "no field", route: config.RouteConfig{Topic: "user-active"}, expectedErr: "route 0: field is required"},
+		{name: "no topic", route: config.RouteConfig{Field: "status"}, expectedErr: "route 0: topic is required"},
+		{name: "unknown field", route: config.RouteConfig{Field: "state", Topic: "user-active"}, expectedErr: `route 0: cannot resolve field "state": tasks.routedModel has no field "state"`},
+		{name: "unknown placeholder", route: config.RouteConfig{Field: "status", Topic: "user-{country.cod}"}, expectedErr: `route 0: cannot resolve field "country.cod": tasks.routedCountry has no field "cod"`},
+		{name: "invalid topic", route: config.RouteConfig{Field: "status", Topic: "user {status}"}, expectedErr: `route 0: invalid topic "user {status}", topics are 1 to 249 of [a-zA-Z0-9._-]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			err := validateRoutes([]config.RouteConfig{tc.route}, model)
+
+			// Assert
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestExpandTopic_InvalidTopics(t *testing.T) {
+	testCases := []struct {
+		name        string
+		topic       string
+		item        routedModel
+		expectedErr string
+	}{
+		{name: "empty value", topic: "user-{manager.status}", expectedErr: `topic user-{manager.status}: field "manager.status" is empty`},
+		{name: "invalid characters", topic: "user-{updated_at}", item: routedModel{UpdatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}, expectedErr: `topic user-{updated_at}: invalid topic "user-2025-01-02T03:04:05Z", topics are 1 to 249 of [a-zA-Z0-9._-]`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			topic, err := expandTopic(tc.topic, &tc.item)
+
+			// Assert
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Empty(t, topic)
+		})
+	}
+}
@@ -19,17 +19,28 @@ func NewTaskRunner(task TaskInterface, interval time.Duration) *TaskRunner {
 }
 
 func (r *TaskRunner) Run(ctx context.Context) {
+	r.RunWithDrain(ctx, ctx)
+}
+
+// RunWithDrain schedules executions until stop is done. A running execution
+// is given work instead, so it can complete and checkpoint after stop is done.
+func (r *TaskRunner) RunWithDrain(stop, work context.Context) {
 	ticker := time.NewTicker(r.Interval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ctx.Done():
+		case <-stop.Done():
 			log.Printf("Stopping task runner")
 			return
 		case <-ticker.C:
+			// select picks randomly when stop and the ticker are both ready
+			if stop.Err() != nil {
+				log.Printf("Stopping task runner")
+				return
+			}
 			log.Printf("Executing task")
-			r.Task.Execute()
+			r.Task.Execute(work)
 		}
 	}
 }
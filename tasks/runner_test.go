@@ -17,13 +17,18 @@ type MockTask struct {
 	mutex          sync.Mutex
 }
 
-func (m *MockTask) Execute() {
+func (m *MockTask) Execute(ctx context.Context) {
 	m.Called()
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 	m.executionCount++
 }
 
+func (m *MockTask) Replay(ctx context.Context, opts ReplayOptions) error {
+	args := m.Called(ctx, opts)
+	return args.Error(0)
+}
+
 func (m *MockTask) GetExecutionCount() int {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -149,3 +154,62 @@ func TestTaskRunner_ExecutesOnTicker(t *testing.T) {
 	assert.GreaterOrEqual(t, mockTask.GetExecutionCount(), 1)
 	mockTask.AssertNumberOfCalls(t, "Execute", mockTask.GetExecutionCount())
 }
+
+// slowTask blocks in Execute until released and records its context error.
+type slowTask struct {
+	started  chan struct{}
+	release  chan struct{}
+	finished chan error
+}
+
+func (s *slowTask) Execute(ctx context.Context) {
+	close(s.started)
+	<-s.release
+	s.finished <- ctx.Err()
+}
+
+func (s *slowTask) Replay(ctx context.Context, opts ReplayOptions) error {
+	return nil
+}
+
+func TestTaskRunner_RunWithDrainCompletesRunningExecution(t *testing.T) {
+	// Arrange
+	task := &slowTask{started: make(chan struct{}), release: make(chan struct{}), finished: make(chan error, 1)}
+	runner := NewTaskRunner(task, time.Millisecond*10)
+	stop, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		runner.RunWithDrain(stop, context.Background())
+		close(done)
+	}()
+	<-task.started
+
+	// Act
+	cancel()
+	close(task.release)
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Task runner did not exit after the running execution completed")
+	}
+	assert.NoError(t, <-task.finished) // the execution was not cancelled by stop
+}
+
+func TestTaskRunner_RunWithDrainDoesNotExecuteAfterStop(t *testing.T) {
+	// Arrange
+	mockTask := new(MockTask)
+	mockTask.On("Execute").Return()
+	stop, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act: the ticker is ready as soon as the runner selects, alongside stop
+	for range 100 {
+		NewTaskRunner(mockTask, time.Nanosecond).RunWithDrain(stop, context.Background())
+	}
+
+	// Assert
+	mockTask.AssertNotCalled(t, "Execute")
+}
@@ -1,19 +1,26 @@
 package tasks
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"reflect"
 	"time"
 
+	"kafka-go-example/infra/avro"
 	"kafka-go-example/infra/config"
+	"kafka-go-example/infra/serde"
 	"kafka-go-example/repositories"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type RepositoryInterface[T any] interface {
-	Stream(since time.Time) (<-chan T, <-chan error)
+	Stream(ctx context.Context, since time.Time) (<-chan T, <-chan error)
+	StreamRange(ctx context.Context, from, to time.Time) (<-chan T, <-chan error)
+	StreamIDs(ctx context.Context, ids []int64) (<-chan T, <-chan error)
 }
 
 type SyncRepositoryInterface interface {
@@ -21,15 +28,40 @@ type SyncRepositoryInterface interface {
 	Set(task string, syncedAt time.Time) error
 }
 
+// SerializerInterface serializes data with the schema registered for subject.
 type SerializerInterface interface {
-	Serialize(schema string, data interface{}) ([]byte, error)
+	Serialize(subject string, data interface{}) ([]byte, error)
 }
 
 type ProducerInterface interface {
-	ProduceMessage(topic string, payload []byte, key []byte) error
+	ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error
 	Close()
 }
 
+// RetriableInterface is implemented by produce errors that report whether producing
+// the message again may succeed, such as kafka.DeliveryError.
+type RetriableInterface interface {
+	Retriable() bool
+}
+
+// ReplayHeader marks messages republished by Replay.
+const ReplayHeader = "replay"
+
+// ReplayOptions selects the rows to republish and where to send them.
+// IDs take precedence over the From/To window.
+type ReplayOptions struct {
+	From  time.Time
+	To    time.Time
+	IDs   []int64
+	Topic string // overrides the task topic when set
+}
+
+// FlusherInterface is implemented by producers that buffer messages,
+// so a task can make sure they are delivered before advancing its checkpoint.
+type FlusherInterface interface {
+	Flush(ctx context.Context) error
+}
+
 type Task[T any] struct {
 	Config     config.TaskConfig
 	Repository RepositoryInterface[T]
@@ -44,6 +76,15 @@ func NewTask[T any](db *sqlx.DB, config config.TaskConfig, serializer Serializer
 		return nil, err
 	}
 
+	model := reflect.TypeOf((*T)(nil)).Elem()
+	if err := validateRoutes(config.Routes, model); err != nil {
+		return nil, fmt.Errorf("invalid routes for task %s: %w", config.Name, err)
+	}
+
+	if err := validateSubjects(config, model); err != nil {
+		return nil, fmt.Errorf("invalid subjects for task %s: %w", config.Name, err)
+	}
+
 	syncRepo := repositories.NewSyncRepository(db)
 	repo := repositories.NewRepository[T](db, query)
 
@@ -56,44 +97,203 @@ func NewTask[T any](db *sqlx.DB, config config.TaskConfig, serializer Serializer
 	}, nil
 }
 
-func (t *Task[T]) Execute() {
-	const TimeDelay = 24 * time.Hour
+// Execute publishes the rows updated since the last run and advances the task checkpoint.
+// The checkpoint is kept when ctx is done or a message fails with a retriable error,
+// so the next run publishes the same rows again.
+func (t *Task[T]) Execute(ctx context.Context) {
 	syncedAt, err := t.SyncRepo.Get(t.Config.Name)
 	if err != nil {
 		log.Printf("Failed to get last sync time: %v", err)
 		return
 	}
 
-	data, errs := t.Repository.Stream(syncedAt)
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	data, errs := t.Repository.Stream(streamCtx, syncedAt)
 	syncedAt = time.Now()
 
+	_, err = t.publish(ctx, data, "", nil)
+	if err != nil {
+		cancel()
+		<-errs
+		log.Printf("Task <%s> aborted, sync time not updated: %v", t.Config.Name, err)
+		return
+	}
+
+	if err, ok := <-errs; ok && err != nil {
+		log.Printf("Error streaming data: %v", err)
+	}
+
+	if err := t.flush(ctx); err != nil {
+		log.Printf("Failed to flush producer: %v", err)
+		return
+	}
+
+	if t.Config.DryRun {
+		log.Printf("Task <%s> completed in dry-run mode, sync time not updated", t.Config.Name)
+		return
+	}
+
+	err = t.SyncRepo.Set(t.Config.Name, syncedAt)
+	if err != nil {
+		log.Printf("Failed to set sync time: %v", err)
+		return
+	}
+
+	log.Printf("Task <%s> completed", t.Config.Name)
+}
+
+// Replay republishes the rows selected by opts without touching the task checkpoint.
+func (t *Task[T]) Replay(ctx context.Context, opts ReplayOptions) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var data <-chan T
+	var errs <-chan error
+	if len(opts.IDs) > 0 {
+		data, errs = t.Repository.StreamIDs(streamCtx, opts.IDs)
+	} else {
+		data, errs = t.Repository.StreamRange(streamCtx, opts.From, opts.To)
+	}
+
+	count, err := t.publish(ctx, data, opts.Topic, map[string]string{ReplayHeader: "true"})
+	if err != nil {
+		cancel()
+		<-errs
+		return fmt.Errorf("replay aborted after %d messages: %w", count, err)
+	}
+
+	if err, ok := <-errs; ok && err != nil {
+		return fmt.Errorf("failed to stream replay data: %w", err)
+	}
+
+	if err := t.flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush producer: %w", err)
+	}
+
+	log.Printf("Task <%s> replayed %d messages", t.Config.Name, count)
+	return nil
+}
+
+// publish serializes and produces every item from data, returning the number of messages produced.
+// Items are routed by the task routes unless topic overrides them. Messages failing permanently
+// are skipped; publishing stops when ctx is done or a message fails with a retriable error.
+func (t *Task[T]) publish(ctx context.Context, data <-chan T, topic string, headers map[string]string) (int, error) {
+	count := 0
 	for item := range data {
-		payload, err := t.Serializer.Serialize(t.Config.Schema, &item)
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		itemTopic, schema := topic, t.Config.Schema
+		if topic == "" {
+			var err error
+			itemTopic, schema, err = resolveRoute(t.Config, &item)
+			if err != nil {
+				log.Printf("Failed to route data: %v", err)
+				continue
+			}
+		}
+
+		subject, err := avro.Subject(t.Config.SubjectStrategy, itemTopic, schema, false)
+		if err != nil {
+			log.Printf("Failed to serialize data: %v", err)
+			continue
+		}
+		payload, err := t.Serializer.Serialize(subject, &item)
 		if err != nil {
 			log.Printf("Failed to serialize data: %v", err)
 			continue
 		}
 
-		err = t.Producer.ProduceMessage(t.Config.Topic, payload, nil)
+		key, err := t.serializeKey(itemTopic, &item)
 		if err != nil {
+			log.Printf("Failed to serialize key: %v", err)
+			continue
+		}
+
+		err = t.Producer.ProduceMessage(ctx, itemTopic, payload, key, headers)
+		if err != nil {
+			if isRetriable(err) || ctx.Err() != nil {
+				return count, err
+			}
 			log.Printf("Failed to produce message: %v", err)
 			continue
 		}
 
-		log.Printf("Message produced for topic: %s", t.Config.Topic)
+		log.Printf("Message produced for topic: %s", itemTopic)
+		count++
 	}
+	return count, ctx.Err()
+}
 
-	if err, ok := <-errs; ok && err != nil {
-		log.Printf("Error streaming data: %v", err)
+// serializeKey serializes the key field of item with the key subject of topic.
+// It returns a nil key when the task has no key field.
+func (t *Task[T]) serializeKey(topic string, item interface{}) ([]byte, error) {
+	if t.Config.Key.Field == "" {
+		return nil, nil
+	}
+	value, err := FieldRef(item, t.Config.Key.Field)
+	if err != nil {
+		return nil, err
+	}
+	if !value.IsValid() {
+		return nil, fmt.Errorf("key field %q is nil", t.Config.Key.Field)
 	}
 
-	err = t.SyncRepo.Set(t.Config.Name, syncedAt)
+	schema, err := avro.KeySchema(t.Config)
 	if err != nil {
-		log.Printf("Failed to set sync time: %v", err)
-		return
+		return nil, err
 	}
+	subject, err := avro.Subject(t.Config.SubjectStrategy, topic, schema, true)
+	if err != nil {
+		return nil, err
+	}
+	// Serializers take a pointer, as they do for models
+	key := reflect.New(value.Type())
+	key.Elem().Set(value)
+	return t.Serializer.Serialize(subject, key.Interface())
+}
 
-	log.Printf("Task <%s> completed", t.Config.Name)
+// flush flushes the producer if it buffers messages.
+func (t *Task[T]) flush(ctx context.Context) error {
+	if f, ok := t.Producer.(FlusherInterface); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+func isRetriable(err error) bool {
+	var retriable RetriableInterface
+	return errors.As(err, &retriable) && retriable.Retriable()
+}
+
+// validateSubjects checks the subject strategy, the key schema it requires and
+// that the key field resolves on model. Protobuf keys must be messages, so the
+// key field of a Protobuf task must be a struct.
+func validateSubjects(cfg config.TaskConfig, model reflect.Type) error {
+	if _, err := avro.Subject(cfg.SubjectStrategy, cfg.Topic, cfg.Schema, false); err != nil {
+		return err
+	}
+	if cfg.Key.Field == "" {
+		return nil
+	}
+	if _, err := avro.KeySchema(cfg); err != nil {
+		return err
+	}
+
+	key, err := resolveFieldType(model, cfg.Key.Field)
+	if err != nil {
+		return fmt.Errorf("key: %w", err)
+	}
+	for key.Kind() == reflect.Pointer {
+		key = key.Elem()
+	}
+	if cfg.Format == serde.FormatProtobuf && key.Kind() != reflect.Struct {
+		return fmt.Errorf("key field %q is %s, protobuf keys must be messages: key by a struct field", cfg.Key.Field, key)
+	}
+	return nil
 }
 
 func loadQueryFromFile(filePath string) (string, error) {
@@ -2,6 +2,7 @@ package tasks
 
 import (
 	"fmt"
+	"reflect"
 
 	"kafka-go-example/infra/config"
 	"kafka-go-example/models"
@@ -19,3 +20,14 @@ func CreateTask(db *sqlx.DB, cfg config.TaskConfig, serializer SerializerInterfa
 		return nil, fmt.Errorf("unsupported task name: %s", cfg.Name)
 	}
 }
+
+// TaskModel returns the model type CreateTask uses for a task name.
+func TaskModel(name string) (reflect.Type, error) {
+	switch name {
+	case "user":
+		return reflect.TypeOf(models.User{}), nil
+	// Add cases for other models here, matching CreateTask
+	default:
+		return nil, fmt.Errorf("unsupported task name: %s", name)
+	}
+}
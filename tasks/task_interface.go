@@ -1,6 +1,9 @@
 package tasks
 
+import "context"
+
 // TaskInterface defines the behavior of a task.
 type TaskInterface interface {
-	Execute()
+	Execute(ctx context.Context)
+	Replay(ctx context.Context, opts ReplayOptions) error
 }
@@ -1,7 +1,9 @@
 package tasks
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
 	"kafka-go-example/infra/config"
 	"os"
@@ -18,8 +20,18 @@ type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) Stream(since time.Time) (<-chan TestModel, <-chan error) {
-	args := m.Called(since)
+func (m *MockRepository) Stream(ctx context.Context, since time.Time) (<-chan TestModel, <-chan error) {
+	args := m.Called(ctx, since)
+	return args.Get(0).(chan TestModel), args.Get(1).(chan error)
+}
+
+func (m *MockRepository) StreamRange(ctx context.Context, from, to time.Time) (<-chan TestModel, <-chan error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).(chan TestModel), args.Get(1).(chan error)
+}
+
+func (m *MockRepository) StreamIDs(ctx context.Context, ids []int64) (<-chan TestModel, <-chan error) {
+	args := m.Called(ctx, ids)
 	return args.Get(0).(chan TestModel), args.Get(1).(chan error)
 }
 
@@ -41,8 +53,8 @@ type MockSerializer struct {
 	mock.Mock
 }
 
-func (m *MockSerializer) Serialize(schema string, data interface{}) ([]byte, error) {
-	args := m.Called(schema, data)
+func (m *MockSerializer) Serialize(subject string, data interface{}) ([]byte, error) {
+	args := m.Called(subject, data)
 	return args.Get(0).([]byte), args.Error(1)
 }
 
@@ -50,8 +62,8 @@ type MockProducer struct {
 	mock.Mock
 }
 
-func (m *MockProducer) ProduceMessage(topic string, payload []byte, key []byte) error {
-	args := m.Called(topic, payload, key)
+func (m *MockProducer) ProduceMessage(ctx context.Context, topic string, payload []byte, key []byte, headers map[string]string) error {
+	args := m.Called(ctx, topic, payload, key, headers)
 	return args.Error(0)
 }
 
@@ -59,6 +71,16 @@ func (m *MockProducer) Close() {
 	m.Called()
 }
 
+// MockBufferedProducer is a producer that buffers messages until flushed.
+type MockBufferedProducer struct {
+	MockProducer
+}
+
+func (m *MockBufferedProducer) Flush(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 type TestModel struct {
 	ID   int    `db:"id"`
 	Name string `db:"name"`
@@ -145,13 +167,13 @@ func TestExecute_Success(t *testing.T) {
 	testItem1 := TestModel{ID: 1, Name: "Test 1"}
 	testItem2 := TestModel{ID: 2, Name: "Test 2"}
 
-	mockRepo.On("Stream", mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
 	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
 	mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem1).Return([]byte("serialized1"), nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem2).Return([]byte("serialized2"), nil)
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized1"), mock.Anything).Return(nil)
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized2"), mock.Anything).Return(nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem1).Return([]byte("serialized1"), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem2).Return([]byte("serialized2"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized2"), mock.Anything, mock.Anything).Return(nil)
 
 	task := &Task[TestModel]{
 		Config: config.TaskConfig{
@@ -173,7 +195,7 @@ func TestExecute_Success(t *testing.T) {
 		close(errChan)
 	}()
 
-	task.Execute()
+	task.Execute(context.Background())
 
 	// Assert
 	mockRepo.AssertExpectations(t)
@@ -195,12 +217,12 @@ func TestExecute_SerializeError(t *testing.T) {
 	testItem1 := TestModel{ID: 1, Name: "Test 1"}
 	testItem2 := TestModel{ID: 2, Name: "Test 2"}
 
-	mockRepo.On("Stream", mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
 	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
 	mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem1).Return([]byte{}, errors.New("serialize error"))
-	mockSerializer.On("Serialize", "test-schema", &testItem2).Return([]byte("serialized2"), nil)
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized2"), mock.Anything).Return(nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem1).Return([]byte{}, errors.New("serialize error"))
+	mockSerializer.On("Serialize", "test-schema-value", &testItem2).Return([]byte("serialized2"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized2"), mock.Anything, mock.Anything).Return(nil)
 
 	task := &Task[TestModel]{
 		Config: config.TaskConfig{
@@ -222,7 +244,7 @@ func TestExecute_SerializeError(t *testing.T) {
 		close(errChan)
 	}()
 
-	task.Execute()
+	task.Execute(context.Background())
 
 	// Assert
 	mockRepo.AssertExpectations(t)
@@ -244,13 +266,13 @@ func TestExecute_ProduceError(t *testing.T) {
 	testItem1 := TestModel{ID: 1, Name: "Test 1"}
 	testItem2 := TestModel{ID: 2, Name: "Test 2"}
 
-	mockRepo.On("Stream", mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
 	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
 	mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem1).Return([]byte("serialized1"), nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem2).Return([]byte("serialized2"), nil)
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized1"), mock.Anything).Return(errors.New("produce error"))
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized2"), mock.Anything).Return(nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem1).Return([]byte("serialized1"), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem2).Return([]byte("serialized2"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(errors.New("produce error"))
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized2"), mock.Anything, mock.Anything).Return(nil)
 
 	task := &Task[TestModel]{
 		Config: config.TaskConfig{
@@ -272,7 +294,7 @@ func TestExecute_ProduceError(t *testing.T) {
 		close(errChan)
 	}()
 
-	task.Execute()
+	task.Execute(context.Background())
 
 	// Assert
 	mockRepo.AssertExpectations(t)
@@ -291,7 +313,7 @@ func TestExecute_StreamError(t *testing.T) {
 	dataChan := make(chan TestModel)
 	errChan := make(chan error, 1)
 
-	mockRepo.On("Stream", mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
 	mockSyncRepo.On("Get", "test").Return(time.Now(), nil)
 	mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
 
@@ -314,7 +336,7 @@ func TestExecute_StreamError(t *testing.T) {
 		close(errChan)
 	}()
 
-	task.Execute()
+	task.Execute(context.Background())
 
 	// Assert
 	mockRepo.AssertExpectations(t)
@@ -424,13 +446,13 @@ func TestExecute_SyncRepoIntegration(t *testing.T) {
 	testItem1 := TestModel{ID: 1, Name: "Test 1"}
 	testItem2 := TestModel{ID: 2, Name: "Test 2"}
 
-	mockRepo.On("Stream", mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
 	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
 	mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem1).Return([]byte("serialized1"), nil)
-	mockSerializer.On("Serialize", "test-schema", &testItem2).Return([]byte("serialized2"), nil)
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized1"), mock.Anything).Return(nil)
-	mockProducer.On("ProduceMessage", "test-topic", []byte("serialized2"), mock.Anything).Return(nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem1).Return([]byte("serialized1"), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem2).Return([]byte("serialized2"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized2"), mock.Anything, mock.Anything).Return(nil)
 
 	task := &Task[TestModel]{
 		Config: config.TaskConfig{
@@ -452,7 +474,7 @@ func TestExecute_SyncRepoIntegration(t *testing.T) {
 		close(errChan)
 	}()
 
-	task.Execute()
+	task.Execute(context.Background())
 
 	// Assert
 	mockRepo.AssertExpectations(t)
@@ -460,3 +482,462 @@ func TestExecute_SyncRepoIntegration(t *testing.T) {
 	mockSerializer.AssertExpectations(t)
 	mockProducer.AssertExpectations(t)
 }
+
+func TestReplay_Window(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSyncRepo := new(MockSyncRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockProducer)
+
+	dataChan := make(chan TestModel, 1)
+	errChan := make(chan error, 1)
+
+	testItem := TestModel{ID: 1, Name: "Test 1"}
+	from := time.Now().Add(-2 * time.Hour)
+	to := time.Now().Add(-time.Hour)
+
+	mockRepo.On("StreamRange", mock.Anything, from, to).Return(dataChan, errChan)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem).Return([]byte("serialized1"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, map[string]string{ReplayHeader: "true"}).Return(nil)
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+		},
+		Repository: mockRepo,
+		SyncRepo:   mockSyncRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	dataChan <- testItem
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	err := task.Replay(context.Background(), ReplayOptions{From: from, To: to})
+
+	// Assert
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockSerializer.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+	mockSyncRepo.AssertNotCalled(t, "Get", mock.Anything)
+	mockSyncRepo.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+func TestReplay_IDsWithTopicOverride(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockProducer)
+
+	dataChan := make(chan TestModel, 1)
+	errChan := make(chan error, 1)
+
+	testItem := TestModel{ID: 7, Name: "Test 7"}
+
+	mockRepo.On("StreamIDs", mock.Anything, []int64{7}).Return(dataChan, errChan)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem).Return([]byte("serialized7"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "replay-topic", []byte("serialized7"), mock.Anything, map[string]string{ReplayHeader: "true"}).Return(nil)
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+		},
+		Repository: mockRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	dataChan <- testItem
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	err := task.Replay(context.Background(), ReplayOptions{IDs: []int64{7}, Topic: "replay-topic"})
+
+	// Assert
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+}
+
+func TestReplay_StreamError(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+
+	dataChan := make(chan TestModel)
+	errChan := make(chan error, 1)
+
+	mockRepo.On("StreamIDs", mock.Anything, []int64{1}).Return(dataChan, errChan)
+
+	task := &Task[TestModel]{
+		Config:     config.TaskConfig{Name: "test", Topic: "test-topic"},
+		Repository: mockRepo,
+	}
+
+	close(dataChan)
+	errChan <- errors.New("stream error")
+	close(errChan)
+
+	// Act
+	err := task.Replay(context.Background(), ReplayOptions{IDs: []int64{1}})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stream error")
+}
+
+func TestExecute_DryRun(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSyncRepo := new(MockSyncRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockProducer)
+
+	dataChan := make(chan TestModel, 1)
+	errChan := make(chan error, 1)
+
+	testItem := TestModel{ID: 1, Name: "Test 1"}
+
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem).Return([]byte("serialized1"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(nil)
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+			DryRun: true,
+		},
+		Repository: mockRepo,
+		SyncRepo:   mockSyncRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	dataChan <- testItem
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	task.Execute(context.Background())
+
+	// Assert
+	mockSerializer.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+	mockSyncRepo.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+func TestExecute_FlushErrorKeepsCheckpoint(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSyncRepo := new(MockSyncRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockBufferedProducer)
+
+	dataChan := make(chan TestModel, 1)
+	errChan := make(chan error, 1)
+
+	testItem := TestModel{ID: 1, Name: "Test 1"}
+
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem).Return([]byte("serialized1"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(nil)
+	mockProducer.On("Flush", mock.Anything).Return(errors.New("flush error"))
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+		},
+		Repository: mockRepo,
+		SyncRepo:   mockSyncRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	dataChan <- testItem
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	task.Execute(context.Background())
+
+	// Assert
+	mockProducer.AssertExpectations(t)
+	mockSyncRepo.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+func TestExecute_Routes(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSyncRepo := new(MockSyncRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockProducer)
+
+	dataChan := make(chan TestModel, 2)
+	errChan := make(chan error, 1)
+
+	testItem1 := TestModel{ID: 1, Name: "routed"}
+	testItem2 := TestModel{ID: 2, Name: "other"}
+
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
+	mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
+	mockSerializer.On("Serialize", "routed-schema-value", &testItem1).Return([]byte("serialized1"), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem2).Return([]byte("serialized2"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "routed-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized2"), mock.Anything, mock.Anything).Return(nil)
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+			Routes: []config.RouteConfig{
+				{Field: "name", Value: "routed", Topic: "routed-topic", Schema: "routed-schema"},
+			},
+		},
+		Repository: mockRepo,
+		SyncRepo:   mockSyncRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	dataChan <- testItem1
+	dataChan <- testItem2
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	task.Execute(context.Background())
+
+	// Assert
+	mockSerializer.AssertExpectations(t)
+	mockProducer.AssertExpectations(t)
+	mockSyncRepo.AssertExpectations(t)
+}
+
+func TestNewTask_InvalidRoutes(t *testing.T) {
+	// Arrange
+	tmpFile, err := os.CreateTemp("", "test-query-*.sql")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	config := config.TaskConfig{
+		Name:      "test",
+		QueryFile: tmpFile.Name(),
+		Topic:     "test-topic",
+		Routes:    []config.RouteConfig{{Field: "name"}},
+	}
+
+	// Act
+	task, err := NewTask[TestModel](sqlx.NewDb(db, "sqlmock"), config, new(MockSerializer), new(MockProducer))
+
+	// Assert
+	assert.Error(t, err)
+	assert.Nil(t, task)
+	assert.Contains(t, err.Error(), "topic is required")
+}
+
+func TestNewTask_InvalidSubjects(t *testing.T) {
+	// Arrange
+	tmpFile, err := os.CreateTemp("", "test-query-*.sql")
+	assert.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	base := config.TaskConfig{Name: "test", QueryFile: tmpFile.Name(), Topic: "test-topic", Schema: "test-schema"}
+	unknownStrategy := base
+	unknownStrategy.SubjectStrategy = "by_date"
+	missingKeySchema := base
+	missingKeySchema.SubjectStrategy = "record_name"
+	missingKeySchema.Key = config.KeyConfig{Field: "id"}
+	unknownKeyField := base
+	unknownKeyField.Key = config.KeyConfig{Field: "user_id"}
+	protobufPrimitiveKey := base
+	protobufPrimitiveKey.Format = "protobuf"
+	protobufPrimitiveKey.Key = config.KeyConfig{Field: "id"}
+
+	// Act
+	_, unknownErr := NewTask[TestModel](sqlx.NewDb(db, "sqlmock"), unknownStrategy, new(MockSerializer), new(MockProducer))
+	_, missingErr := NewTask[TestModel](sqlx.NewDb(db, "sqlmock"), missingKeySchema, new(MockSerializer), new(MockProducer))
+	_, unknownKeyErr := NewTask[TestModel](sqlx.NewDb(db, "sqlmock"), unknownKeyField, new(MockSerializer), new(MockProducer))
+	_, primitiveKeyErr := NewTask[TestModel](sqlx.NewDb(db, "sqlmock"), protobufPrimitiveKey, new(MockSerializer), new(MockProducer))
+
+	// Assert
+	assert.ErrorContains(t, unknownErr, `unsupported subject strategy "by_date"`)
+	assert.ErrorContains(t, missingErr, "key schema is required with the record_name subject strategy")
+	assert.ErrorContains(t, unknownKeyErr, `key: cannot resolve field "user_id": tasks.TestModel has no field "user_id"`)
+	assert.ErrorContains(t, primitiveKeyErr, `key field "id" is int, protobuf keys must be messages`)
+}
+
+func TestExecute_KeysAndSubjectStrategy(t *testing.T) {
+	testCases := []struct {
+		strategy     string
+		keySchema    string
+		valueSubject string
+		keySubject   string
+	}{
+		{strategy: "", valueSubject: "test-schema-value", keySubject: "test-schema-key"},
+		{strategy: "record_name", keySchema: "example.TestKey", valueSubject: "test-schema", keySubject: "example.TestKey"},
+		{strategy: "topic_record_name", keySchema: "example.TestKey", valueSubject: "test-topic-test-schema", keySubject: "test-topic-example.TestKey"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(MockRepository)
+			mockSyncRepo := new(MockSyncRepository)
+			mockSerializer := new(MockSerializer)
+			mockProducer := new(MockProducer)
+
+			dataChan := make(chan TestModel, 1)
+			errChan := make(chan error, 1)
+			testItem := TestModel{ID: 7, Name: "keyed"}
+
+			mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+			mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
+			mockSyncRepo.On("Set", "test", mock.AnythingOfType("time.Time")).Return(nil)
+			mockSerializer.On("Serialize", tc.valueSubject, &testItem).Return([]byte("value"), nil)
+			keyValue := 7
+			mockSerializer.On("Serialize", tc.keySubject, &keyValue).Return([]byte("key"), nil)
+			mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("value"), []byte("key"), mock.Anything).Return(nil)
+
+			task := &Task[TestModel]{
+				Config: config.TaskConfig{
+					Name:            "test",
+					Topic:           "test-topic",
+					Schema:          "test-schema",
+					SubjectStrategy: tc.strategy,
+					Key:             config.KeyConfig{Field: "id", Schema: tc.keySchema},
+				},
+				Repository: mockRepo,
+				SyncRepo:   mockSyncRepo,
+				Serializer: mockSerializer,
+				Producer:   mockProducer,
+			}
+
+			dataChan <- testItem
+			close(dataChan)
+			close(errChan)
+
+			// Act
+			task.Execute(context.Background())
+
+			// Assert
+			mockSerializer.AssertExpectations(t)
+			mockProducer.AssertExpectations(t)
+			mockSyncRepo.AssertExpectations(t)
+		})
+	}
+}
+
+// retriableError mimics kafka.DeliveryError without importing the Kafka client.
+type retriableError struct{}
+
+func (retriableError) Error() string   { return "broker unavailable" }
+func (retriableError) Retriable() bool { return true }
+
+func TestExecute_RetriableProduceErrorKeepsCheckpoint(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSyncRepo := new(MockSyncRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockProducer)
+
+	dataChan := make(chan TestModel, 2)
+	errChan := make(chan error, 1)
+
+	testItem1 := TestModel{ID: 1, Name: "Test 1"}
+	testItem2 := TestModel{ID: 2, Name: "Test 2"}
+
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
+	mockSerializer.On("Serialize", "test-schema-value", &testItem1).Return([]byte("serialized1"), nil)
+	mockProducer.On("ProduceMessage", mock.Anything, "test-topic", []byte("serialized1"), mock.Anything, mock.Anything).Return(fmt.Errorf("wrapped: %w", retriableError{}))
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+		},
+		Repository: mockRepo,
+		SyncRepo:   mockSyncRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	dataChan <- testItem1
+	dataChan <- testItem2
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	task.Execute(context.Background())
+
+	// Assert
+	mockProducer.AssertNumberOfCalls(t, "ProduceMessage", 1)
+	mockSyncRepo.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}
+
+func TestExecute_CancelledKeepsCheckpoint(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockRepository)
+	mockSyncRepo := new(MockSyncRepository)
+	mockSerializer := new(MockSerializer)
+	mockProducer := new(MockProducer)
+
+	dataChan := make(chan TestModel, 1)
+	errChan := make(chan error, 1)
+
+	mockRepo.On("Stream", mock.Anything, mock.AnythingOfType("time.Time")).Return(dataChan, errChan)
+	mockSyncRepo.On("Get", "test").Return(time.Now().Add(-time.Hour), nil)
+
+	task := &Task[TestModel]{
+		Config: config.TaskConfig{
+			Name:   "test",
+			Topic:  "test-topic",
+			Schema: "test-schema",
+		},
+		Repository: mockRepo,
+		SyncRepo:   mockSyncRepo,
+		Serializer: mockSerializer,
+		Producer:   mockProducer,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dataChan <- TestModel{ID: 1, Name: "Test 1"}
+	close(dataChan)
+	close(errChan)
+
+	// Act
+	task.Execute(ctx)
+
+	// Assert
+	mockSerializer.AssertNotCalled(t, "Serialize", mock.Anything, mock.Anything)
+	mockSyncRepo.AssertNotCalled(t, "Set", mock.Anything, mock.Anything)
+}